@@ -1,4 +1,5 @@
-import { useState } from "react";
+import { useEffect, useState } from "react";
+import axios from "axios";
 import { FaCheckCircle, FaExclamationCircle } from "react-icons/fa";
 import type { WaitlistInput } from "../../hooks/usewaitlist";
 
@@ -10,6 +11,17 @@ const AddWaitlist = ({ onSubmit }: AddWaitlistProps) => {
   const [isSuccess, setIsSuccess] = useState(false);
   const [isSubmitting, setIsSubmitting] = useState(false);
   const [submissionError, setSubmissionError] = useState<string | null>(null);
+  const [formToken, setFormToken] = useState("");
+
+  const loadFormToken = () =>
+    axios
+      .get("/api/waitlist/form-token")
+      .then((res) => setFormToken(res.data.token))
+      .catch((err) => console.error("Waitlist form token error:", err));
+
+  useEffect(() => {
+    loadFormToken();
+  }, []);
 
   const [formData, setFormData] = useState<WaitlistInput>({
     firstname: "",
@@ -17,6 +29,7 @@ const AddWaitlist = ({ onSubmit }: AddWaitlistProps) => {
     email: "",
     phone: "",
     preferences: "",
+    website: "",
   });
 
   const handleChange = (
@@ -32,7 +45,7 @@ const AddWaitlist = ({ onSubmit }: AddWaitlistProps) => {
     setSubmissionError(null);
 
     try {
-      await onSubmit(formData);
+      await onSubmit({ ...formData, form_token: formToken });
 
       setIsSuccess(true);
       setFormData({
@@ -41,7 +54,9 @@ const AddWaitlist = ({ onSubmit }: AddWaitlistProps) => {
         email: "",
         phone: "",
         preferences: "",
+        website: "",
       });
+      loadFormToken();
 
       setTimeout(() => setIsSuccess(false), 5000);
     } catch (err: any) {
@@ -183,6 +198,20 @@ const AddWaitlist = ({ onSubmit }: AddWaitlistProps) => {
                 />
               </div>
 
+              {/* Honeypot: hidden from people, filled in by bots */}
+              <div className="absolute -left-[9999px]" aria-hidden="true">
+                <label htmlFor="website">Website</label>
+                <input
+                  type="text"
+                  id="website"
+                  name="website"
+                  tabIndex={-1}
+                  autoComplete="off"
+                  value={formData.website}
+                  onChange={handleChange}
+                />
+              </div>
+
               {/* Error Display */}
               {submissionError && (
                 <div className="flex items-center gap-2 text-red-400 bg-red-400/10 p-3 rounded-lg border border-red-400/20 text-sm">
@@ -19,6 +19,8 @@ export interface WaitlistInput {
   email: string;
   phone: string;
   preferences: string;
+  website?: string;
+  form_token?: string;
 }
 
 export interface WaitlistUpdateInput extends WaitlistInput {
@@ -58,7 +60,8 @@ export const useWaitlist = (fetchData = true) => {
       }
     } catch (err) {
       console.error("Create waitlist entry error:", err);
-      throw new Error("Failed to create waitlist entry");
+      const message = axios.isAxiosError(err) ? err.response?.data?.error : null;
+      throw new Error(message || "Failed to create waitlist entry");
     }
   };
 
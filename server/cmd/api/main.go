@@ -4,12 +4,15 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/config"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/controllers"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/middleware"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/logger"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/stream"
@@ -25,6 +28,16 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	if gin.Mode() == gin.ReleaseMode && usesDefaultJWTSecret(cfg) {
+		slog.Error("refusing to start in release mode with the default JWT secret, set JWT_SECRET")
+		os.Exit(1)
+	}
+
+	if err := utils.InitJWTKeys(cfg); err != nil {
+		slog.Error("failed to load jwt keys", "error", err)
+		os.Exit(1)
+	}
+
 	database.Connect(cfg.DatabaseURL)
 	defer database.Close()
 	database.CreateTables()
@@ -36,6 +49,9 @@ func main() {
 
 	slog.Info("storage directories ready")
 
+	go utils.StartEmailWorker()
+	go utils.StartHealthReminderWorker()
+
 	if err := stream.Initialize(stream.Config{
 		RTMPAddr:      cfg.RTMPAddr,
 		RTMPSAddr:     cfg.RTMPSAddr,
@@ -48,13 +64,25 @@ func main() {
 		slog.Error("failed to initialize stream manager", "error", err)
 	}
 
-	r := gin.Default()
+	r := gin.New()
+	r.Use(gin.LoggerWithFormatter(middleware.RequestLogFormatter), gin.Recovery())
+
+	// Only trust forwarding headers from our own proxies so per-IP rate
+	// limits and audit addresses can't be spoofed with X-Forwarded-For.
+	if err := r.SetTrustedProxies(splitList(cfg.TrustedProxies)); err != nil {
+		slog.Error("invalid TRUSTED_PROXIES", "error", err)
+		os.Exit(1)
+	}
 
 	api := r.Group("/api")
 	{
 		// Auth
 		api.POST("/auth/login", controllers.LoginUser)
 		api.POST("/auth/logout", middleware.RequireAuth, controllers.LogoutUser)
+		api.POST("/auth/forgot-password", controllers.ForgotPassword)
+		api.POST("/auth/reset-password", controllers.ResetPassword)
+		api.POST("/auth/verify-email", controllers.VerifyEmail)
+		api.POST("/auth/verify-email/resend", middleware.RequireAuth, controllers.ResendVerificationEmail)
 
 		// Users
 		api.GET("/users/:id", middleware.RequireAuth, controllers.GetUser)
@@ -62,48 +90,155 @@ func main() {
 		api.PATCH("/users/:id", middleware.RequireAuth, controllers.UpdateUser)
 		api.DELETE("/users/:id", middleware.RequireAuth, controllers.DeleteUser)
 
+		// API Keys
+		api.GET("/api-keys", middleware.RequireAuth, controllers.GetAPIKeys)
+		api.POST("/api-keys", middleware.RequireAuth, controllers.CreateAPIKey)
+		api.DELETE("/api-keys/:id", middleware.RequireAuth, controllers.RevokeAPIKey)
+
+		// Audit
+		api.GET("/audit", middleware.RequireAuth, controllers.GetAuditLog)
+
+		// Email Templates
+		api.GET("/email-templates", middleware.RequireAuth, controllers.GetEmailTemplates)
+		api.GET("/email-templates/:key", middleware.RequireAuth, controllers.GetEmailTemplate)
+		api.PATCH("/email-templates/:key", middleware.RequireAuth, controllers.UpdateEmailTemplate)
+		api.DELETE("/email-templates/:key", middleware.RequireAuth, controllers.ResetEmailTemplate)
+		api.POST("/email-templates/:key/preview", middleware.RequireAuth, controllers.PreviewEmailTemplate)
+
+		// Email Outbox
+		api.GET("/email-outbox", middleware.RequireAuth, controllers.GetEmailOutbox)
+		api.POST("/email-outbox/:id/resend", middleware.RequireAuth, controllers.ResendEmail)
+
 		// Breeder
 		api.GET("/breeder", controllers.GetBreeder)
-		api.PATCH("/breeder", middleware.RequireAuth, controllers.UpdateBreeder)
+		api.PATCH("/breeder", middleware.RequireScope(models.ScopeBreederWrite), controllers.UpdateBreeder)
 
 		// Dogs
 		api.GET("/dogs", controllers.GetDogs)
 		api.GET("/dogs/:id", controllers.GetDog)
-		api.POST("/dogs", middleware.RequireAuth, controllers.CreateDog)
-		api.PATCH("/dogs/:id", middleware.RequireAuth, controllers.UpdateDog)
-		api.DELETE("/dogs/:id", middleware.RequireAuth, controllers.DeleteDog)
+		api.POST("/dogs", middleware.RequireScope(models.ScopeDogsWrite), controllers.CreateDog)
+		api.PATCH("/dogs/:id", middleware.RequireScope(models.ScopeDogsWrite), controllers.UpdateDog)
+		api.DELETE("/dogs/:id", middleware.RequireScope(models.ScopeDogsWrite), controllers.DeleteDog)
+		api.GET("/dogs/:id/pedigree", controllers.GetDogPedigree)
+		api.GET("/dogs/:id/health", controllers.GetDogHealth)
+		api.GET("/pairings/coi", controllers.GetPairingCOI)
+
+		// External Ancestors
+		api.GET("/ancestors", controllers.GetExternalAncestors)
+		api.GET("/ancestors/:id", controllers.GetExternalAncestor)
+		api.POST("/ancestors", middleware.RequireScope(models.ScopeDogsWrite), controllers.CreateExternalAncestor)
+		api.PATCH("/ancestors/:id", middleware.RequireScope(models.ScopeDogsWrite), controllers.UpdateExternalAncestor)
+		api.DELETE("/ancestors/:id", middleware.RequireScope(models.ScopeDogsWrite), controllers.DeleteExternalAncestor)
 
 		// Litters
 		api.GET("/litters", controllers.GetLitters)
 		api.GET("/litters/:id", controllers.GetLitter)
-		api.POST("/litters", middleware.RequireAuth, controllers.CreateLitter)
-		api.PATCH("/litters/:id", middleware.RequireAuth, controllers.UpdateLitter)
-		api.DELETE("/litters/:id", middleware.RequireAuth, controllers.DeleteLitter)
+		api.POST("/litters", middleware.RequireScope(models.ScopeLittersWrite), controllers.CreateLitter)
+		api.PATCH("/litters/:id", middleware.RequireScope(models.ScopeLittersWrite), controllers.UpdateLitter)
+		api.DELETE("/litters/:id", middleware.RequireScope(models.ScopeLittersWrite), controllers.DeleteLitter)
+		api.GET("/litters/:id/weights", controllers.GetLitterWeights)
+		api.POST("/litters/:id/weights", middleware.RequireScope(models.ScopePuppiesWrite), controllers.CreateLitterWeighIn)
 
 		// Puppies
-		api.GET("/puppies", controllers.GetPuppies)
-		api.GET("/puppies/:id", controllers.GetPuppy)
-		api.POST("/puppies", middleware.RequireAuth, controllers.CreatePuppy)
-		api.PATCH("/puppies/:id", middleware.RequireAuth, controllers.UpdatePuppy)
-		api.DELETE("/puppies/:id", middleware.RequireAuth, controllers.DeletePuppy)
+		api.GET("/puppies", middleware.OptionalScope(models.ScopePuppiesRead), controllers.GetPuppies)
+		api.GET("/puppies/:id", middleware.OptionalScope(models.ScopePuppiesRead), controllers.GetPuppy)
+		api.POST("/puppies", middleware.RequireScope(models.ScopePuppiesWrite), controllers.CreatePuppy)
+		api.PATCH("/puppies/:id", middleware.RequireScope(models.ScopePuppiesWrite), controllers.UpdatePuppy)
+		api.DELETE("/puppies/:id", middleware.RequireScope(models.ScopePuppiesWrite), controllers.DeletePuppy)
+		api.GET("/puppies/:id/pedigree", controllers.GetPuppyPedigree)
+		api.GET("/puppies/:id/health", controllers.GetPuppyHealth)
+		api.POST("/puppies/:id/promote", middleware.RequireScope(models.ScopeDogsWrite), controllers.PromotePuppy)
+		api.GET("/puppies/:id/weights", controllers.GetPuppyWeights)
+		api.POST("/puppies/:id/weights", middleware.RequireScope(models.ScopePuppiesWrite), controllers.CreatePuppyWeight)
+		api.DELETE("/puppies/:id/weights/:weightId", middleware.RequireScope(models.ScopePuppiesWrite), controllers.DeletePuppyWeight)
+		api.GET("/puppies/:id/sale", middleware.RequireScope(models.ScopeSalesRead), controllers.GetPuppySale)
+		api.PUT("/puppies/:id/sale", middleware.RequireScope(models.ScopeSalesWrite), controllers.RecordPuppySale)
+		api.DELETE("/puppies/:id/sale", middleware.RequireScope(models.ScopeSalesWrite), controllers.DeletePuppySale)
+		api.POST("/puppies/:id/payments", middleware.RequireScope(models.ScopeSalesWrite), controllers.CreatePayment)
+		api.DELETE("/puppies/:id/payments/:paymentId", middleware.RequireScope(models.ScopeSalesWrite), controllers.DeletePayment)
+		api.POST("/puppies/:id/contract", middleware.RequireScope(models.ScopeSalesWrite), controllers.GenerateContract)
+
+		// Coat Colors
+		api.GET("/colors", controllers.GetCoatColors)
+		api.POST("/colors", middleware.RequireScope(models.ScopePuppiesWrite), controllers.CreateCoatColor)
+		api.PATCH("/colors/:id", middleware.RequireScope(models.ScopePuppiesWrite), controllers.UpdateCoatColor)
+		api.DELETE("/colors/:id", middleware.RequireScope(models.ScopePuppiesWrite), controllers.DeleteCoatColor)
 
 		// Waitlist
-		api.POST("/waitlist", controllers.CreateWaitlist)
-		api.GET("/waitlist", middleware.RequireAuth, controllers.GetWaitlist)
-		api.PATCH("/waitlist/:id", middleware.RequireAuth, controllers.UpdateWaitlist)
-		api.DELETE("/waitlist/:id", middleware.RequireAuth, controllers.DeleteWaitlist)
+		api.GET("/waitlist/form-token", controllers.GetWaitlistFormToken)
+		api.POST("/waitlist", middleware.RateLimit(5, time.Hour), controllers.CreateWaitlist)
+		api.POST("/waitlist/portal/link", middleware.RateLimit(5, time.Hour), controllers.RequestWaitlistPortalLink)
+		api.GET("/waitlist/portal", middleware.RequireWaitlistToken, controllers.GetWaitlistPortal)
+		api.PATCH("/waitlist/portal", middleware.RequireWaitlistToken, controllers.UpdateWaitlistPortal)
+		api.POST("/waitlist/portal/withdraw", middleware.RequireWaitlistToken, controllers.WithdrawWaitlistPortal)
+		api.POST("/waitlist/email/confirm", middleware.RateLimit(10, time.Hour), controllers.ConfirmWaitlistEmailChange)
+		api.GET("/waitlist/matches", middleware.RequireScope(models.ScopeWaitlistRead), controllers.GetWaitlistMatches)
+		api.GET("/waitlist/export", middleware.RequireScope(models.ScopeWaitlistRead), controllers.ExportWaitlist)
+		api.POST("/waitlist/import", middleware.RequireScope(models.ScopeWaitlistWrite), controllers.ImportWaitlist)
+		api.GET("/waitlist/duplicates", middleware.RequireScope(models.ScopeWaitlistRead), controllers.GetWaitlistDuplicates)
+		api.POST("/waitlist/:id/merge", middleware.RequireScope(models.ScopeWaitlistWrite), controllers.MergeWaitlist)
+		api.GET("/waitlist", middleware.RequireScope(models.ScopeWaitlistRead), controllers.GetWaitlist)
+		api.PATCH("/waitlist/:id", middleware.RequireScope(models.ScopeWaitlistWrite), controllers.UpdateWaitlist)
+		api.DELETE("/waitlist/:id", middleware.RequireScope(models.ScopeWaitlistWrite), controllers.DeleteWaitlist)
+		api.GET("/waitlist/:id/history", middleware.RequireScope(models.ScopeWaitlistRead), controllers.GetWaitlistHistory)
+		api.PUT("/waitlist/:id/deposit", middleware.RequireScope(models.ScopeWaitlistWrite), controllers.RecordWaitlistDeposit)
+		api.PUT("/waitlist/:id/position", middleware.RequireScope(models.ScopeWaitlistWrite), controllers.MoveWaitlistEntry)
+
+		// Reservations
+		api.GET("/reservations", middleware.RequireScope(models.ScopeWaitlistRead), controllers.GetReservations)
+		api.GET("/reservations/:id", middleware.RequireScope(models.ScopeWaitlistRead), controllers.GetReservation)
+		api.POST("/reservations", middleware.RequireScope(models.ScopeWaitlistWrite), controllers.CreateReservation)
+		api.PATCH("/reservations/:id", middleware.RequireScope(models.ScopeWaitlistWrite), controllers.UpdateReservation)
+		api.POST("/reservations/:id/cancel", middleware.RequireScope(models.ScopeWaitlistWrite), controllers.CancelReservation)
+		api.POST("/reservations/:id/complete", middleware.RequireScope(models.ScopeWaitlistWrite), controllers.CompleteReservation)
 
 		// Settings
 		api.GET("/settings", controllers.GetSettings)
 		api.GET("/settings/stream/status", controllers.GetStreamStatus)
-		api.GET("/settings/stream/admin-status", middleware.RequireAuth, controllers.GetAdminStreamStatus)
-		api.PATCH("/settings/waitlist", middleware.RequireAuth, controllers.UpdateWaitlistStatus)
-		api.PATCH("/settings/stream", middleware.RequireAuth, controllers.UpdateStreamStatus)
+		api.GET("/settings/stream/admin-status", middleware.RequireScope(models.ScopeStreamRead), controllers.GetAdminStreamStatus)
+		api.PATCH("/settings/waitlist", middleware.RequireScope(models.ScopeSettingsWrite), controllers.UpdateWaitlistStatus)
+		api.PATCH("/settings/stream", middleware.RequireScope(models.ScopeStreamWrite), controllers.UpdateStreamStatus)
+
+		// Health Records
+		api.GET("/health-records", middleware.RequireScope(models.ScopeHealthRead), controllers.GetHealthRecords)
+		api.GET("/health-records/due", middleware.RequireScope(models.ScopeHealthRead), controllers.GetDueHealthRecords)
+		api.GET("/health-records/:id", middleware.RequireScope(models.ScopeHealthRead), controllers.GetHealthRecord)
+		api.POST("/health-records", middleware.RequireScope(models.ScopeHealthWrite), controllers.CreateHealthRecord)
+		api.PATCH("/health-records/:id", middleware.RequireScope(models.ScopeHealthWrite), controllers.UpdateHealthRecord)
+		api.DELETE("/health-records/:id", middleware.RequireScope(models.ScopeHealthWrite), controllers.DeleteHealthRecord)
+
+		// Breeding Calendar
+		api.GET("/breeding-events", middleware.RequireScope(models.ScopeBreedingRead), controllers.GetBreedingEvents)
+		api.GET("/breeding-events/upcoming", middleware.RequireScope(models.ScopeBreedingRead), controllers.GetUpcomingBreedingEvents)
+		api.GET("/breeding-events/:id", middleware.RequireScope(models.ScopeBreedingRead), controllers.GetBreedingEvent)
+		api.POST("/breeding-events", middleware.RequireScope(models.ScopeBreedingWrite), controllers.CreateBreedingEvent)
+		api.PATCH("/breeding-events/:id", middleware.RequireScope(models.ScopeBreedingWrite), controllers.UpdateBreedingEvent)
+		api.DELETE("/breeding-events/:id", middleware.RequireScope(models.ScopeBreedingWrite), controllers.DeleteBreedingEvent)
+		api.POST("/breeding-events/:id/litter", middleware.RequireScope(models.ScopeBreedingWrite), controllers.PlanLitterFromBreeding)
+
+		// Sales Reports
+		api.GET("/sales/revenue/litters", middleware.RequireScope(models.ScopeSalesRead), controllers.GetLitterRevenue)
+		api.GET("/sales/revenue/years", middleware.RequireScope(models.ScopeSalesRead), controllers.GetYearRevenue)
+
+		// Contract Templates
+		api.GET("/contract-templates", middleware.RequireScope(models.ScopeSalesRead), controllers.GetContractTemplates)
+		api.GET("/contract-templates/default", middleware.RequireScope(models.ScopeSalesRead), controllers.GetDefaultContractTemplate)
+		api.GET("/contract-templates/:id", middleware.RequireScope(models.ScopeSalesRead), controllers.GetContractTemplate)
+		api.POST("/contract-templates", middleware.RequireScope(models.ScopeSalesWrite), controllers.CreateContractTemplate)
+		api.PATCH("/contract-templates/:id", middleware.RequireScope(models.ScopeSalesWrite), controllers.UpdateContractTemplate)
+		api.DELETE("/contract-templates/:id", middleware.RequireScope(models.ScopeSalesWrite), controllers.DeleteContractTemplate)
+
+		// Calendar Feeds
+		api.GET("/calendar/litters.ics", controllers.GetLittersCalendar)
+		api.GET("/calendar/admin.ics", middleware.RequireCalendarToken, controllers.GetAdminCalendar)
+		api.POST("/calendar/feed-tokens", middleware.RequireAuth, controllers.CreateCalendarFeedToken)
 
 		// Files
-		api.GET("/files", middleware.RequireAuth, controllers.GetFiles)
-		api.POST("/files", middleware.RequireAuth, controllers.CreateFile)
-		api.DELETE("/files/:id", middleware.RequireAuth, controllers.DeleteFile)
+		api.GET("/files", middleware.RequireScope(models.ScopeFilesRead), controllers.GetFiles)
+		api.POST("/files", middleware.RequireScope(models.ScopeFilesWrite), controllers.CreateFile)
+		api.DELETE("/files/:id", middleware.RequireScope(models.ScopeFilesWrite), controllers.DeleteFile)
+		api.POST("/files/:id/email", middleware.RequireAuth, controllers.EmailFile)
+		api.GET("/private-files/*filepath", middleware.RequireScope(models.ScopeFilesRead), controllers.DownloadPrivateFile)
 	}
 
 	r.Static("/assets", "./public/dist/assets")
@@ -125,3 +260,25 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+func usesDefaultJWTSecret(cfg *config.Config) bool {
+	if cfg.JWTSecret == config.DefaultJWTSecret {
+		return true
+	}
+	for _, secret := range strings.Split(cfg.JWTOldSecrets, ",") {
+		if strings.TrimSpace(secret) == config.DefaultJWTSecret {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
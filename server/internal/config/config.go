@@ -7,12 +7,20 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// DefaultJWTSecret is the development fallback for JWT_SECRET. The server
+// refuses to start with it in release mode.
+const DefaultJWTSecret = "verylongsecret"
+
 type Config struct {
 	Port             string
 	DatabaseURL      string
 	JWTSecret        string
+	JWTOldSecrets    string
+	JWTKeyFile       string
+	JWTOldKeyFiles   string
 	LogLevel         string
 	StorageRoot      string
+	PrivateRoot      string
 	UploadsURLBase   string
 	RTMPAddr         string
 	RTMPSAddr        string
@@ -27,6 +35,19 @@ type Config struct {
 	EmailPassword    string
 	EmailServiceHost string
 	EmailServicePort string
+	EmailFromName    string
+	EmailTransport   string
+	EmailTLSMode     string
+	EmailSendmail    string
+	EmailMaildir     string
+	EmailHTTPURL     string
+	EmailHTTPToken   string
+	AppBaseURL       string
+	CookieDomain     string
+	CookieSecure     bool
+	TrustedProxies   string
+	WaitlistMinFill  string
+	HealthRemindDays string
 }
 
 func Load() *Config {
@@ -37,9 +58,13 @@ func Load() *Config {
 	return &Config{
 		Port:             getEnv("PORT", "4000"),
 		DatabaseURL:      getEnv("DATABASE_URL", ""),
-		JWTSecret:        getEnv("JWT_SECRET", "verylongsecret"),
+		JWTSecret:        getEnv("JWT_SECRET", DefaultJWTSecret),
+		JWTOldSecrets:    getEnv("JWT_PREVIOUS_SECRETS", ""),
+		JWTKeyFile:       getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		JWTOldKeyFiles:   getEnv("JWT_PUBLIC_KEY_FILES", ""),
 		LogLevel:         getEnv("LOG_LEVEL", "info"),
 		StorageRoot:      getEnv("STORAGE_ROOT", "./storage"),
+		PrivateRoot:      getEnv("PRIVATE_STORAGE_ROOT", "./storage-private"),
 		UploadsURLBase:   getEnv("UPLOADS_URL_BASE", "/uploads"),
 		RTMPAddr:         getEnv("RTMP_ADDR", ":1935"),
 		RTMPSAddr:        getEnv("RTMPS_ADDR", ":1936"),
@@ -54,6 +79,19 @@ func Load() *Config {
 		EmailPassword:    getEnv("EMAIL_PASSWORD", ""),
 		EmailServiceHost: getEnv("EMAIL_HOST", "smtp.gmail.com"),
 		EmailServicePort: getEnv("EMAIL_PORT", "587"),
+		EmailFromName:    getEnv("EMAIL_FROM_NAME", "April's Lil Pugs"),
+		EmailTransport:   getEnv("EMAIL_TRANSPORT", "smtp"),
+		EmailTLSMode:     getEnv("EMAIL_TLS_MODE", ""),
+		EmailSendmail:    getEnv("EMAIL_SENDMAIL_PATH", "/usr/sbin/sendmail"),
+		EmailMaildir:     getEnv("EMAIL_MAILDIR", "./maildir"),
+		EmailHTTPURL:     getEnv("EMAIL_HTTP_URL", ""),
+		EmailHTTPToken:   getEnv("EMAIL_HTTP_TOKEN", ""),
+		AppBaseURL:       getEnv("APP_BASE_URL", "https://aprilslilpugs.com"),
+		CookieDomain:     getEnv("COOKIE_DOMAIN", ""),
+		CookieSecure:     getEnv("COOKIE_SECURE", "true") != "false",
+		TrustedProxies:   getEnv("TRUSTED_PROXIES", "127.0.0.1,::1,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16"),
+		WaitlistMinFill:  getEnv("WAITLIST_MIN_FILL_SECONDS", "3"),
+		HealthRemindDays: getEnv("HEALTH_REMINDER_DAYS", "7"),
 	}
 }
 
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
+)
+
+func GetAPIKeys(c *gin.Context) {
+	query := `
+		SELECT id, name, key_prefix, scopes, created_by, last_used_at, last_used_ip, expires_at, revoked_at, created_at
+		FROM api_keys
+		ORDER BY created_at DESC`
+
+	rows, err := database.Pool.Query(c, query)
+	if err != nil {
+		slog.Error("get api keys: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var k models.APIKey
+		if err := rows.Scan(
+			&k.ID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedBy, &k.LastUsedAt, &k.LastUsedIP, &k.ExpiresAt, &k.RevokedAt, &k.CreatedAt,
+		); err != nil {
+			slog.Debug("get api keys: failed to scan row", "error", err)
+			continue
+		}
+		keys = append(keys, k)
+	}
+
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": keys, "availableScopes": models.APIKeyScopes})
+}
+
+func CreateAPIKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("create api key: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			slog.Debug("create api key: unknown scope", "scope", scope)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope})
+			return
+		}
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	authUser := userVal.(models.User)
+
+	resp, err := insertAPIKey(c, req.Name, req.Scopes, authUser.ID, req.ExpiresAt)
+	if err != nil {
+		slog.Error("create api key: failed to create key", "name", req.Name, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	recordAudit(c, auditActionCreate, "api_keys", resp.ID, nil, snapshotEntity(c, "api_keys", resp.ID))
+
+	slog.Info("create api key: key created", "api_key_id", resp.ID, "name", resp.Name, "scopes", resp.Scopes, "user_id", authUser.ID)
+	c.JSON(http.StatusCreated, resp)
+}
+
+// insertAPIKey generates and stores a new key. The raw key is only ever
+// returned here.
+func insertAPIKey(c *gin.Context, name string, scopes []string, userID int, expiresAt *time.Time) (models.CreateAPIKeyResponse, error) {
+	rawKey, prefix, keyHash, err := utils.GenerateAPIKey()
+	if err != nil {
+		return models.CreateAPIKeyResponse{}, err
+	}
+
+	resp := models.CreateAPIKeyResponse{
+		APIKey: models.APIKey{
+			Name:      name,
+			Prefix:    prefix,
+			Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+			CreatedBy: &userID,
+			ExpiresAt: expiresAt,
+		},
+		Key: rawKey,
+	}
+
+	query := `
+		INSERT INTO api_keys (name, key_prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err = database.Pool.QueryRow(c, query,
+		resp.Name, resp.Prefix, keyHash, resp.Scopes, userID, resp.ExpiresAt,
+	).Scan(&resp.ID, &resp.CreatedAt)
+	return resp, err
+}
+
+func RevokeAPIKey(c *gin.Context) {
+	id := c.Param("id")
+	before := snapshotEntity(c, "api_keys", id)
+
+	result, err := database.Pool.Exec(c, "UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		slog.Error("revoke api key: database error", "api_key_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		slog.Debug("revoke api key: not found or already revoked", "api_key_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "api_keys", id, before, snapshotEntity(c, "api_keys", id))
+
+	slog.Info("revoke api key: key revoked", "api_key_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
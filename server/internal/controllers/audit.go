@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+const (
+	auditActionCreate = "create"
+	auditActionUpdate = "update"
+	auditActionDelete = "delete"
+)
+
+// auditRedactedFields never leave the database through the audit log.
+var auditRedactedFields = []string{"password_hash", "key_hash", "token_hash"}
+
+// auditRedactedTableFields are redacted for one table only; queued email
+// bodies can carry live sign-in links.
+var auditRedactedTableFields = map[string][]string{
+	"email_outbox": {"html_body", "text_body"},
+}
+
+// auditIgnoredFields change on every write and would only add noise to diffs.
+var auditIgnoredFields = map[string]bool{"updated_at": true}
+
+// snapshotEntity returns the current row of table as a JSON object, or nil if
+// it does not exist. table must be a trusted identifier, never user input.
+func snapshotEntity(c *gin.Context, table string, id any) map[string]any {
+	var raw []byte
+	query := fmt.Sprintf(`SELECT to_jsonb(t) FROM %s t WHERE id = $1`, pgx.Identifier{table}.Sanitize())
+
+	if err := database.Pool.QueryRow(c, query, id).Scan(&raw); err != nil {
+		if err != pgx.ErrNoRows {
+			slog.Warn("audit: failed to snapshot entity", "entity_type", table, "entity_id", id, "error", err)
+		}
+		return nil
+	}
+
+	var snapshot map[string]any
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		slog.Warn("audit: failed to decode entity snapshot", "entity_type", table, "entity_id", id, "error", err)
+		return nil
+	}
+
+	for _, field := range auditRedactedFields {
+		delete(snapshot, field)
+	}
+	for _, field := range auditRedactedTableFields[table] {
+		delete(snapshot, field)
+	}
+
+	return snapshot
+}
+
+// recordAudit writes an audit entry for a completed mutation. Failures are
+// logged rather than returned so auditing never undoes a successful change.
+func recordAudit(c *gin.Context, action string, entityType string, entityID any, before, after map[string]any) {
+	var actorUserID, actorSessionID, actorAPIKeyID *int
+
+	if userVal, ok := c.Get("user"); ok {
+		user := userVal.(models.User)
+		actorUserID = &user.ID
+	}
+	if sessionVal, ok := c.Get("session_id"); ok {
+		sessionID := sessionVal.(int)
+		actorSessionID = &sessionID
+	}
+	if keyVal, ok := c.Get("api_key"); ok {
+		key := keyVal.(models.APIKey)
+		actorAPIKeyID = &key.ID
+	}
+
+	var entityIDStr *string
+	if entityID != nil {
+		s := fmt.Sprint(entityID)
+		entityIDStr = &s
+	}
+
+	beforeJSON, afterJSON, changesJSON := marshalAuditJSON(before), marshalAuditJSON(after), marshalAuditJSON(diffSnapshots(before, after))
+
+	query := `
+		INSERT INTO audit_log (
+			actor_user_id, actor_session_id, actor_api_key_id, action, entity_type, entity_id,
+			before, after, changes, ip_address, user_agent
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err := database.Pool.Exec(c, query,
+		actorUserID, actorSessionID, actorAPIKeyID, action, entityType, entityIDStr,
+		beforeJSON, afterJSON, changesJSON, c.ClientIP(), truncate(c.Request.UserAgent(), 255),
+	)
+	if err != nil {
+		slog.Error("audit: failed to record entry", "action", action, "entity_type", entityType, "entity_id", entityID, "error", err)
+	}
+}
+
+func diffSnapshots(before, after map[string]any) map[string]any {
+	changes := make(map[string]any)
+
+	keys := make(map[string]bool)
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+
+	for k := range keys {
+		if auditIgnoredFields[k] {
+			continue
+		}
+		if !reflect.DeepEqual(before[k], after[k]) {
+			changes[k] = map[string]any{"before": before[k], "after": after[k]}
+		}
+	}
+
+	return changes
+}
+
+func marshalAuditJSON(v map[string]any) []byte {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Warn("audit: failed to marshal snapshot", "error", err)
+		return nil
+	}
+	return data
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
+
+func GetAuditLog(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	where := ` WHERE 1=1`
+	args := []interface{}{}
+
+	addFilter := func(clause string, value interface{}) {
+		args = append(args, value)
+		where += fmt.Sprintf(" AND "+clause, len(args))
+	}
+
+	if v := c.Query("actor_user_id"); v != "" {
+		addFilter("a.actor_user_id = $%d", v)
+	}
+	if v := c.Query("actor_api_key_id"); v != "" {
+		addFilter("a.actor_api_key_id = $%d", v)
+	}
+	if v := c.Query("action"); v != "" {
+		addFilter("a.action = $%d", v)
+	}
+	if v := c.Query("entity_type"); v != "" {
+		addFilter("a.entity_type = $%d", v)
+	}
+	if v := c.Query("entity_id"); v != "" {
+		addFilter("a.entity_id = $%d", v)
+	}
+	if v := c.Query("from"); v != "" {
+		from, err := parseAuditTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+			return
+		}
+		addFilter("a.created_at >= $%d", from)
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := parseAuditTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+			return
+		}
+		addFilter("a.created_at < $%d", to)
+	}
+
+	var total int
+	if err := database.Pool.QueryRow(c, `SELECT count(*) FROM audit_log a`+where, args...).Scan(&total); err != nil {
+		slog.Error("get audit log: failed to count entries", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+
+	query := `
+		SELECT
+			a.id, a.actor_user_id, u.first_name || ' ' || u.last_name, a.actor_session_id, a.actor_api_key_id,
+			a.action, a.entity_type, a.entity_id, a.before, a.after, a.changes,
+			a.ip_address, a.user_agent, a.created_at
+		FROM audit_log a
+		LEFT JOIN users u ON a.actor_user_id = u.id` + where +
+		fmt.Sprintf(` ORDER BY a.created_at DESC, a.id DESC LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+
+	rows, err := database.Pool.Query(c, query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		slog.Error("get audit log: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(
+			&e.ID, &e.ActorUserID, &e.ActorName, &e.ActorSessionID, &e.ActorAPIKeyID,
+			&e.Action, &e.EntityType, &e.EntityID, &e.Before, &e.After, &e.Changes,
+			&e.IPAddress, &e.UserAgent, &e.CreatedAt,
+		); err != nil {
+			slog.Debug("get audit log: failed to scan row", "error", err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":  entries,
+		"page":     page,
+		"pageSize": pageSize,
+		"total":    total,
+	})
+}
+
+// parseAuditTime accepts either a full RFC 3339 timestamp or a plain date.
+func parseAuditTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
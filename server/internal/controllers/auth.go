@@ -1,12 +1,17 @@
 package controllers
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/config"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
@@ -67,14 +72,29 @@ func LoginUser(c *gin.Context) {
 		return
 	}
 
-	slog.Info("login: user authenticated", "user_id", user.ID, "remote_addr", clientIP)
-
-	c.JSON(http.StatusOK, models.LoginResponse{
-		Token:     tokenString,
+	response := models.LoginResponse{
 		Email:     user.Email,
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
-	})
+	}
+
+	if req.UseCookie {
+		csrfToken, _, err := utils.GenerateSecureToken()
+		if err != nil {
+			slog.Error("login: failed to generate csrf token", "session_id", sessionID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+
+		utils.SetAuthCookies(c.Writer, tokenString, csrfToken, expirationTime)
+		response.CSRFToken = csrfToken
+	} else {
+		response.Token = tokenString
+	}
+
+	slog.Info("login: user authenticated", "user_id", user.ID, "remote_addr", clientIP, "cookie_mode", req.UseCookie)
+
+	c.JSON(http.StatusOK, response)
 }
 
 func LogoutUser(c *gin.Context) {
@@ -92,5 +112,291 @@ func LogoutUser(c *gin.Context) {
 		slog.Warn("logout: session id missing from request context")
 	}
 
+	utils.ClearAuthCookies(c.Writer)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Successfully logged out"})
 }
+
+const (
+	tokenPurposePasswordReset     = "PasswordReset"
+	tokenPurposeEmailVerification = "EmailVerification"
+
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+func ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("forgot password: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	genericResponse := gin.H{"message": "If that email is registered, a password reset link has been sent"}
+
+	var user models.User
+	query := `SELECT id, first_name, email FROM users WHERE email = $1`
+	err := database.Pool.QueryRow(c, query, req.Email).Scan(&user.ID, &user.FirstName, &user.Email)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("forgot password: email not found", "email", req.Email)
+			c.JSON(http.StatusOK, genericResponse)
+			return
+		}
+
+		slog.Error("forgot password: failed to fetch user by email", "email", req.Email, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password reset"})
+		return
+	}
+
+	token, err := issueUserToken(c, user.ID, tokenPurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		slog.Error("forgot password: failed to issue reset token", "user_id", user.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password reset"})
+		return
+	}
+
+	sendPasswordResetEmail(user, token)
+
+	slog.Info("forgot password: reset token issued", "user_id", user.ID, "remote_addr", c.ClientIP())
+	c.JSON(http.StatusOK, genericResponse)
+}
+
+func ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("reset password: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		slog.Error("reset password: failed to hash password", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("reset password: failed to begin transaction", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	userID, err := consumeUserToken(c, tx, req.Token, tokenPurposePasswordReset)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("reset password: invalid or expired token")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+			return
+		}
+
+		slog.Error("reset password: failed to consume token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	// Following the emailed link proves ownership of the address, so an
+	// unverified account is verified as part of the reset.
+	updateQuery := `
+		UPDATE users
+		SET password_hash=$1, email_verified_at=COALESCE(email_verified_at, NOW()), updated_at=NOW()
+		WHERE id = $2`
+
+	if _, err := tx.Exec(c, updateQuery, hashedPassword, userID); err != nil {
+		slog.Error("reset password: failed to update password", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if _, err := tx.Exec(c, "DELETE FROM sessions WHERE user_id = $1", userID); err != nil {
+		slog.Error("reset password: failed to revoke sessions", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("reset password: failed to commit transaction", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	slog.Info("reset password: password reset", "user_id", userID, "remote_addr", c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+func VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("verify email: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("verify email: failed to begin transaction", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	userID, err := consumeUserToken(c, tx, req.Token, tokenPurposeEmailVerification)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("verify email: invalid or expired token")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+			return
+		}
+
+		slog.Error("verify email: failed to consume token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	updateQuery := `UPDATE users SET email_verified_at=COALESCE(email_verified_at, NOW()), updated_at=NOW() WHERE id = $1`
+	if _, err := tx.Exec(c, updateQuery, userID); err != nil {
+		slog.Error("verify email: failed to mark email verified", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("verify email: failed to commit transaction", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	slog.Info("verify email: email verified", "user_id", userID)
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+func ResendVerificationEmail(c *gin.Context) {
+	userVal, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	authUser := userVal.(models.User)
+
+	var verifiedAt *time.Time
+	err := database.Pool.QueryRow(c, "SELECT email_verified_at FROM users WHERE id = $1", authUser.ID).Scan(&verifiedAt)
+	if err != nil {
+		slog.Error("resend verification: failed to fetch user", "user_id", authUser.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	if verifiedAt != nil {
+		slog.Debug("resend verification: email already verified", "user_id", authUser.ID)
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	token, err := issueUserToken(c, authUser.ID, tokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		slog.Error("resend verification: failed to issue token", "user_id", authUser.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	sendVerificationEmail(authUser, token)
+
+	slog.Info("resend verification: token issued", "user_id", authUser.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// issueUserToken stores the hash of a fresh single-use token for the user and
+// returns the raw value for delivery. Any outstanding tokens with the same
+// purpose are invalidated so only the most recent link works.
+func issueUserToken(ctx context.Context, userID int, purpose string, ttl time.Duration) (string, error) {
+	token, tokenHash, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	invalidateQuery := `UPDATE user_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	if _, err := tx.Exec(ctx, invalidateQuery, userID, purpose); err != nil {
+		return "", fmt.Errorf("failed to invalidate previous tokens: %w", err)
+	}
+
+	insertQuery := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)`
+
+	if _, err := tx.Exec(ctx, insertQuery, userID, purpose, tokenHash, time.Now().Add(ttl)); err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeUserToken atomically marks a valid token as used and returns the
+// owning user ID, or pgx.ErrNoRows if the token is unknown, used or expired.
+func consumeUserToken(ctx context.Context, tx pgx.Tx, token string, purpose string) (int, error) {
+	var userID int
+	query := `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`
+
+	err := tx.QueryRow(ctx, query, utils.HashSecureToken(token), purpose).Scan(&userID)
+	return userID, err
+}
+
+func buildAppLink(path string, token string) string {
+	base := strings.TrimSuffix(config.Load().AppBaseURL, "/")
+	return base + path + "?token=" + url.QueryEscape(token)
+}
+
+func sendPasswordResetEmail(user models.User, token string) {
+	subject, htmlBody, err := utils.RenderEmailTemplate(context.Background(), utils.EmailTemplatePasswordReset, map[string]any{
+		"FirstName": user.FirstName,
+		"ResetURL":  buildAppLink("/reset-password", token),
+		"ExpiresIn": "1 hour",
+	})
+	if err != nil {
+		slog.Error("password reset email: failed to render template", "user_id", user.ID, "error", err)
+		return
+	}
+
+	if _, err := utils.QueueSensitiveEmail(context.Background(), []string{user.Email}, subject, htmlBody); err != nil {
+		slog.Error("password reset email: failed to queue", "user_id", user.ID, "error", err)
+		return
+	}
+
+	slog.Info("password reset email: queued", "user_id", user.ID)
+}
+
+func sendVerificationEmail(user models.User, token string) {
+	subject, htmlBody, err := utils.RenderEmailTemplate(context.Background(), utils.EmailTemplateEmailVerification, map[string]any{
+		"FirstName": user.FirstName,
+		"VerifyURL": buildAppLink("/verify-email", token),
+		"ExpiresIn": "48 hours",
+	})
+	if err != nil {
+		slog.Error("verification email: failed to render template", "user_id", user.ID, "error", err)
+		return
+	}
+
+	if _, err := utils.QueueSensitiveEmail(context.Background(), []string{user.Email}, subject, htmlBody); err != nil {
+		slog.Error("verification email: failed to queue", "user_id", user.ID, "error", err)
+		return
+	}
+
+	slog.Info("verification email: queued", "user_id", user.ID)
+}
@@ -76,6 +76,8 @@ func UpdateBreeder(c *gin.Context) {
 		return
 	}
 
+	before := snapshotEntity(c, "breeders", id)
+
 	var currentPP *models.Image
 	var currentGallery []models.Image
 	if len(oldPPRaw) > 0 {
@@ -160,6 +162,8 @@ func UpdateBreeder(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditActionUpdate, "breeders", id, before, snapshotEntity(c, "breeders", id))
+
 	slog.Info("update breeder: profile updated", "id", id)
 	c.JSON(http.StatusOK, gin.H{"message": "Breeder profile updated successfully"})
 }
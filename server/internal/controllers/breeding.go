@@ -0,0 +1,575 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+// Puppies usually go home at eight weeks.
+const goHomeAgeDays = 56
+
+// defaultHeatIntervalDays is used to predict the next heat until a female has
+// two recorded cycles to average.
+const defaultHeatIntervalDays = 180
+
+const breedingEventSelect = `
+	SELECT
+		b.id, b.dog_id, d.name, b.type, b.event_date, b.end_date, b.progesterone::float8,
+		b.sire_id, b.sire_external_id, COALESCE(s.name, e.name, b.sire_name, ''), b.due_date, b.litter_id,
+		COALESCE(b.notes, ''), b.created_by, b.created_at, b.updated_at
+	FROM breeding_events b
+	JOIN dogs d ON b.dog_id = d.id
+	LEFT JOIN dogs s ON b.sire_id = s.id
+	LEFT JOIN external_ancestors e ON b.sire_external_id = e.id`
+
+func scanBreedingEvent(row pgx.Row) (models.BreedingEvent, error) {
+	var b models.BreedingEvent
+	err := row.Scan(
+		&b.ID, &b.DogID, &b.DogName, &b.Type, &b.EventDate, &b.EndDate, &b.Progesterone,
+		&b.SireID, &b.SireExternalID, &b.SireName, &b.DueDate, &b.LitterID,
+		&b.Notes, &b.CreatedBy, &b.CreatedAt, &b.UpdatedAt,
+	)
+	return b, err
+}
+
+// GetBreedingEvents lists calendar entries newest first; filter by dog_id for
+// one female's history of heats, progesterone tests and breedings.
+func GetBreedingEvents(c *gin.Context) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+
+	for _, filter := range []struct{ param, column string }{
+		{"dog_id", "b.dog_id"},
+		{"type", "b.type::text"},
+	} {
+		if value := c.Query(filter.param); value != "" {
+			args = append(args, value)
+			where += fmt.Sprintf(" AND %s = $%d", filter.column, len(args))
+		}
+	}
+	for _, filter := range []struct{ param, op string }{{"from", ">="}, {"to", "<="}} {
+		if value := c.Query(filter.param); value != "" {
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": filter.param + " must be in YYYY-MM-DD format"})
+				return
+			}
+			args = append(args, date)
+			where += fmt.Sprintf(" AND b.event_date %s $%d", filter.op, len(args))
+		}
+	}
+
+	rows, err := database.Pool.Query(c, breedingEventSelect+where+" ORDER BY b.event_date DESC, b.id DESC", args...)
+	if err != nil {
+		slog.Error("get breeding events: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch breeding events"})
+		return
+	}
+	defer rows.Close()
+
+	events := []models.BreedingEvent{}
+	for rows.Next() {
+		b, err := scanBreedingEvent(rows)
+		if err != nil {
+			slog.Debug("get breeding events: failed to scan row", "error", err)
+			continue
+		}
+		events = append(events, b)
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+func GetBreedingEvent(c *gin.Context) {
+	id := c.Param("id")
+
+	b, err := scanBreedingEvent(database.Pool.QueryRow(c, breedingEventSelect+" WHERE b.id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("get breeding event: not found", "breeding_event_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Breeding event not found"})
+			return
+		}
+
+		slog.Error("get breeding event: database error", "breeding_event_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch breeding event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, b)
+}
+
+func CreateBreedingEvent(c *gin.Context) {
+	var req models.CreateBreedingEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("create breeding event: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if !slices.Contains(models.BreedingEventTypes, req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid breeding event type"})
+		return
+	}
+	eventDate, err := time.Parse("2006-01-02", req.EventDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Event date must be in YYYY-MM-DD format"})
+		return
+	}
+	endDate, err := parseOptionalDate(req.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End date must be in YYYY-MM-DD format"})
+		return
+	}
+
+	if endDate != nil && req.Type != "Heat" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only a heat cycle has an end date"})
+		return
+	}
+	if endDate != nil && endDate.Before(eventDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End date cannot be before the start of the heat"})
+		return
+	}
+	if (req.Type == "Progesterone") != (req.Progesterone != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A progesterone level is required for, and only allowed on, progesterone tests"})
+		return
+	}
+	hasSire := req.SireID != nil || req.SireExternalID != nil || req.SireName != ""
+	if hasSire && req.Type != "Breeding" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only a breeding has a sire"})
+		return
+	}
+
+	var gender string
+	if err := database.Pool.QueryRow(c, "SELECT gender FROM dogs WHERE id=$1", req.DogID).Scan(&gender); err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Dog not found"})
+			return
+		}
+		slog.Error("create breeding event: failed to check dog", "dog_id", req.DogID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create breeding event"})
+		return
+	}
+	if gender != "Female" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Breeding events can only be recorded for females"})
+		return
+	}
+
+	if !checkPedigreeParents(c, "create breeding event", nil, req.SireID, req.SireExternalID, nil, nil) {
+		return
+	}
+
+	var createdBy *int
+	if userVal, ok := c.Get("user"); ok {
+		user := userVal.(models.User)
+		createdBy = &user.ID
+	}
+
+	var id int
+	query := `
+		INSERT INTO breeding_events (
+			dog_id, type, event_date, end_date, progesterone, sire_id, sire_external_id, sire_name, notes, created_by
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10)
+		RETURNING id`
+
+	err = database.Pool.QueryRow(c, query,
+		req.DogID, req.Type, eventDate, endDate, req.Progesterone,
+		req.SireID, req.SireExternalID, req.SireName, req.Notes, createdBy,
+	).Scan(&id)
+	if err != nil {
+		slog.Error("create breeding event: database error", "dog_id", req.DogID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create breeding event"})
+		return
+	}
+
+	recordAudit(c, auditActionCreate, "breeding_events", id, nil, snapshotEntity(c, "breeding_events", id))
+
+	slog.Info("create breeding event: event created", "breeding_event_id", id, "dog_id", req.DogID, "type", req.Type)
+	c.JSON(http.StatusCreated, gin.H{"message": "Breeding event created", "id": id})
+}
+
+// UpdateBreedingEvent edits an entry. The type and the female can't change;
+// delete and re-enter the event instead.
+func UpdateBreedingEvent(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.UpdateBreedingEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("update breeding event: invalid request body", "breeding_event_id", id, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	var eventType string
+	var eventDate time.Time
+	var endDate *time.Time
+	err := database.Pool.QueryRow(c, "SELECT type, event_date, end_date FROM breeding_events WHERE id=$1", id).Scan(&eventType, &eventDate, &endDate)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("update breeding event: not found", "breeding_event_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Breeding event not found"})
+			return
+		}
+		slog.Error("update breeding event: database error", "breeding_event_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update breeding event"})
+		return
+	}
+
+	sets := "updated_at=NOW()"
+	args := []interface{}{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		sets += fmt.Sprintf(", %s=$%d", column, len(args))
+	}
+
+	if req.EventDate != nil {
+		date, err := time.Parse("2006-01-02", *req.EventDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Event date must be in YYYY-MM-DD format"})
+			return
+		}
+		eventDate = date
+		set("event_date", date)
+	}
+	if req.EndDate != nil {
+		if eventType != "Heat" && *req.EndDate != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only a heat cycle has an end date"})
+			return
+		}
+		date, err := parseOptionalDate(*req.EndDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "End date must be in YYYY-MM-DD format"})
+			return
+		}
+		endDate = date
+		set("end_date", date)
+	}
+	if endDate != nil && endDate.Before(eventDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End date cannot be before the start of the heat"})
+		return
+	}
+	if req.Progesterone != nil {
+		if eventType != "Progesterone" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only a progesterone test has a progesterone level"})
+			return
+		}
+		set("progesterone", *req.Progesterone)
+	}
+
+	if req.ClearSire || req.SireID != nil || req.SireExternalID != nil || req.SireName != nil {
+		if eventType != "Breeding" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only a breeding has a sire"})
+			return
+		}
+	}
+	if req.ClearSire {
+		set("sire_id", nil)
+		set("sire_external_id", nil)
+		set("sire_name", nil)
+	} else {
+		if req.SireID != nil || req.SireExternalID != nil {
+			if !checkPedigreeParents(c, "update breeding event", nil, req.SireID, req.SireExternalID, nil, nil) {
+				return
+			}
+			set("sire_id", req.SireID)
+			set("sire_external_id", req.SireExternalID)
+		}
+		if req.SireName != nil {
+			args = append(args, *req.SireName)
+			sets += fmt.Sprintf(", sire_name=NULLIF($%d, '')", len(args))
+		}
+	}
+	if req.Notes != nil {
+		set("notes", *req.Notes)
+	}
+
+	before := snapshotEntity(c, "breeding_events", id)
+
+	args = append(args, id)
+	result, err := database.Pool.Exec(c, fmt.Sprintf("UPDATE breeding_events SET %s WHERE id=$%d", sets, len(args)), args...)
+	if err != nil {
+		slog.Error("update breeding event: database error", "breeding_event_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update breeding event"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("update breeding event: not found", "breeding_event_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Breeding event not found"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "breeding_events", id, before, snapshotEntity(c, "breeding_events", id))
+
+	slog.Info("update breeding event: event updated", "breeding_event_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Breeding event updated"})
+}
+
+// DeleteBreedingEvent keeps any litter planned from the breeding.
+func DeleteBreedingEvent(c *gin.Context) {
+	id := c.Param("id")
+	before := snapshotEntity(c, "breeding_events", id)
+
+	result, err := database.Pool.Exec(c, "DELETE FROM breeding_events WHERE id=$1", id)
+	if err != nil {
+		slog.Error("delete breeding event: database error", "breeding_event_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete breeding event"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("delete breeding event: not found", "breeding_event_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Breeding event not found"})
+		return
+	}
+
+	recordAudit(c, auditActionDelete, "breeding_events", id, before, nil)
+
+	slog.Info("delete breeding event: event deleted", "breeding_event_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Breeding event deleted"})
+}
+
+// PlanLitterFromBreeding creates a Planned litter for a breeding, born on the
+// due date and going home eight weeks later unless another date is given.
+func PlanLitterFromBreeding(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid breeding event ID"})
+		return
+	}
+
+	// The body is optional; an empty one takes every default.
+	var req models.PlanLitterRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		slog.Debug("plan litter: invalid request body", "breeding_event_id", id, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	availableDate, err := parseOptionalDate(req.AvailableDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Available date must be in YYYY-MM-DD format"})
+		return
+	}
+
+	b, err := scanBreedingEvent(database.Pool.QueryRow(c, breedingEventSelect+" WHERE b.id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Breeding event not found"})
+			return
+		}
+		slog.Error("plan litter: database error", "breeding_event_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan litter"})
+		return
+	}
+	if b.Type != "Breeding" || b.DueDate == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A litter can only be planned from a breeding"})
+		return
+	}
+	if b.LitterID != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A litter has already been planned for this breeding", "litter_id": *b.LitterID})
+		return
+	}
+
+	name := req.Name
+	if name == "" {
+		name = b.DogName + "'s Litter"
+		if b.SireName != "" {
+			name = b.DogName + " x " + b.SireName
+		}
+	}
+	if availableDate == nil {
+		date := b.DueDate.AddDate(0, 0, goHomeAgeDays)
+		availableDate = &date
+	}
+	if availableDate.Before(*b.DueDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Available date cannot be before the due date"})
+		return
+	}
+
+	// Only a free-text sire goes in external_father_name; linked sires are
+	// named from their own records.
+	var externalFather *string
+	if b.SireID == nil && b.SireExternalID == nil && b.SireName != "" {
+		externalFather = &b.SireName
+	}
+
+	coi, err := litterCOI(c, b.SireID, b.SireExternalID, &b.DogID, nil)
+	if err != nil {
+		slog.Warn("plan litter: failed to calculate coi", "breeding_event_id", id, "error", err)
+	}
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("plan litter: failed to begin transaction", "breeding_event_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan litter"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	var litterID int
+	err = tx.QueryRow(c, `
+		INSERT INTO litters (name, mother_id, father_id, father_external_id, external_father_name, birth_date, available_date, status, coi)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, 'Planned', $8)
+		RETURNING id`,
+		name, b.DogID, b.SireID, b.SireExternalID, externalFather, *b.DueDate, *availableDate, coi,
+	).Scan(&litterID)
+	if err != nil {
+		slog.Error("plan litter: failed to create litter", "breeding_event_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan litter"})
+		return
+	}
+
+	// The litter_id check guards against two requests planning at once.
+	result, err := tx.Exec(c, "UPDATE breeding_events SET litter_id=$1, updated_at=NOW() WHERE id=$2 AND litter_id IS NULL", litterID, id)
+	if err != nil {
+		slog.Error("plan litter: failed to link breeding", "breeding_event_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan litter"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A litter has already been planned for this breeding"})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("plan litter: failed to commit transaction", "breeding_event_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan litter"})
+		return
+	}
+
+	recordAudit(c, auditActionCreate, "litters", litterID, nil, snapshotEntity(c, "litters", litterID))
+
+	slog.Info("plan litter: litter planned", "breeding_event_id", id, "litter_id", litterID)
+	c.JSON(http.StatusCreated, gin.H{"message": "Litter planned", "id": litterID})
+}
+
+// loadCalendarEvents collects whelping due dates, predicted heats and litter
+// go-home dates between from and to. A predicted heat stays on the calendar
+// for a month after its date until a new heat is recorded.
+func loadCalendarEvents(c *gin.Context, from, to time.Time) ([]models.CalendarEvent, error) {
+	events := []models.CalendarEvent{}
+
+	rows, err := database.Pool.Query(c, `
+		SELECT b.id, b.dog_id, d.name, b.due_date, b.litter_id
+		FROM breeding_events b
+		JOIN dogs d ON b.dog_id = d.id
+		WHERE b.due_date BETWEEN $1 AND $2
+		ORDER BY b.due_date, b.id`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var breedingID, dogID int
+		var name string
+		var date time.Time
+		var litterID *int
+		if err := rows.Scan(&breedingID, &dogID, &name, &date, &litterID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		events = append(events, models.CalendarEvent{
+			Date: date, Type: "Due Date", Title: name + " due to whelp",
+			DogID: &dogID, LitterID: litterID, BreedingEventID: &breedingID, Estimated: true,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Females with a pregnancy still due are left out of heat predictions.
+	rows, err = database.Pool.Query(c, `
+		WITH heats AS (
+			SELECT dog_id, event_date, event_date - lag(event_date) OVER (PARTITION BY dog_id ORDER BY event_date) AS gap
+			FROM breeding_events
+			WHERE type = 'Heat'
+		)
+		SELECT h.dog_id, d.name, max(h.event_date), COALESCE(round(avg(h.gap)), $1)::int
+		FROM heats h
+		JOIN dogs d ON h.dog_id = d.id
+		WHERE NOT EXISTS (
+			SELECT 1 FROM breeding_events p WHERE p.dog_id = h.dog_id AND p.due_date >= CURRENT_DATE
+		)
+		GROUP BY h.dog_id, d.name`, defaultHeatIntervalDays)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var dogID, interval int
+		var name string
+		var lastHeat time.Time
+		if err := rows.Scan(&dogID, &name, &lastHeat, &interval); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		next := lastHeat.AddDate(0, 0, interval)
+		if next.After(to) || next.Before(from.AddDate(0, 0, -30)) {
+			continue
+		}
+		events = append(events, models.CalendarEvent{
+			Date: next, Type: "Expected Heat", Title: name + " expected in heat", DogID: &dogID, Estimated: true,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = database.Pool.Query(c, `
+		SELECT id, name, available_date
+		FROM litters
+		WHERE status <> 'Sold' AND available_date BETWEEN $1 AND $2
+		ORDER BY available_date, id`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var litterID int
+		var name string
+		var date time.Time
+		if err := rows.Scan(&litterID, &name, &date); err != nil {
+			return nil, err
+		}
+		events = append(events, models.CalendarEvent{
+			Date: date, Type: "Go Home", Title: name + " puppies go home", LitterID: &litterID,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(events, func(a, b models.CalendarEvent) int {
+		return a.Date.Compare(b.Date)
+	})
+	return events, nil
+}
+
+// GetUpcomingBreedingEvents lists calendar dates within ?days (default 60).
+func GetUpcomingBreedingEvents(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "60"))
+	if err != nil || days < 0 || days > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 0 and 365"})
+		return
+	}
+
+	today := time.Now().Truncate(24 * time.Hour)
+	events, err := loadCalendarEvents(c, today, today.AddDate(0, 0, days))
+	if err != nil {
+		slog.Error("get upcoming breeding events: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch upcoming events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/config"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
+)
+
+// Feeds keep a month of history so recent events don't vanish from calendars
+// the moment they pass.
+const (
+	calendarPastDays   = 30
+	calendarFutureDays = 365
+)
+
+// calendarUIDDomain keeps event UIDs globally unique and stable between
+// refreshes, as RFC 5545 requires.
+func calendarUIDDomain() string {
+	if u, err := url.Parse(config.Load().AppBaseURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "aprilslilpugs.com"
+}
+
+func writeCalendar(c *gin.Context, name, filename string, events []utils.ICalEvent) {
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	c.Header("Cache-Control", "private, max-age=900")
+	c.Status(http.StatusOK)
+
+	if err := utils.WriteICalendar(c.Writer, name, events); err != nil {
+		slog.Warn("calendar: failed to write feed", "feed", filename, "error", err)
+	}
+}
+
+// GetLittersCalendar is the public feed of upcoming litters: expected or
+// actual birth dates and go-home days.
+func GetLittersCalendar(c *gin.Context) {
+	base := strings.TrimSuffix(config.Load().AppBaseURL, "/")
+	domain := calendarUIDDomain()
+	today := time.Now().Truncate(24 * time.Hour)
+
+	rows, err := database.Pool.Query(c, `
+		SELECT id, name, birth_date, available_date, status
+		FROM litters
+		WHERE status <> 'Sold' AND available_date >= $1
+		ORDER BY birth_date, id`, today.AddDate(0, 0, -calendarPastDays))
+	if err != nil {
+		slog.Error("get litters calendar: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build calendar"})
+		return
+	}
+	defer rows.Close()
+
+	events := []utils.ICalEvent{}
+	for rows.Next() {
+		var id int
+		var name, status string
+		var birthDate, availableDate time.Time
+		if err := rows.Scan(&id, &name, &birthDate, &availableDate, &status); err != nil {
+			slog.Debug("get litters calendar: failed to scan row", "error", err)
+			continue
+		}
+
+		link := fmt.Sprintf("%s/litter/%d", base, id)
+		expected := status == "Planned" && birthDate.After(today)
+		birthSummary := name + " born"
+		if expected {
+			birthSummary = name + " expected"
+		}
+
+		events = append(events,
+			utils.ICalEvent{
+				UID:       fmt.Sprintf("litter-%d-birth@%s", id, domain),
+				Summary:   birthSummary,
+				URL:       link,
+				Start:     birthDate,
+				Tentative: expected,
+			},
+			utils.ICalEvent{
+				UID:       fmt.Sprintf("litter-%d-go-home@%s", id, domain),
+				Summary:   name + " go-home day",
+				URL:       link,
+				Start:     availableDate,
+				Tentative: expected,
+			},
+		)
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error("get litters calendar: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build calendar"})
+		return
+	}
+
+	writeCalendar(c, "April's Lil Pugs Litters", "litters.ics", events)
+}
+
+// GetAdminCalendar is the private feed: health care coming due, heat cycles,
+// whelping due dates, predicted heats, go-home days and puppy pickups.
+func GetAdminCalendar(c *gin.Context) {
+	adminURL := strings.TrimSuffix(config.Load().AppBaseURL, "/") + "/admin"
+	domain := calendarUIDDomain()
+	today := time.Now().Truncate(24 * time.Hour)
+	from, to := today.AddDate(0, 0, -calendarPastDays), today.AddDate(0, 0, calendarFutureDays)
+
+	events := []utils.ICalEvent{}
+	fail := func(source string, err error) {
+		slog.Error("get admin calendar: database error", "source", source, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build calendar"})
+	}
+
+	due, err := queryHealthRecords(c, " WHERE h.next_due_date BETWEEN $1 AND $2 AND"+healthRecordCurrent, " ORDER BY h.next_due_date, h.id", from, to)
+	if err != nil {
+		fail("health_records", err)
+		return
+	}
+	for _, h := range due {
+		events = append(events, utils.ICalEvent{
+			UID:         fmt.Sprintf("health-%d-due@%s", h.ID, domain),
+			Summary:     fmt.Sprintf("%s due: %s (%s)", h.AnimalName, h.Title, h.Type),
+			Description: h.Notes,
+			URL:         adminURL,
+			Start:       *h.NextDueDate,
+		})
+	}
+
+	// Heats without an end date are shown for their first day only.
+	rows, err := database.Pool.Query(c, `
+		SELECT b.id, d.name, b.event_date, b.end_date, COALESCE(b.notes, '')
+		FROM breeding_events b
+		JOIN dogs d ON b.dog_id = d.id
+		WHERE b.type = 'Heat' AND COALESCE(b.end_date, b.event_date) >= $1 AND b.event_date <= $2
+		ORDER BY b.event_date, b.id`, from, to)
+	if err != nil {
+		fail("breeding_events", err)
+		return
+	}
+	for rows.Next() {
+		var id int
+		var name, notes string
+		var start time.Time
+		var end *time.Time
+		if err := rows.Scan(&id, &name, &start, &end, &notes); err != nil {
+			rows.Close()
+			fail("breeding_events", err)
+			return
+		}
+		event := utils.ICalEvent{
+			UID:         fmt.Sprintf("breeding-%d-heat@%s", id, domain),
+			Summary:     name + " in heat",
+			Description: notes,
+			URL:         adminURL,
+			Start:       start,
+		}
+		if end != nil {
+			event.End = *end
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fail("breeding_events", err)
+		return
+	}
+
+	calendar, err := loadCalendarEvents(c, from, to)
+	if err != nil {
+		fail("breeding calendar", err)
+		return
+	}
+	for _, event := range calendar {
+		var uid string
+		switch {
+		case event.BreedingEventID != nil:
+			uid = fmt.Sprintf("breeding-%d-due@%s", *event.BreedingEventID, domain)
+		case event.LitterID != nil:
+			uid = fmt.Sprintf("litter-%d-go-home@%s", *event.LitterID, domain)
+		case event.DogID != nil:
+			uid = fmt.Sprintf("dog-%d-expected-heat@%s", *event.DogID, domain)
+		default:
+			continue
+		}
+		events = append(events, utils.ICalEvent{
+			UID:       uid,
+			Summary:   event.Title,
+			URL:       adminURL,
+			Start:     event.Date,
+			Tentative: event.Estimated,
+		})
+	}
+
+	rows, err = database.Pool.Query(c, `
+		SELECT r.id, p.name, w.first_name, w.last_name, COALESCE(w.phone, ''), r.pickup_date
+		FROM puppy_reservations r
+		JOIN puppies p ON r.puppy_id = p.id
+		JOIN waitlist w ON r.waitlist_id = w.id
+		WHERE r.status = 'Active' AND r.pickup_date BETWEEN $1 AND $2
+		ORDER BY r.pickup_date, r.id`, from, to)
+	if err != nil {
+		fail("puppy_reservations", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var puppy, firstName, lastName, phone string
+		var pickup time.Time
+		if err := rows.Scan(&id, &puppy, &firstName, &lastName, &phone, &pickup); err != nil {
+			fail("puppy_reservations", err)
+			return
+		}
+		event := utils.ICalEvent{
+			UID:     fmt.Sprintf("reservation-%d-pickup@%s", id, domain),
+			Summary: fmt.Sprintf("%s pickup: %s %s", puppy, firstName, lastName),
+			URL:     adminURL,
+			Start:   pickup,
+		}
+		if phone != "" {
+			event.Description = "Phone: " + phone
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		fail("puppy_reservations", err)
+		return
+	}
+
+	writeCalendar(c, "April's Lil Pugs Admin", "admin.ics", events)
+}
+
+// CreateCalendarFeedToken issues a key for subscribing to the admin feed.
+// It grants nothing but calendar:read, since the URL it goes in is stored by
+// calendar apps and proxies.
+func CreateCalendarFeedToken(c *gin.Context) {
+	var req models.CalendarFeedTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		slog.Debug("create calendar feed token: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Name == "" {
+		req.Name = "Calendar feed"
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	authUser := userVal.(models.User)
+
+	key, err := insertAPIKey(c, req.Name, []string{models.ScopeCalendarRead}, authUser.ID, req.ExpiresAt)
+	if err != nil {
+		slog.Error("create calendar feed token: failed to create key", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed token"})
+		return
+	}
+
+	recordAudit(c, auditActionCreate, "api_keys", key.ID, nil, snapshotEntity(c, "api_keys", key.ID))
+
+	feedURL := strings.TrimSuffix(config.Load().AppBaseURL, "/") + "/api/calendar/admin.ics?token=" + url.QueryEscape(key.Key)
+
+	slog.Info("create calendar feed token: token created", "api_key_id", key.ID, "user_id", authUser.ID)
+	c.JSON(http.StatusCreated, models.CalendarFeedToken{CreateAPIKeyResponse: key, FeedURL: feedURL})
+}
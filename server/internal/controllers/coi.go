@@ -0,0 +1,257 @@
+package controllers
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+)
+
+// coiDefaultGenerations counts the sire and dam as the first generation, so
+// the default looks five generations behind them.
+const coiDefaultGenerations = 6
+
+// coiCalculator computes Wright's coefficient of inbreeding over a pedigree
+// graph loaded up front, so no queries run during path enumeration.
+type coiCalculator struct {
+	graph    map[pedigreeRef]pedigreeRecord
+	maxSteps int
+	memo     map[pedigreeRef]float64
+	active   map[pedigreeRef]bool
+}
+
+type coiPathPair struct {
+	sirePath, damPath []pedigreeRef
+}
+
+// loadCOIGraph loads roots and their ancestors, generations levels deep.
+func loadCOIGraph(c *gin.Context, roots []pedigreeRef, generations int) (map[pedigreeRef]pedigreeRecord, error) {
+	graph := make(map[pedigreeRef]pedigreeRecord)
+	level := roots
+
+	for gen := 1; gen <= generations && len(level) > 0; gen++ {
+		records, err := loadPedigreeRecords(c, level)
+		if err != nil {
+			return nil, err
+		}
+
+		var next []pedigreeRef
+		for ref, rec := range records {
+			graph[ref] = rec
+			for _, parent := range []*pedigreeRef{rec.sire, rec.dam} {
+				if parent != nil {
+					if _, ok := graph[*parent]; !ok {
+						next = append(next, *parent)
+					}
+				}
+			}
+		}
+		level = next
+	}
+
+	return graph, nil
+}
+
+// paths returns every ancestral path from start, keyed by the ancestor it
+// ends at. Each path includes both start and the ancestor.
+func (calc *coiCalculator) paths(start pedigreeRef) map[pedigreeRef][][]pedigreeRef {
+	result := make(map[pedigreeRef][][]pedigreeRef)
+
+	var walk func(path []pedigreeRef)
+	walk = func(path []pedigreeRef) {
+		end := path[len(path)-1]
+		result[end] = append(result[end], append([]pedigreeRef(nil), path...))
+		if len(path)-1 >= calc.maxSteps {
+			return
+		}
+		rec, ok := calc.graph[end]
+		if !ok {
+			return
+		}
+		for _, parent := range []*pedigreeRef{rec.sire, rec.dam} {
+			if parent != nil {
+				walk(append(path, *parent))
+			}
+		}
+	}
+	walk([]pedigreeRef{start})
+
+	return result
+}
+
+// pairing returns the coefficient for offspring of sire and dam along with
+// the independent path pairs through each common ancestor. Two paths are
+// independent when the ancestor is the only animal they share.
+func (calc *coiCalculator) pairing(sire, dam pedigreeRef) (float64, map[pedigreeRef][]coiPathPair) {
+	sirePaths, damPaths := calc.paths(sire), calc.paths(dam)
+	common := make(map[pedigreeRef][]coiPathPair)
+	total := 0.0
+
+	for ancestor, fromSire := range sirePaths {
+		fromDam, ok := damPaths[ancestor]
+		if !ok {
+			continue
+		}
+		for _, p1 := range fromSire {
+			onSireSide := make(map[pedigreeRef]bool, len(p1))
+			for _, ref := range p1[:len(p1)-1] {
+				onSireSide[ref] = true
+			}
+			for _, p2 := range fromDam {
+				independent := true
+				for _, ref := range p2[:len(p2)-1] {
+					if onSireSide[ref] {
+						independent = false
+						break
+					}
+				}
+				if independent {
+					common[ancestor] = append(common[ancestor], coiPathPair{p1, p2})
+				}
+			}
+		}
+	}
+
+	for ancestor, pairs := range common {
+		fa := calc.inbreeding(ancestor)
+		for _, pair := range pairs {
+			total += coiContribution(pair, fa)
+		}
+	}
+
+	return total, common
+}
+
+// inbreeding is the ancestor's own coefficient, which scales its
+// contribution to the pairing.
+func (calc *coiCalculator) inbreeding(ref pedigreeRef) float64 {
+	if f, ok := calc.memo[ref]; ok {
+		return f
+	}
+	rec, ok := calc.graph[ref]
+	if !ok || rec.sire == nil || rec.dam == nil || calc.active[ref] {
+		return 0
+	}
+
+	calc.active[ref] = true
+	f, _ := calc.pairing(*rec.sire, *rec.dam)
+	delete(calc.active, ref)
+
+	calc.memo[ref] = f
+	return f
+}
+
+func coiContribution(pair coiPathPair, ancestorCOI float64) float64 {
+	n := len(pair.sirePath) - 1 + len(pair.damPath) - 1 + 1
+	return math.Pow(0.5, float64(n)) * (1 + ancestorCOI)
+}
+
+func calculateCOI(c *gin.Context, sire, dam pedigreeRef, generations int) (models.COIResult, error) {
+	result := models.COIResult{Generations: generations, CommonAncestors: []models.COICommonAncestor{}}
+
+	graph, err := loadCOIGraph(c, []pedigreeRef{sire, dam}, generations)
+	if err != nil {
+		return result, err
+	}
+
+	calc := &coiCalculator{
+		graph:    graph,
+		maxSteps: generations - 1,
+		memo:     make(map[pedigreeRef]float64),
+		active:   make(map[pedigreeRef]bool),
+	}
+
+	total, common := calc.pairing(sire, dam)
+	result.Sire, result.Dam = graph[sire].node, graph[dam].node
+	result.COI = total
+	result.COIPercent = math.Round(total*10000) / 100
+
+	names := func(path []pedigreeRef) []string {
+		out := make([]string, len(path))
+		for i, ref := range path {
+			out[i] = graph[ref].node.Name
+		}
+		return out
+	}
+
+	for ancestor, pairs := range common {
+		entry := models.COICommonAncestor{
+			Ancestor:    graph[ancestor].node,
+			AncestorCOI: calc.inbreeding(ancestor),
+			Paths:       []models.COIPath{},
+		}
+		for _, pair := range pairs {
+			contribution := coiContribution(pair, entry.AncestorCOI)
+			entry.Contribution += contribution
+			entry.Paths = append(entry.Paths, models.COIPath{
+				SirePath:     names(pair.sirePath),
+				DamPath:      names(pair.damPath),
+				Length:       len(pair.sirePath) + len(pair.damPath) - 1,
+				Contribution: contribution,
+			})
+		}
+		sort.Slice(entry.Paths, func(i, j int) bool { return entry.Paths[i].Length < entry.Paths[j].Length })
+		result.CommonAncestors = append(result.CommonAncestors, entry)
+	}
+
+	sort.Slice(result.CommonAncestors, func(i, j int) bool {
+		a, b := result.CommonAncestors[i], result.CommonAncestors[j]
+		if a.Contribution != b.Contribution {
+			return a.Contribution > b.Contribution
+		}
+		return a.Ancestor.Name < b.Ancestor.Name
+	})
+
+	return result, nil
+}
+
+// litterCOI is stored on litters when both parents are linked to pedigree
+// records. It returns nil when either parent is unknown.
+func litterCOI(c *gin.Context, fatherID, fatherExternalID, motherID, motherExternalID *int) (*float64, error) {
+	sire, dam := pedigreeParentRef(fatherID, fatherExternalID), pedigreeParentRef(motherID, motherExternalID)
+	if sire == nil || dam == nil {
+		return nil, nil
+	}
+
+	result, err := calculateCOI(c, *sire, *dam, coiDefaultGenerations)
+	if err != nil {
+		return nil, err
+	}
+	return &result.COI, nil
+}
+
+// GetPairingCOI calculates the coefficient of inbreeding for a hypothetical
+// litter. The sire and dam may each be a kennel dog or an external ancestor.
+func GetPairingCOI(c *gin.Context) {
+	sireID, sireExternalID := parseOptionalID(c.Query("sire_id")), parseOptionalID(c.Query("sire_external_id"))
+	damID, damExternalID := parseOptionalID(c.Query("dam_id")), parseOptionalID(c.Query("dam_external_id"))
+
+	sire, dam := pedigreeParentRef(sireID, sireExternalID), pedigreeParentRef(damID, damExternalID)
+	if sire == nil || dam == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A sire and a dam are required"})
+		return
+	}
+
+	generations, err := strconv.Atoi(c.DefaultQuery("generations", strconv.Itoa(coiDefaultGenerations)))
+	if err != nil || generations < 2 || generations > pedigreeMaxGenerations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "generations must be between 2 and " + strconv.Itoa(pedigreeMaxGenerations)})
+		return
+	}
+
+	if !checkPedigreeParents(c, "get pairing coi", nil, sireID, sireExternalID, damID, damExternalID) {
+		return
+	}
+
+	result, err := calculateCOI(c, *sire, *dam, generations)
+	if err != nil {
+		slog.Error("get pairing coi: failed to calculate", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate coefficient of inbreeding"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
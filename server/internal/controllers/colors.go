@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+func GetCoatColors(c *gin.Context) {
+	rows, err := database.Pool.Query(c, "SELECT id, name, sort_order, created_at FROM coat_colors ORDER BY sort_order ASC, name ASC")
+	if err != nil {
+		slog.Error("get coat colors: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch colors"})
+		return
+	}
+	defer rows.Close()
+
+	colors := []models.CoatColor{}
+	for rows.Next() {
+		var color models.CoatColor
+		if err := rows.Scan(&color.ID, &color.Name, &color.SortOrder, &color.CreatedAt); err != nil {
+			slog.Debug("get coat colors: failed to scan row", "error", err)
+			continue
+		}
+		colors = append(colors, color)
+	}
+
+	c.JSON(http.StatusOK, colors)
+}
+
+func CreateCoatColor(c *gin.Context) {
+	var req models.CoatColorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("create coat color: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	var id int
+	query := `
+		INSERT INTO coat_colors (name, sort_order)
+		VALUES ($1, COALESCE($2, (SELECT COALESCE(max(sort_order), 0) + 1 FROM coat_colors)))
+		RETURNING id`
+	if err := database.Pool.QueryRow(c, query, name, req.SortOrder).Scan(&id); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "This color already exists"})
+			return
+		}
+		slog.Error("create coat color: database error", "name", name, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save color"})
+		return
+	}
+
+	recordAudit(c, auditActionCreate, "coat_colors", id, nil, snapshotEntity(c, "coat_colors", id))
+
+	slog.Info("create coat color: color created", "color_id", id, "name", name)
+	c.JSON(http.StatusCreated, gin.H{"message": "Color created", "id": id})
+}
+
+// UpdateCoatColor renames or reorders a color. Puppies already linked to it
+// are renamed too, since they were given this color from the list.
+func UpdateCoatColor(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.CoatColorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("update coat color: invalid request body", "color_id", id, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	before := snapshotEntity(c, "coat_colors", id)
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("update coat color: failed to begin transaction", "color_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save color"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	result, err := tx.Exec(c, "UPDATE coat_colors SET name=$1, sort_order=COALESCE($2, sort_order) WHERE id=$3", name, req.SortOrder, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "This color already exists"})
+			return
+		}
+		slog.Error("update coat color: database error", "color_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save color"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("update coat color: not found", "color_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Color not found"})
+		return
+	}
+
+	if _, err := tx.Exec(c, "UPDATE puppies SET color=$1, updated_at=NOW() WHERE color_id=$2 AND color <> $1", name, id); err != nil {
+		slog.Error("update coat color: failed to rename puppy colors", "color_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save color"})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("update coat color: failed to commit transaction", "color_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save color"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "coat_colors", id, before, snapshotEntity(c, "coat_colors", id))
+
+	slog.Info("update coat color: color updated", "color_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Color updated"})
+}
+
+// DeleteCoatColor removes a color from the list. Puppies keep the color name
+// they were given, just no longer linked to the list.
+func DeleteCoatColor(c *gin.Context) {
+	id := c.Param("id")
+	before := snapshotEntity(c, "coat_colors", id)
+
+	result, err := database.Pool.Exec(c, "DELETE FROM coat_colors WHERE id=$1", id)
+	if err != nil {
+		slog.Error("delete coat color: database error", "color_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete color"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("delete coat color: not found", "color_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Color not found"})
+		return
+	}
+
+	recordAudit(c, auditActionDelete, "coat_colors", id, before, nil)
+
+	slog.Info("delete coat color: color deleted", "color_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Color deleted"})
+}
@@ -0,0 +1,444 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
+)
+
+const contractTemplateColumns = `id, name, body, COALESCE(health_guarantee, ''), is_default, created_at, updated_at`
+
+func scanContractTemplate(row pgx.Row) (models.ContractTemplate, error) {
+	var t models.ContractTemplate
+	err := row.Scan(&t.ID, &t.Name, &t.Body, &t.HealthGuarantee, &t.IsDefault, &t.CreatedAt, &t.UpdatedAt)
+	return t, err
+}
+
+func GetContractTemplates(c *gin.Context) {
+	rows, err := database.Pool.Query(c, `SELECT `+contractTemplateColumns+` FROM contract_templates ORDER BY is_default DESC, name ASC`)
+	if err != nil {
+		slog.Error("get contract templates: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contract templates"})
+		return
+	}
+	defer rows.Close()
+
+	templates := []models.ContractTemplate{}
+	for rows.Next() {
+		t, err := scanContractTemplate(rows)
+		if err != nil {
+			slog.Debug("get contract templates: failed to scan row", "error", err)
+			continue
+		}
+		templates = append(templates, t)
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetDefaultContractTemplate returns the built-in contract and the
+// placeholders a template may use, as a starting point for new templates.
+func GetDefaultContractTemplate(c *gin.Context) {
+	placeholders := make([]string, 0, len(utils.ContractSample))
+	for key := range utils.ContractSample {
+		placeholders = append(placeholders, key)
+	}
+	slices.Sort(placeholders)
+
+	c.JSON(http.StatusOK, gin.H{
+		"body":            utils.DefaultContractTemplate,
+		"healthGuarantee": utils.DefaultHealthGuarantee,
+		"placeholders":    placeholders,
+	})
+}
+
+func GetContractTemplate(c *gin.Context) {
+	id := c.Param("id")
+
+	t, err := scanContractTemplate(database.Pool.QueryRow(c, `SELECT `+contractTemplateColumns+` FROM contract_templates WHERE id=$1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("get contract template: not found", "template_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contract template not found"})
+			return
+		}
+
+		slog.Error("get contract template: database error", "template_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contract template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// bindContractTemplate validates the request and renders the template
+// against sample data, so a broken template is rejected before it is saved.
+func bindContractTemplate(c *gin.Context, action string) (models.ContractTemplateRequest, bool) {
+	var req models.ContractTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug(action+": invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return req, false
+	}
+
+	if _, err := utils.RenderContractText(req.Body, utils.ContractSample); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	return req, true
+}
+
+// saveContractTemplate inserts (id 0) or updates a template. Marking one as
+// the default clears the flag on every other template.
+func saveContractTemplate(c *gin.Context, id int, req models.ContractTemplateRequest) (int, error) {
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	if req.IsDefault {
+		if _, err := tx.Exec(c, "UPDATE contract_templates SET is_default=false WHERE is_default AND id <> $1", id); err != nil {
+			return 0, err
+		}
+	}
+
+	if id == 0 {
+		err = tx.QueryRow(c, `
+			INSERT INTO contract_templates (name, body, health_guarantee, is_default)
+			VALUES ($1, $2, NULLIF($3, ''), $4)
+			RETURNING id`, req.Name, req.Body, req.HealthGuarantee, req.IsDefault,
+		).Scan(&id)
+	} else {
+		err = tx.QueryRow(c, `
+			UPDATE contract_templates
+			SET name=$1, body=$2, health_guarantee=NULLIF($3, ''), is_default=$4, updated_at=NOW()
+			WHERE id=$5
+			RETURNING id`, req.Name, req.Body, req.HealthGuarantee, req.IsDefault, id,
+		).Scan(&id)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit(c)
+}
+
+func CreateContractTemplate(c *gin.Context) {
+	req, ok := bindContractTemplate(c, "create contract template")
+	if !ok {
+		return
+	}
+
+	id, err := saveContractTemplate(c, 0, req)
+	if err != nil {
+		slog.Error("create contract template: database error", "name", req.Name, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save contract template"})
+		return
+	}
+
+	recordAudit(c, auditActionCreate, "contract_templates", id, nil, snapshotEntity(c, "contract_templates", id))
+
+	slog.Info("create contract template: template created", "template_id", id, "name", req.Name)
+	c.JSON(http.StatusCreated, gin.H{"message": "Contract template created", "id": id})
+}
+
+func UpdateContractTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contract template ID"})
+		return
+	}
+
+	req, ok := bindContractTemplate(c, "update contract template")
+	if !ok {
+		return
+	}
+
+	before := snapshotEntity(c, "contract_templates", id)
+
+	if _, err := saveContractTemplate(c, id, req); err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("update contract template: not found", "template_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contract template not found"})
+			return
+		}
+		slog.Error("update contract template: database error", "template_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save contract template"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "contract_templates", id, before, snapshotEntity(c, "contract_templates", id))
+
+	slog.Info("update contract template: template updated", "template_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Contract template updated"})
+}
+
+// DeleteContractTemplate leaves contracts already generated from it in the
+// files library.
+func DeleteContractTemplate(c *gin.Context) {
+	id := c.Param("id")
+	before := snapshotEntity(c, "contract_templates", id)
+
+	result, err := database.Pool.Exec(c, "DELETE FROM contract_templates WHERE id=$1", id)
+	if err != nil {
+		slog.Error("delete contract template: database error", "template_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete contract template"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("delete contract template: not found", "template_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contract template not found"})
+		return
+	}
+
+	recordAudit(c, auditActionDelete, "contract_templates", id, before, nil)
+
+	slog.Info("delete contract template: template deleted", "template_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Contract template deleted"})
+}
+
+// contractTemplateFor loads the requested template, else the one marked as
+// default, else the built-in contract.
+func contractTemplateFor(c *gin.Context, templateID *int) (models.ContractTemplate, error) {
+	query := `SELECT ` + contractTemplateColumns + ` FROM contract_templates WHERE is_default`
+	args := []interface{}{}
+	if templateID != nil {
+		query = `SELECT ` + contractTemplateColumns + ` FROM contract_templates WHERE id=$1`
+		args = append(args, *templateID)
+	}
+
+	t, err := scanContractTemplate(database.Pool.QueryRow(c, query, args...))
+	if err == pgx.ErrNoRows && templateID == nil {
+		return models.ContractTemplate{Name: "Puppy Sale Contract", Body: utils.DefaultContractTemplate}, nil
+	}
+	return t, err
+}
+
+// contractData gathers the placeholder values for a puppy's contract from
+// its sale, litter, parents, payments and the breeder profile.
+func contractData(c *gin.Context, puppyID int) (map[string]any, *models.PuppySale, error) {
+	sale, err := scanPuppySale(database.Pool.QueryRow(c, `SELECT `+puppySaleColumns+` FROM puppy_sales WHERE puppy_id=$1`, puppyID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var puppyName, gender, color, microchip, registrationBody, registrationNumber string
+	var litterName, sireName, damName string
+	var birthDate *time.Time
+	var listPrice, depositAmount *float64
+	err = database.Pool.QueryRow(c, `
+		SELECT p.name, p.gender, p.color, COALESCE(p.microchip, ''), COALESCE(p.registration_body, ''),
+			COALESCE(p.registration_number, ''), p.list_price::float8, p.deposit_amount::float8,
+			COALESCE(l.name, ''), l.birth_date,
+			COALESCE(f.name, fe.name, l.external_father_name, ''), COALESCE(m.name, me.name, l.external_mother_name, '')
+		FROM puppies p
+		LEFT JOIN litters l ON p.litter_id = l.id
+		LEFT JOIN dogs f ON l.father_id = f.id
+		LEFT JOIN external_ancestors fe ON l.father_external_id = fe.id
+		LEFT JOIN dogs m ON l.mother_id = m.id
+		LEFT JOIN external_ancestors me ON l.mother_external_id = me.id
+		WHERE p.id = $1`, puppyID,
+	).Scan(
+		&puppyName, &gender, &color, &microchip, &registrationBody,
+		&registrationNumber, &listPrice, &depositAmount,
+		&litterName, &birthDate, &sireName, &damName,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var paid, deposits float64
+	err = database.Pool.QueryRow(c, `
+		SELECT COALESCE(sum(`+netPayment+`), 0)::float8, COALESCE(sum(amount) FILTER (WHERE kind = 'Deposit'), 0)::float8
+		FROM puppy_payments WHERE puppy_id=$1`, puppyID,
+	).Scan(&paid, &deposits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var breederName, breederEmail, breederPhone, breederLocation string
+	err = database.Pool.QueryRow(c, `
+		SELECT first_name || ' ' || last_name, email, phone_number, location
+		FROM breeders ORDER BY id ASC LIMIT 1`,
+	).Scan(&breederName, &breederEmail, &breederPhone, &breederLocation)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, nil, err
+	}
+	if breederName == "" {
+		breederName = "April's Lil Pugs"
+	}
+
+	const dateLayout = "January 2, 2006"
+	money := func(amount *float64) string {
+		if amount == nil {
+			return ""
+		}
+		return utils.FormatMoney(*amount)
+	}
+	// The deposit is what the puppy asks for, or failing that what was paid.
+	if depositAmount == nil && deposits > 0 {
+		depositAmount = &deposits
+	}
+	balance := sale.SalePrice - paid
+	birth := ""
+	if birthDate != nil {
+		birth = birthDate.Format(dateLayout)
+	}
+
+	return map[string]any{
+		"Date":              time.Now().Format(dateLayout),
+		"BreederName":       breederName,
+		"BreederEmail":      breederEmail,
+		"BreederPhone":      breederPhone,
+		"BreederLocation":   breederLocation,
+		"BuyerName":         sale.BuyerName,
+		"BuyerEmail":        sale.BuyerEmail,
+		"BuyerPhone":        sale.BuyerPhone,
+		"BuyerAddress":      sale.BuyerAddress,
+		"PuppyName":         puppyName,
+		"PuppyGender":       gender,
+		"PuppyColor":        color,
+		"PuppyBirthDate":    birth,
+		"PuppyMicrochip":    microchip,
+		"PuppyRegistration": strings.TrimSpace(registrationBody + " " + registrationNumber),
+		"LitterName":        litterName,
+		"SireName":          sireName,
+		"DamName":           damName,
+		"ListPrice":         money(listPrice),
+		"SalePrice":         utils.FormatMoney(sale.SalePrice),
+		"DepositAmount":     money(depositAmount),
+		"AmountPaid":        utils.FormatMoney(paid),
+		"Balance":           utils.FormatMoney(balance),
+		"SaleDate":          sale.SaleDate.Format(dateLayout),
+	}, &sale, nil
+}
+
+// GenerateContract renders a puppy's contract to PDF, adds it to the files
+// library, links it to the sale and, when asked, emails it to the buyer.
+// The sale must be recorded first since it holds the buyer's details.
+func GenerateContract(c *gin.Context) {
+	puppyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid puppy ID"})
+		return
+	}
+
+	// The body is optional; an empty one uses the default template.
+	var req models.GenerateContractRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		slog.Debug("generate contract: invalid request body", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	data, sale, err := contractData(c, puppyID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusConflict, gin.H{"error": "Record the sale before generating a contract"})
+			return
+		}
+		slog.Error("generate contract: failed to load contract data", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate contract"})
+		return
+	}
+	if req.Email && sale.BuyerEmail == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The sale has no buyer email to send the contract to"})
+		return
+	}
+
+	tmpl, err := contractTemplateFor(c, req.TemplateID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contract template not found"})
+			return
+		}
+		slog.Error("generate contract: failed to load template", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate contract"})
+		return
+	}
+	data["HealthGuarantee"] = utils.DefaultHealthGuarantee
+	if tmpl.HealthGuarantee != "" {
+		data["HealthGuarantee"] = tmpl.HealthGuarantee
+	}
+
+	text, err := utils.RenderContractText(tmpl.Body, data)
+	if err != nil {
+		slog.Warn("generate contract: failed to render template", "puppy_id", puppyID, "template_id", tmpl.ID, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	title := fmt.Sprintf("%s - %s - %s", tmpl.Name, data["PuppyName"], sale.BuyerName)
+	file, err := utils.StorePrivateFile("contracts", title+".pdf", utils.ContractPDF(title, text))
+	if err != nil {
+		slog.Error("generate contract: failed to store pdf", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate contract"})
+		return
+	}
+
+	if err := database.Pool.QueryRow(c, "INSERT INTO files (name, url) VALUES ($1, $2) RETURNING id", file.Name, file.URL).Scan(&file.ID); err != nil {
+		slog.Error("generate contract: failed to save file", "puppy_id", puppyID, "error", err)
+		if err := utils.DeleteFile(file.URL); err != nil {
+			slog.Warn("generate contract: failed to clean up pdf", "url", file.URL, "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate contract"})
+		return
+	}
+	recordAudit(c, auditActionCreate, "files", file.ID, nil, snapshotEntity(c, "files", file.ID))
+
+	saleBefore := snapshotEntity(c, "puppy_sales", sale.ID)
+	if _, err := database.Pool.Exec(c, "UPDATE puppy_sales SET contract_file_id=$1, updated_at=NOW() WHERE id=$2", file.ID, sale.ID); err != nil {
+		slog.Warn("generate contract: failed to link contract to sale", "sale_id", sale.ID, "file_id", file.ID, "error", err)
+	} else {
+		recordAudit(c, auditActionUpdate, "puppy_sales", sale.ID, saleBefore, snapshotEntity(c, "puppy_sales", sale.ID))
+	}
+
+	slog.Info("generate contract: contract generated", "puppy_id", puppyID, "file_id", file.ID, "template_id", tmpl.ID)
+
+	if !req.Email {
+		c.JSON(http.StatusCreated, gin.H{"message": "Contract generated", "file": file})
+		return
+	}
+
+	subject := req.Subject
+	if subject == "" {
+		subject = fmt.Sprintf("Your puppy contract for %s - April's Lil Pugs", data["PuppyName"])
+	}
+	subject, htmlBody, err := utils.RenderEmailTemplate(c, utils.EmailTemplateFileAttachment, map[string]any{
+		"Subject":  subject,
+		"Message":  req.Message,
+		"FileName": file.Name,
+	})
+	if err == nil {
+		var emailID int64
+		emailID, err = utils.QueueMessage(c, utils.EmailMessage{
+			To:          []string{sale.BuyerEmail},
+			Subject:     subject,
+			HTMLBody:    htmlBody,
+			Attachments: []models.EmailAttachment{{Name: file.Name, URL: file.URL}},
+		})
+		if err == nil {
+			recordAudit(c, auditActionCreate, "email_outbox", emailID, nil, snapshotEntity(c, "email_outbox", emailID))
+			slog.Info("generate contract: email queued", "puppy_id", puppyID, "file_id", file.ID, "email_id", emailID)
+			c.JSON(http.StatusCreated, gin.H{"message": "Contract generated and emailed", "file": file, "emailId": emailID})
+			return
+		}
+	}
+
+	// The contract is saved either way; it can be resent from the files library.
+	slog.Error("generate contract: failed to email contract", "puppy_id", puppyID, "file_id", file.ID, "error", err)
+	c.JSON(http.StatusCreated, gin.H{"message": "Contract generated but the email could not be queued", "file": file})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,6 +19,7 @@ func GetDogs(c *gin.Context) {
 	query := `
 		SELECT 
 			id, name, gender, description, birth_date,
+			COALESCE(registration_number, ''), sire_id, sire_external_id, dam_id, dam_external_id, litter_id,
 			profile_picture, gallery, created_at, updated_at
 		FROM dogs
 		ORDER BY created_at DESC`
@@ -37,6 +39,7 @@ func GetDogs(c *gin.Context) {
 
 		if err := rows.Scan(
 			&dog.ID, &dog.Name, &dog.Gender, &dog.Description, &dog.BirthDate,
+			&dog.RegistrationNumber, &dog.SireID, &dog.SireExternalID, &dog.DamID, &dog.DamExternalID, &dog.LitterID,
 			&ppRaw, &galleryRaw, &dog.CreatedAt, &dog.UpdatedAt,
 		); err != nil {
 			slog.Debug("get dogs: failed to scan row", "error", err)
@@ -71,12 +74,14 @@ func GetDog(c *gin.Context) {
 	query := `
 		SELECT 
 			id, name, gender, description, birth_date,
+			COALESCE(registration_number, ''), sire_id, sire_external_id, dam_id, dam_external_id, litter_id,
 			profile_picture, gallery, created_at, updated_at
 		FROM dogs
 		WHERE id=$1`
 
 	err := database.Pool.QueryRow(c, query, id).Scan(
 		&dog.ID, &dog.Name, &dog.Gender, &dog.Description, &dog.BirthDate,
+		&dog.RegistrationNumber, &dog.SireID, &dog.SireExternalID, &dog.DamID, &dog.DamExternalID, &dog.LitterID,
 		&ppRaw, &galleryRaw, &dog.CreatedAt, &dog.UpdatedAt,
 	)
 
@@ -110,6 +115,12 @@ func GetDog(c *gin.Context) {
 }
 
 func CreateDog(c *gin.Context) {
+	sireID, sireExternalID := formParentIDs(c, "sireId", "sireExternalId", nil, nil)
+	damID, damExternalID := formParentIDs(c, "damId", "damExternalId", nil, nil)
+	if !checkPedigreeParents(c, "create dog", nil, sireID, sireExternalID, damID, damExternalID) {
+		return
+	}
+
 	profilePic, err := utils.UploadAndCreateImage(c, "profilePicture", "dogs")
 	if err != nil {
 		slog.Warn("create dog: failed to process profile picture", "error", err)
@@ -132,6 +143,7 @@ func CreateDog(c *gin.Context) {
 	name := c.PostForm("name")
 	gender := c.PostForm("gender")
 	desc := c.PostForm("description")
+	registrationNumber := c.PostForm("registrationNumber")
 	birthDate, _ := time.Parse("2006-01-02", c.PostForm("birthDate"))
 
 	ppJSON, err := json.Marshal(profilePic)
@@ -145,25 +157,39 @@ func CreateDog(c *gin.Context) {
 
 	var dogID int
 	query := `
-		INSERT INTO dogs (name, gender, description, birth_date, profile_picture, gallery)
-		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
-
-	err = database.Pool.QueryRow(c, query, name, gender, desc, birthDate, ppJSON, galleryJSON).Scan(&dogID)
+		INSERT INTO dogs (
+			name, gender, description, birth_date, profile_picture, gallery,
+			registration_number, sire_id, sire_external_id, dam_id, dam_external_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+
+	err = database.Pool.QueryRow(c, query,
+		name, gender, desc, birthDate, ppJSON, galleryJSON,
+		registrationNumber, sireID, sireExternalID, damID, damExternalID,
+	).Scan(&dogID)
 	if err != nil {
 		slog.Error("create dog: database error", "name", name, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, auditActionCreate, "dogs", dogID, nil, snapshotEntity(c, "dogs", dogID))
+
 	slog.Info("create dog: dog created", "dog_id", dogID, "name", name)
 	c.JSON(http.StatusCreated, gin.H{"message": "Dog created", "dogId": dogID})
 }
 
 func UpdateDog(c *gin.Context) {
 	id := c.Param("id")
+	before := snapshotEntity(c, "dogs", id)
 
 	var oldPPRaw, oldGalleryRaw []byte
-	err := database.Pool.QueryRow(c, "SELECT profile_picture, gallery FROM dogs WHERE id=$1", id).Scan(&oldPPRaw, &oldGalleryRaw)
+	var current models.Dog
+	err := database.Pool.QueryRow(c, `
+		SELECT profile_picture, gallery, COALESCE(registration_number, ''), sire_id, sire_external_id, dam_id, dam_external_id
+		FROM dogs WHERE id=$1`, id).Scan(
+		&oldPPRaw, &oldGalleryRaw, &current.RegistrationNumber, &current.SireID, &current.SireExternalID, &current.DamID, &current.DamExternalID,
+	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			slog.Debug("update dog: not found", "dog_id", id, "error", err)
@@ -176,6 +202,18 @@ func UpdateDog(c *gin.Context) {
 		return
 	}
 
+	dogID, _ := strconv.Atoi(id)
+	sireID, sireExternalID := formParentIDs(c, "sireId", "sireExternalId", current.SireID, current.SireExternalID)
+	damID, damExternalID := formParentIDs(c, "damId", "damExternalId", current.DamID, current.DamExternalID)
+	if !checkPedigreeParents(c, "update dog", &pedigreeRef{kind: "dog", id: dogID}, sireID, sireExternalID, damID, damExternalID) {
+		return
+	}
+
+	registrationNumber := current.RegistrationNumber
+	if value, ok := c.GetPostForm("registrationNumber"); ok {
+		registrationNumber = value
+	}
+
 	var currentPP *models.Image
 	var currentGallery []models.Image
 	if len(oldPPRaw) > 0 {
@@ -258,9 +296,11 @@ func UpdateDog(c *gin.Context) {
 	}
 
 	_, err = database.Pool.Exec(c, `
-		UPDATE dogs SET name=$1, gender=$2, description=$3, birth_date=$4, profile_picture=$5, gallery=$6, updated_at=NOW()
-		WHERE id=$7`,
-		name, gender, desc, birthDate, ppJSON, galleryJSON, id)
+		UPDATE dogs SET name=$1, gender=$2, description=$3, birth_date=$4, profile_picture=$5, gallery=$6,
+			registration_number=$7, sire_id=$8, sire_external_id=$9, dam_id=$10, dam_external_id=$11, updated_at=NOW()
+		WHERE id=$12`,
+		name, gender, desc, birthDate, ppJSON, galleryJSON,
+		registrationNumber, sireID, sireExternalID, damID, damExternalID, id)
 
 	if err != nil {
 		slog.Error("update dog: database error", "dog_id", id, "error", err)
@@ -268,12 +308,15 @@ func UpdateDog(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditActionUpdate, "dogs", id, before, snapshotEntity(c, "dogs", id))
+
 	slog.Info("update dog: dog updated", "dog_id", id)
 	c.JSON(http.StatusOK, gin.H{"message": "Dog updated successfully"})
 }
 
 func DeleteDog(c *gin.Context) {
 	id := c.Param("id")
+	before := snapshotEntity(c, "dogs", id)
 
 	var ppRaw, galleryRaw []byte
 	if err := database.Pool.QueryRow(c, "SELECT profile_picture, gallery FROM dogs WHERE id=$1", id).Scan(&ppRaw, &galleryRaw); err != nil {
@@ -315,6 +358,8 @@ func DeleteDog(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditActionDelete, "dogs", id, before, nil)
+
 	slog.Info("delete dog: dog deleted", "dog_id", id)
 	c.JSON(http.StatusOK, gin.H{"message": "Dog deleted"})
 }
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+func GetEmailOutbox(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	where := ""
+	args := []interface{}{}
+
+	if status := c.Query("status"); status != "" {
+		where = ` WHERE status = $1`
+		args = append(args, status)
+	}
+
+	var total int
+	if err := database.Pool.QueryRow(c, `SELECT count(*) FROM email_outbox`+where, args...).Scan(&total); err != nil {
+		slog.Error("get email outbox: failed to count messages", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch email outbox"})
+		return
+	}
+
+	query := `
+		SELECT id, recipients, cc, bcc, subject,
+			CASE WHEN sensitive THEN NULL ELSE text_body END,
+			CASE WHEN sensitive THEN '' ELSE html_body END,
+			sensitive, attachments, status, attempts, max_attempts, next_attempt_at,
+			last_error, sent_at, created_at, updated_at
+		FROM email_outbox` + where +
+		fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+
+	rows, err := database.Pool.Query(c, query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		slog.Error("get email outbox: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch email outbox"})
+		return
+	}
+	defer rows.Close()
+
+	emails := []models.OutboxEmail{}
+	for rows.Next() {
+		var e models.OutboxEmail
+		if err := rows.Scan(
+			&e.ID, &e.Recipients, &e.Cc, &e.Bcc, &e.Subject, &e.TextBody, &e.HTMLBody, &e.Sensitive, &e.Attachments, &e.Status, &e.Attempts, &e.MaxAttempts, &e.NextAttemptAt,
+			&e.LastError, &e.SentAt, &e.CreatedAt, &e.UpdatedAt,
+		); err != nil {
+			slog.Debug("get email outbox: failed to scan row", "error", err)
+			continue
+		}
+		emails = append(emails, e)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"emails":   emails,
+		"page":     page,
+		"pageSize": pageSize,
+		"total":    total,
+	})
+}
+
+func ResendEmail(c *gin.Context) {
+	id := c.Param("id")
+	before := snapshotEntity(c, "email_outbox", id)
+
+	query := `
+		UPDATE email_outbox
+		SET status = 'Pending', attempts = 0, next_attempt_at = NOW(), sent_at = NULL, updated_at = NOW()
+		WHERE id = $1`
+
+	result, err := database.Pool.Exec(c, query, id)
+	if err != nil {
+		slog.Error("resend email: database error", "email_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resend email"})
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		slog.Debug("resend email: not found", "email_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email not found"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "email_outbox", id, before, snapshotEntity(c, "email_outbox", id))
+
+	slog.Info("resend email: message requeued", "email_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Email queued for delivery"})
+}
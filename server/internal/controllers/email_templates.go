@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
+)
+
+func GetEmailTemplates(c *gin.Context) {
+	rows, err := database.Pool.Query(c, "SELECT key, subject, html_body, updated_at FROM email_templates")
+	if err != nil {
+		slog.Error("get email templates: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch email templates"})
+		return
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]models.EmailTemplate)
+	for rows.Next() {
+		var t models.EmailTemplate
+		var updatedAt time.Time
+		if err := rows.Scan(&t.Key, &t.Subject, &t.HTMLBody, &updatedAt); err != nil {
+			slog.Debug("get email templates: failed to scan row", "error", err)
+			continue
+		}
+		t.UpdatedAt = &updatedAt
+		overrides[t.Key] = t
+	}
+
+	templates := []models.EmailTemplate{}
+	for _, def := range utils.DefaultEmailTemplates {
+		t := emailTemplateFromDefinition(def)
+		if override, ok := overrides[def.Key]; ok {
+			t.Subject = override.Subject
+			t.HTMLBody = override.HTMLBody
+			t.UpdatedAt = override.UpdatedAt
+			t.IsCustom = true
+		}
+		templates = append(templates, t)
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+func GetEmailTemplate(c *gin.Context) {
+	key := c.Param("key")
+
+	def, ok := utils.FindEmailTemplateDefinition(key)
+	if !ok {
+		slog.Debug("get email template: unknown key", "key", key)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+
+	t := emailTemplateFromDefinition(def)
+
+	var updatedAt time.Time
+	err := database.Pool.QueryRow(c, "SELECT subject, html_body, updated_at FROM email_templates WHERE key = $1", key).Scan(&t.Subject, &t.HTMLBody, &updatedAt)
+	if err == nil {
+		t.UpdatedAt = &updatedAt
+		t.IsCustom = true
+	} else if err != pgx.ErrNoRows {
+		slog.Error("get email template: database error", "key", key, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch email template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+func UpdateEmailTemplate(c *gin.Context) {
+	key := c.Param("key")
+
+	def, ok := utils.FindEmailTemplateDefinition(key)
+	if !ok {
+		slog.Debug("update email template: unknown key", "key", key)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+
+	var req models.UpdateEmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("update email template: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, _, err := utils.ExecuteEmailTemplate(key, req.Subject, req.HTMLBody, def.Sample); err != nil {
+		slog.Debug("update email template: template failed validation", "key", key, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var id int
+	var before map[string]any
+	if err := database.Pool.QueryRow(c, "SELECT id FROM email_templates WHERE key = $1", key).Scan(&id); err == nil {
+		before = snapshotEntity(c, "email_templates", id)
+	}
+
+	query := `
+		INSERT INTO email_templates (key, subject, html_body)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET subject = EXCLUDED.subject, html_body = EXCLUDED.html_body, updated_at = NOW()
+		RETURNING id`
+
+	if err := database.Pool.QueryRow(c, query, key, req.Subject, req.HTMLBody).Scan(&id); err != nil {
+		slog.Error("update email template: database error", "key", key, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update email template"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "email_templates", id, before, snapshotEntity(c, "email_templates", id))
+
+	slog.Info("update email template: template updated", "key", key)
+	c.JSON(http.StatusOK, gin.H{"message": "Email template updated"})
+}
+
+func ResetEmailTemplate(c *gin.Context) {
+	key := c.Param("key")
+
+	if _, ok := utils.FindEmailTemplateDefinition(key); !ok {
+		slog.Debug("reset email template: unknown key", "key", key)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+
+	var id int
+	if err := database.Pool.QueryRow(c, "SELECT id FROM email_templates WHERE key = $1", key).Scan(&id); err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusOK, gin.H{"message": "Email template already uses the default"})
+			return
+		}
+
+		slog.Error("reset email template: database error", "key", key, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset email template"})
+		return
+	}
+
+	before := snapshotEntity(c, "email_templates", id)
+
+	if _, err := database.Pool.Exec(c, "DELETE FROM email_templates WHERE id = $1", id); err != nil {
+		slog.Error("reset email template: database error", "key", key, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset email template"})
+		return
+	}
+
+	recordAudit(c, auditActionDelete, "email_templates", id, before, nil)
+
+	slog.Info("reset email template: template reset to default", "key", key)
+	c.JSON(http.StatusOK, gin.H{"message": "Email template reset to default"})
+}
+
+func PreviewEmailTemplate(c *gin.Context) {
+	key := c.Param("key")
+
+	def, ok := utils.FindEmailTemplateDefinition(key)
+	if !ok {
+		slog.Debug("preview email template: unknown key", "key", key)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+
+	var req models.UpdateEmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("preview email template: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subject, body, err := utils.ExecuteEmailTemplate(key, req.Subject, req.HTMLBody, def.Sample)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subject": subject, "htmlBody": body})
+}
+
+func emailTemplateFromDefinition(def utils.EmailTemplateDefinition) models.EmailTemplate {
+	return models.EmailTemplate{
+		Key:         def.Key,
+		Description: def.Description,
+		Subject:     def.Subject,
+		HTMLBody:    def.HTMLBody,
+		Variables:   slices.Sorted(maps.Keys(def.Sample)),
+	}
+}
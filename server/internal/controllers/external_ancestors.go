@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+const externalAncestorColumns = `
+	id, name, gender, COALESCE(registration_number, ''), COALESCE(registry, ''), birth_date,
+	COALESCE(color, ''), COALESCE(breeder, ''), sire_id, sire_external_id, dam_id, dam_external_id,
+	COALESCE(notes, ''), created_at, updated_at`
+
+func scanExternalAncestor(row pgx.Row) (models.ExternalAncestor, error) {
+	var a models.ExternalAncestor
+	err := row.Scan(
+		&a.ID, &a.Name, &a.Gender, &a.RegistrationNumber, &a.Registry, &a.BirthDate,
+		&a.Color, &a.Breeder, &a.SireID, &a.SireExternalID, &a.DamID, &a.DamExternalID,
+		&a.Notes, &a.CreatedAt, &a.UpdatedAt,
+	)
+	return a, err
+}
+
+func GetExternalAncestors(c *gin.Context) {
+	query := `SELECT ` + externalAncestorColumns + ` FROM external_ancestors`
+	args := []interface{}{}
+
+	if search := c.Query("search"); search != "" {
+		query += ` WHERE name ILIKE $1 OR registration_number ILIKE $1`
+		args = append(args, "%"+search+"%")
+	}
+	query += ` ORDER BY name ASC, id ASC`
+
+	rows, err := database.Pool.Query(c, query, args...)
+	if err != nil {
+		slog.Error("get external ancestors: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch external ancestors"})
+		return
+	}
+	defer rows.Close()
+
+	ancestors := []models.ExternalAncestor{}
+	for rows.Next() {
+		a, err := scanExternalAncestor(rows)
+		if err != nil {
+			slog.Debug("get external ancestors: failed to scan row", "error", err)
+			continue
+		}
+		ancestors = append(ancestors, a)
+	}
+
+	c.JSON(http.StatusOK, ancestors)
+}
+
+func GetExternalAncestor(c *gin.Context) {
+	id := c.Param("id")
+
+	a, err := scanExternalAncestor(database.Pool.QueryRow(c, `SELECT `+externalAncestorColumns+` FROM external_ancestors WHERE id=$1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("get external ancestor: not found", "ancestor_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "External ancestor not found"})
+			return
+		}
+
+		slog.Error("get external ancestor: database error", "ancestor_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch external ancestor"})
+		return
+	}
+
+	c.JSON(http.StatusOK, a)
+}
+
+// bindExternalAncestor validates the request body and its parent links,
+// writing the error response itself when it returns false.
+func bindExternalAncestor(c *gin.Context, action string, self *pedigreeRef) (models.ExternalAncestorRequest, bool) {
+	var req models.ExternalAncestorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug(action+": invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return req, false
+	}
+
+	if _, err := parseOptionalDate(req.BirthDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Birth date must be in YYYY-MM-DD format"})
+		return req, false
+	}
+
+	return req, checkPedigreeParents(c, action, self, req.SireID, req.SireExternalID, req.DamID, req.DamExternalID)
+}
+
+func CreateExternalAncestor(c *gin.Context) {
+	req, ok := bindExternalAncestor(c, "create external ancestor", nil)
+	if !ok {
+		return
+	}
+	birthDate, _ := parseOptionalDate(req.BirthDate)
+
+	var id int
+	query := `
+		INSERT INTO external_ancestors (
+			name, gender, registration_number, registry, birth_date, color, breeder,
+			sire_id, sire_external_id, dam_id, dam_external_id, notes
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id`
+
+	err := database.Pool.QueryRow(c, query,
+		req.Name, req.Gender, req.RegistrationNumber, req.Registry, birthDate, req.Color, req.Breeder,
+		req.SireID, req.SireExternalID, req.DamID, req.DamExternalID, req.Notes,
+	).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "An external ancestor with this registration number already exists"})
+			return
+		}
+		slog.Error("create external ancestor: database error", "name", req.Name, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save external ancestor"})
+		return
+	}
+
+	recordAudit(c, auditActionCreate, "external_ancestors", id, nil, snapshotEntity(c, "external_ancestors", id))
+
+	slog.Info("create external ancestor: ancestor created", "ancestor_id", id, "name", req.Name)
+	c.JSON(http.StatusCreated, gin.H{"message": "External ancestor created", "id": id})
+}
+
+func UpdateExternalAncestor(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid external ancestor ID"})
+		return
+	}
+
+	req, ok := bindExternalAncestor(c, "update external ancestor", &pedigreeRef{kind: "external", id: id})
+	if !ok {
+		return
+	}
+	birthDate, _ := parseOptionalDate(req.BirthDate)
+
+	before := snapshotEntity(c, "external_ancestors", id)
+
+	query := `
+		UPDATE external_ancestors
+		SET name=$1, gender=$2, registration_number=$3, registry=$4, birth_date=$5, color=$6, breeder=$7,
+			sire_id=$8, sire_external_id=$9, dam_id=$10, dam_external_id=$11, notes=$12, updated_at=NOW()
+		WHERE id=$13`
+
+	result, err := database.Pool.Exec(c, query,
+		req.Name, req.Gender, req.RegistrationNumber, req.Registry, birthDate, req.Color, req.Breeder,
+		req.SireID, req.SireExternalID, req.DamID, req.DamExternalID, req.Notes, id,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "An external ancestor with this registration number already exists"})
+			return
+		}
+		slog.Error("update external ancestor: database error", "ancestor_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save external ancestor"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("update external ancestor: not found", "ancestor_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "External ancestor not found"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "external_ancestors", id, before, snapshotEntity(c, "external_ancestors", id))
+
+	slog.Info("update external ancestor: ancestor updated", "ancestor_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "External ancestor updated"})
+}
+
+// DeleteExternalAncestor removes the record; dogs, litters and other
+// ancestors that referenced it keep their other links.
+func DeleteExternalAncestor(c *gin.Context) {
+	id := c.Param("id")
+	before := snapshotEntity(c, "external_ancestors", id)
+
+	result, err := database.Pool.Exec(c, "DELETE FROM external_ancestors WHERE id=$1", id)
+	if err != nil {
+		slog.Error("delete external ancestor: database error", "ancestor_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete external ancestor"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("delete external ancestor: not found", "ancestor_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "External ancestor not found"})
+		return
+	}
+
+	recordAudit(c, auditActionDelete, "external_ancestors", id, before, nil)
+
+	slog.Info("delete external ancestor: ancestor deleted", "ancestor_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "External ancestor deleted"})
+}
@@ -3,6 +3,8 @@ package controllers
 import (
 	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
@@ -54,12 +56,15 @@ func CreateFile(c *gin.Context) {
 	}
 
 	file.ID = id
+	recordAudit(c, auditActionCreate, "files", id, nil, snapshotEntity(c, "files", id))
+
 	slog.Info("create file: file created", "file_id", id, "name", file.Name)
 	c.JSON(http.StatusCreated, file)
 }
 
 func DeleteFile(c *gin.Context) {
 	id := c.Param("id")
+	before := snapshotEntity(c, "files", id)
 	var url string
 
 	err := database.Pool.QueryRow(c, "SELECT url FROM files WHERE id=$1", id).Scan(&url)
@@ -86,6 +91,105 @@ func DeleteFile(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditActionDelete, "files", id, before, nil)
+
 	slog.Info("delete file: file deleted", "file_id", id)
 	c.JSON(http.StatusOK, gin.H{"message": "File deleted"})
 }
+
+func EmailFile(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.EmailFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("email file: invalid request body", "file_id", id, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	var f models.File
+	err := database.Pool.QueryRow(c, "SELECT id, name, url FROM files WHERE id=$1", id).Scan(&f.ID, &f.Name, &f.URL)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("email file: not found", "file_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+
+		slog.Error("email file: database error", "file_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to email file"})
+		return
+	}
+
+	fileName := f.Name
+	if filepath.Ext(fileName) == "" {
+		fileName += filepath.Ext(f.URL)
+	}
+
+	subject, htmlBody, err := utils.RenderEmailTemplate(c, utils.EmailTemplateFileAttachment, map[string]any{
+		"Subject":  req.Subject,
+		"Message":  req.Message,
+		"FileName": fileName,
+	})
+	if err != nil {
+		slog.Error("email file: failed to render template", "file_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to email file"})
+		return
+	}
+
+	emailID, err := utils.QueueMessage(c, utils.EmailMessage{
+		To:       req.To,
+		Cc:       req.Cc,
+		Bcc:      req.Bcc,
+		Subject:  subject,
+		HTMLBody: htmlBody,
+		Attachments: []models.EmailAttachment{
+			{Name: fileName, URL: f.URL},
+		},
+	})
+	if err != nil {
+		slog.Error("email file: failed to queue email", "file_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to email file"})
+		return
+	}
+
+	recordAudit(c, auditActionCreate, "email_outbox", emailID, nil, snapshotEntity(c, "email_outbox", emailID))
+
+	slog.Info("email file: email queued", "file_id", id, "email_id", emailID, "recipient_count", len(req.To)+len(req.Cc)+len(req.Bcc))
+	c.JSON(http.StatusAccepted, gin.H{"message": "Email queued", "emailId": emailID})
+}
+
+// DownloadPrivateFile serves a files-library entry kept in private storage,
+// such as a signed contract, to authenticated callers only.
+func DownloadPrivateFile(c *gin.Context) {
+	fileURL := utils.PrivateFilesURLBase + c.Param("filepath")
+
+	var name string
+	err := database.Pool.QueryRow(c, "SELECT name FROM files WHERE url=$1", fileURL).Scan(&name)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("download private file: not found", "url", fileURL)
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+
+		slog.Error("download private file: database error", "url", fileURL, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download file"})
+		return
+	}
+
+	absPath, err := utils.PrivateFilePath(fileURL)
+	if err != nil || absPath == "" {
+		slog.Warn("download private file: invalid storage path", "url", fileURL, "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		slog.Error("download private file: missing from storage", "url", fileURL, "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	slog.Info("download private file: file served", "url", fileURL)
+	c.FileAttachment(absPath, name)
+}
@@ -0,0 +1,409 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+const healthRecordSelect = `
+	SELECT
+		h.id, h.dog_id, h.puppy_id, COALESCE(d.name, p.name, ''), h.type, h.title, h.record_date, h.next_due_date,
+		COALESCE(h.veterinarian, ''), COALESCE(h.result, ''), COALESCE(h.lab, ''), h.certificate_file_id, f.name, f.url,
+		COALESCE(h.notes, ''), h.is_public, h.reminder_sent_at, h.created_by, h.created_at, h.updated_at
+	FROM health_records h
+	LEFT JOIN dogs d ON h.dog_id = d.id
+	LEFT JOIN puppies p ON h.puppy_id = p.id
+	LEFT JOIN files f ON h.certificate_file_id = f.id`
+
+// healthRecordCurrent excludes records whose next dose or check-up has
+// already been entered as a later record of the same type and title.
+const healthRecordCurrent = `
+	NOT EXISTS (
+		SELECT 1 FROM health_records n
+		WHERE n.dog_id IS NOT DISTINCT FROM h.dog_id
+			AND n.puppy_id IS NOT DISTINCT FROM h.puppy_id
+			AND n.type = h.type AND n.title = h.title
+			AND n.record_date > h.record_date
+	)`
+
+func scanHealthRecord(row pgx.Row) (models.HealthRecord, error) {
+	var h models.HealthRecord
+	var fileName, fileURL *string
+	err := row.Scan(
+		&h.ID, &h.DogID, &h.PuppyID, &h.AnimalName, &h.Type, &h.Title, &h.RecordDate, &h.NextDueDate,
+		&h.Veterinarian, &h.Result, &h.Lab, &h.CertificateFileID, &fileName, &fileURL,
+		&h.Notes, &h.IsPublic, &h.ReminderSentAt, &h.CreatedBy, &h.CreatedAt, &h.UpdatedAt,
+	)
+	if err == nil && h.CertificateFileID != nil && fileName != nil && fileURL != nil {
+		h.Certificate = &models.File{ID: *h.CertificateFileID, Name: *fileName, URL: *fileURL}
+	}
+	return h, err
+}
+
+func queryHealthRecords(c *gin.Context, where string, order string, args ...interface{}) ([]models.HealthRecord, error) {
+	rows, err := database.Pool.Query(c, healthRecordSelect+where+order, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []models.HealthRecord{}
+	for rows.Next() {
+		h, err := scanHealthRecord(rows)
+		if err != nil {
+			slog.Debug("health records: failed to scan row", "error", err)
+			continue
+		}
+		records = append(records, h)
+	}
+	return records, rows.Err()
+}
+
+// GetHealthRecords lists records newest first, so filtering by dog_id or
+// puppy_id gives that animal's full health timeline.
+func GetHealthRecords(c *gin.Context) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+
+	for _, filter := range []struct{ param, column string }{
+		{"dog_id", "h.dog_id"},
+		{"puppy_id", "h.puppy_id"},
+		{"type", "h.type::text"},
+	} {
+		if value := c.Query(filter.param); value != "" {
+			args = append(args, value)
+			where += fmt.Sprintf(" AND %s = $%d", filter.column, len(args))
+		}
+	}
+	for _, filter := range []struct{ param, op string }{{"from", ">="}, {"to", "<="}} {
+		if value := c.Query(filter.param); value != "" {
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": filter.param + " must be in YYYY-MM-DD format"})
+				return
+			}
+			args = append(args, date)
+			where += fmt.Sprintf(" AND h.record_date %s $%d", filter.op, len(args))
+		}
+	}
+
+	records, err := queryHealthRecords(c, where, " ORDER BY h.record_date DESC, h.id DESC", args...)
+	if err != nil {
+		slog.Error("get health records: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch health records"})
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+// GetDueHealthRecords lists scheduled doses and check-ups due within ?days
+// (default 30), including overdue ones that haven't been recorded yet.
+func GetDueHealthRecords(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 0 || days > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 0 and 365"})
+		return
+	}
+
+	where := ` WHERE h.next_due_date <= CURRENT_DATE + $1::int AND` + healthRecordCurrent
+	records, err := queryHealthRecords(c, where, " ORDER BY h.next_due_date ASC, h.id ASC", days)
+	if err != nil {
+		slog.Error("get due health records: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch health records"})
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+func GetHealthRecord(c *gin.Context) {
+	id := c.Param("id")
+
+	h, err := scanHealthRecord(database.Pool.QueryRow(c, healthRecordSelect+" WHERE h.id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("get health record: not found", "health_record_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Health record not found"})
+			return
+		}
+
+		slog.Error("get health record: database error", "health_record_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch health record"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h)
+}
+
+// GetDogHealth is the public health timeline shown on a dog's page.
+func GetDogHealth(c *gin.Context) {
+	getPublicHealthTimeline(c, "dogs", "h.dog_id", "Dog not found")
+}
+
+// GetPuppyHealth is the public health timeline shown on a puppy's page.
+func GetPuppyHealth(c *gin.Context) {
+	getPublicHealthTimeline(c, "puppies", "h.puppy_id", "Puppy not found")
+}
+
+func getPublicHealthTimeline(c *gin.Context, table, column, notFound string) {
+	id := c.Param("id")
+
+	var exists bool
+	if err := database.Pool.QueryRow(c, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id=$1)", table), id).Scan(&exists); err != nil {
+		slog.Error("get health timeline: database error", "table", table, "id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch health records"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+
+	records, err := queryHealthRecords(c, fmt.Sprintf(" WHERE %s = $1 AND h.is_public", column), " ORDER BY h.record_date DESC, h.id DESC", id)
+	if err != nil {
+		slog.Error("get health timeline: database error", "table", table, "id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch health records"})
+		return
+	}
+
+	timeline := make([]models.PublicHealthRecord, 0, len(records))
+	for _, h := range records {
+		timeline = append(timeline, models.PublicHealthRecord{
+			ID:          h.ID,
+			Type:        h.Type,
+			Title:       h.Title,
+			RecordDate:  h.RecordDate,
+			Result:      h.Result,
+			Lab:         h.Lab,
+			Certificate: h.Certificate,
+		})
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+func certificateFileExists(c *gin.Context, fileID int) (bool, error) {
+	var exists bool
+	err := database.Pool.QueryRow(c, "SELECT EXISTS (SELECT 1 FROM files WHERE id=$1)", fileID).Scan(&exists)
+	return exists, err
+}
+
+func CreateHealthRecord(c *gin.Context) {
+	var req models.CreateHealthRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("create health record: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if (req.DogID == nil) == (req.PuppyID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A health record belongs to exactly one dog or puppy"})
+		return
+	}
+	if !slices.Contains(models.HealthRecordTypes, req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid health record type"})
+		return
+	}
+	recordDate, err := time.Parse("2006-01-02", req.RecordDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Record date must be in YYYY-MM-DD format"})
+		return
+	}
+	nextDueDate, err := parseOptionalDate(req.NextDueDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Next due date must be in YYYY-MM-DD format"})
+		return
+	}
+
+	table, animalID, notFound := "dogs", req.DogID, "Dog not found"
+	if req.PuppyID != nil {
+		table, animalID, notFound = "puppies", req.PuppyID, "Puppy not found"
+	}
+	var exists bool
+	if err := database.Pool.QueryRow(c, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id=$1)", table), *animalID).Scan(&exists); err != nil {
+		slog.Error("create health record: failed to check animal", "table", table, "id", *animalID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create health record"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": notFound})
+		return
+	}
+
+	if req.CertificateFileID != nil {
+		exists, err := certificateFileExists(c, *req.CertificateFileID)
+		if err != nil {
+			slog.Error("create health record: failed to check certificate", "file_id", *req.CertificateFileID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create health record"})
+			return
+		}
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Certificate file not found"})
+			return
+		}
+	}
+
+	var createdBy *int
+	if userVal, ok := c.Get("user"); ok {
+		user := userVal.(models.User)
+		createdBy = &user.ID
+	}
+
+	var id int
+	query := `
+		INSERT INTO health_records (
+			dog_id, puppy_id, type, title, record_date, next_due_date, veterinarian,
+			result, lab, certificate_file_id, notes, is_public, created_by
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id`
+
+	err = database.Pool.QueryRow(c, query,
+		req.DogID, req.PuppyID, req.Type, req.Title, recordDate, nextDueDate, req.Veterinarian,
+		req.Result, req.Lab, req.CertificateFileID, req.Notes, req.IsPublic, createdBy,
+	).Scan(&id)
+	if err != nil {
+		slog.Error("create health record: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create health record"})
+		return
+	}
+
+	recordAudit(c, auditActionCreate, "health_records", id, nil, snapshotEntity(c, "health_records", id))
+
+	slog.Info("create health record: record created", "health_record_id", id, "type", req.Type)
+	c.JSON(http.StatusCreated, gin.H{"message": "Health record created", "id": id})
+}
+
+func UpdateHealthRecord(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.UpdateHealthRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("update health record: invalid request body", "health_record_id", id, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	sets := "updated_at=NOW()"
+	args := []interface{}{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		sets += fmt.Sprintf(", %s=$%d", column, len(args))
+	}
+
+	if req.Type != nil {
+		if !slices.Contains(models.HealthRecordTypes, *req.Type) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid health record type"})
+			return
+		}
+		set("type", *req.Type)
+	}
+	if req.Title != nil {
+		if *req.Title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+			return
+		}
+		set("title", *req.Title)
+	}
+	if req.RecordDate != nil {
+		date, err := time.Parse("2006-01-02", *req.RecordDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Record date must be in YYYY-MM-DD format"})
+			return
+		}
+		set("record_date", date)
+	}
+	if req.NextDueDate != nil {
+		date, err := parseOptionalDate(*req.NextDueDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Next due date must be in YYYY-MM-DD format"})
+			return
+		}
+		set("next_due_date", date)
+		// A new due date deserves its own reminder.
+		sets += ", reminder_sent_at=NULL"
+	}
+	if req.Veterinarian != nil {
+		set("veterinarian", *req.Veterinarian)
+	}
+	if req.Result != nil {
+		set("result", *req.Result)
+	}
+	if req.Lab != nil {
+		set("lab", *req.Lab)
+	}
+	if req.ClearCertificate {
+		set("certificate_file_id", nil)
+	} else if req.CertificateFileID != nil {
+		exists, err := certificateFileExists(c, *req.CertificateFileID)
+		if err != nil {
+			slog.Error("update health record: failed to check certificate", "health_record_id", id, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update health record"})
+			return
+		}
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Certificate file not found"})
+			return
+		}
+		set("certificate_file_id", *req.CertificateFileID)
+	}
+	if req.Notes != nil {
+		set("notes", *req.Notes)
+	}
+	if req.IsPublic != nil {
+		set("is_public", *req.IsPublic)
+	}
+
+	before := snapshotEntity(c, "health_records", id)
+
+	args = append(args, id)
+	result, err := database.Pool.Exec(c, fmt.Sprintf("UPDATE health_records SET %s WHERE id=$%d", sets, len(args)), args...)
+	if err != nil {
+		slog.Error("update health record: database error", "health_record_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update health record"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("update health record: not found", "health_record_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Health record not found"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "health_records", id, before, snapshotEntity(c, "health_records", id))
+
+	slog.Info("update health record: record updated", "health_record_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Health record updated"})
+}
+
+// DeleteHealthRecord leaves any certificate in the files library.
+func DeleteHealthRecord(c *gin.Context) {
+	id := c.Param("id")
+	before := snapshotEntity(c, "health_records", id)
+
+	result, err := database.Pool.Exec(c, "DELETE FROM health_records WHERE id=$1", id)
+	if err != nil {
+		slog.Error("delete health record: database error", "health_record_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete health record"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("delete health record: not found", "health_record_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Health record not found"})
+		return
+	}
+
+	recordAudit(c, auditActionDelete, "health_records", id, before, nil)
+
+	slog.Info("delete health record: record deleted", "health_record_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Health record deleted"})
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
@@ -30,14 +32,16 @@ func GetLitters(c *gin.Context) {
 	query := `
 		SELECT 
 			l.id, l.name, l.birth_date, l.available_date, l.status,
-			l.mother_id, l.father_id,
+			l.mother_id, l.father_id, l.mother_external_id, l.father_external_id, l.coi,
 			l.external_mother_name, l.external_father_name,
-			COALESCE(m.name, l.external_mother_name, 'Unknown') as mother_display_name,
-			COALESCE(f.name, l.external_father_name, 'Unknown') as father_display_name,
+			COALESCE(m.name, me.name, l.external_mother_name, 'Unknown') as mother_display_name,
+			COALESCE(f.name, fe.name, l.external_father_name, 'Unknown') as father_display_name,
 			l.profile_picture, l.gallery, l.created_at, l.updated_at
 		FROM litters l
 		LEFT JOIN dogs m ON l.mother_id = m.id
 		LEFT JOIN dogs f ON l.father_id = f.id
+		LEFT JOIN external_ancestors me ON l.mother_external_id = me.id
+		LEFT JOIN external_ancestors fe ON l.father_external_id = fe.id
 		ORDER BY l.created_at DESC`
 
 	rows, err := database.Pool.Query(c, query)
@@ -57,7 +61,7 @@ func GetLitters(c *gin.Context) {
 
 		err := rows.Scan(
 			&l.ID, &l.Name, &l.BirthDate, &l.AvailableDate, &l.Status,
-			&l.MotherID, &l.FatherID,
+			&l.MotherID, &l.FatherID, &l.MotherExternalID, &l.FatherExternalID, &l.COI,
 			&extMother, &extFather,
 			&mName, &fName,
 			&ppRaw, &galleryRaw, &l.CreatedAt, &l.UpdatedAt,
@@ -105,19 +109,21 @@ func GetLitter(c *gin.Context) {
 	query := `
 		SELECT 
 			l.id, l.name, l.birth_date, l.available_date, l.status,
-			l.mother_id, l.father_id,
+			l.mother_id, l.father_id, l.mother_external_id, l.father_external_id, l.coi,
 			l.external_mother_name, l.external_father_name,
-			COALESCE(m.name, l.external_mother_name, 'Unknown') as mother_display_name,
-			COALESCE(f.name, l.external_father_name, 'Unknown') as father_display_name,
+			COALESCE(m.name, me.name, l.external_mother_name, 'Unknown') as mother_display_name,
+			COALESCE(f.name, fe.name, l.external_father_name, 'Unknown') as father_display_name,
 			l.profile_picture, l.gallery, l.created_at, l.updated_at
 		FROM litters l
 		LEFT JOIN dogs m ON l.mother_id = m.id
 		LEFT JOIN dogs f ON l.father_id = f.id
+		LEFT JOIN external_ancestors me ON l.mother_external_id = me.id
+		LEFT JOIN external_ancestors fe ON l.father_external_id = fe.id
 		WHERE l.id = $1`
 
 	err := database.Pool.QueryRow(c, query, id).Scan(
 		&l.ID, &l.Name, &l.BirthDate, &l.AvailableDate, &l.Status,
-		&l.MotherID, &l.FatherID,
+		&l.MotherID, &l.FatherID, &l.MotherExternalID, &l.FatherExternalID, &l.COI,
 		&extMother, &extFather,
 		&mName, &fName,
 		&ppRaw, &galleryRaw, &l.CreatedAt, &l.UpdatedAt,
@@ -162,6 +168,16 @@ func GetLitter(c *gin.Context) {
 }
 
 func CreateLitter(c *gin.Context) {
+	motherID, motherExternalID := formParentIDs(c, "mother_id", "mother_external_id", nil, nil)
+	fatherID, fatherExternalID := formParentIDs(c, "father_id", "father_external_id", nil, nil)
+	if !checkPedigreeParents(c, "create litter", nil, fatherID, fatherExternalID, motherID, motherExternalID) {
+		return
+	}
+	coi, err := litterCOI(c, fatherID, fatherExternalID, motherID, motherExternalID)
+	if err != nil {
+		slog.Warn("create litter: failed to calculate coi", "error", err)
+	}
+
 	profilePic, err := utils.UploadAndCreateImage(c, "profile_picture", "litters")
 	if err != nil {
 		slog.Warn("create litter: failed to process profile picture", "error", err)
@@ -183,8 +199,6 @@ func CreateLitter(c *gin.Context) {
 
 	name := c.PostForm("name")
 	status := c.PostForm("status")
-	motherID := parseOptionalID(c.PostForm("mother_id"))
-	fatherID := parseOptionalID(c.PostForm("father_id"))
 	extMother := c.PostForm("external_mother_name")
 	extFather := c.PostForm("external_father_name")
 	birthDate, _ := time.Parse("2006-01-02", c.PostForm("birth_date"))
@@ -203,14 +217,16 @@ func CreateLitter(c *gin.Context) {
 	query := `
 		INSERT INTO litters (
 			name, mother_id, father_id, external_mother_name, external_father_name,
-			birth_date, available_date, status, profile_picture, gallery
+			birth_date, available_date, status, profile_picture, gallery,
+			mother_external_id, father_external_id, coi
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING id`
 
 	err = database.Pool.QueryRow(c, query,
 		name, motherID, fatherID, extMother, extFather,
 		birthDate, availDate, status, ppJSON, galleryJSON,
+		motherExternalID, fatherExternalID, coi,
 	).Scan(&newID)
 
 	if err != nil {
@@ -219,15 +235,21 @@ func CreateLitter(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditActionCreate, "litters", newID, nil, snapshotEntity(c, "litters", newID))
+
 	slog.Info("create litter: litter created", "litter_id", newID, "name", name)
 	c.JSON(http.StatusCreated, gin.H{"message": "Litter created", "id": newID})
 }
 
 func UpdateLitter(c *gin.Context) {
 	id := c.Param("id")
+	before := snapshotEntity(c, "litters", id)
 
 	var oldPPRaw, oldGalleryRaw []byte
-	err := database.Pool.QueryRow(c, "SELECT profile_picture, gallery FROM litters WHERE id=$1", id).Scan(&oldPPRaw, &oldGalleryRaw)
+	var currentMotherExternalID, currentFatherExternalID *int
+	err := database.Pool.QueryRow(c, "SELECT profile_picture, gallery, mother_external_id, father_external_id FROM litters WHERE id=$1", id).Scan(
+		&oldPPRaw, &oldGalleryRaw, &currentMotherExternalID, &currentFatherExternalID,
+	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			slog.Debug("update litter: not found", "litter_id", id, "error", err)
@@ -240,6 +262,25 @@ func UpdateLitter(c *gin.Context) {
 		return
 	}
 
+	// mother_id and father_id are always sent by the litter form; the external
+	// links are kept unless sent or replaced by a kennel dog.
+	motherID := parseOptionalID(c.PostForm("mother_id"))
+	fatherID := parseOptionalID(c.PostForm("father_id"))
+	motherExternalID, fatherExternalID := currentMotherExternalID, currentFatherExternalID
+	if value, ok := c.GetPostForm("mother_external_id"); ok || motherID != nil {
+		motherExternalID = parseOptionalID(value)
+	}
+	if value, ok := c.GetPostForm("father_external_id"); ok || fatherID != nil {
+		fatherExternalID = parseOptionalID(value)
+	}
+	if !checkPedigreeParents(c, "update litter", nil, fatherID, fatherExternalID, motherID, motherExternalID) {
+		return
+	}
+	coi, err := litterCOI(c, fatherID, fatherExternalID, motherID, motherExternalID)
+	if err != nil {
+		slog.Warn("update litter: failed to calculate coi", "litter_id", id, "error", err)
+	}
+
 	var currentPP *models.Image
 	var currentGallery []models.Image
 	if len(oldPPRaw) > 0 {
@@ -258,8 +299,6 @@ func UpdateLitter(c *gin.Context) {
 
 	name := c.PostForm("name")
 	status := c.PostForm("status")
-	motherID := parseOptionalID(c.PostForm("mother_id"))
-	fatherID := parseOptionalID(c.PostForm("father_id"))
 	extMother := c.PostForm("external_mother_name")
 	extFather := c.PostForm("external_father_name")
 	birthDate, _ := time.Parse("2006-01-02", c.PostForm("birth_date"))
@@ -327,12 +366,14 @@ func UpdateLitter(c *gin.Context) {
 	query := `
 		UPDATE litters 
 		SET name=$1, mother_id=$2, father_id=$3, external_mother_name=$4, external_father_name=$5,
-			birth_date=$6, available_date=$7, status=$8, profile_picture=$9, gallery=$10, updated_at=NOW()
-		WHERE id=$11`
+			birth_date=$6, available_date=$7, status=$8, profile_picture=$9, gallery=$10,
+			mother_external_id=$11, father_external_id=$12, coi=$13, updated_at=NOW()
+		WHERE id=$14`
 
 	_, err = database.Pool.Exec(c, query,
 		name, motherID, fatherID, extMother, extFather,
-		birthDate, availDate, status, ppJSON, galleryJSON, id,
+		birthDate, availDate, status, ppJSON, galleryJSON,
+		motherExternalID, fatherExternalID, coi, id,
 	)
 
 	if err != nil {
@@ -341,6 +382,8 @@ func UpdateLitter(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditActionUpdate, "litters", id, before, snapshotEntity(c, "litters", id))
+
 	slog.Info("update litter: litter updated", "litter_id", id)
 	c.JSON(http.StatusOK, gin.H{"message": "Litter updated successfully"})
 }
@@ -348,6 +391,32 @@ func UpdateLitter(c *gin.Context) {
 func DeleteLitter(c *gin.Context) {
 	id := c.Param("id")
 
+	// Deleting a litter deletes its puppies, which would take their sale
+	// and reservation records with them.
+	var hasRecords bool
+	err := database.Pool.QueryRow(c, `
+		SELECT EXISTS (
+			SELECT 1 FROM puppies p
+			WHERE p.litter_id = $1 AND (
+				EXISTS (SELECT 1 FROM puppy_sales s WHERE s.puppy_id = p.id)
+				OR EXISTS (SELECT 1 FROM puppy_payments pay WHERE pay.puppy_id = p.id)
+				OR EXISTS (SELECT 1 FROM puppy_reservations r WHERE r.puppy_id = p.id)
+			)
+		)`, id,
+	).Scan(&hasRecords)
+	if err != nil {
+		slog.Error("delete litter: failed to check sales", "litter_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete litter"})
+		return
+	}
+	if hasRecords {
+		slog.Debug("delete litter: litter has sold or reserved puppies", "litter_id", id)
+		c.JSON(http.StatusConflict, gin.H{"error": "This litter has puppies with recorded sales, payments or reservations and can't be deleted"})
+		return
+	}
+
+	before := snapshotEntity(c, "litters", id)
+
 	var ppRaw, galleryRaw []byte
 	if err := database.Pool.QueryRow(c, "SELECT profile_picture, gallery FROM litters WHERE id=$1", id).Scan(&ppRaw, &galleryRaw); err != nil {
 		if err == pgx.ErrNoRows {
@@ -381,13 +450,21 @@ func DeleteLitter(c *gin.Context) {
 		}
 	}
 
-	_, err := database.Pool.Exec(c, "DELETE FROM litters WHERE id=$1", id)
+	_, err = database.Pool.Exec(c, "DELETE FROM litters WHERE id=$1", id)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			slog.Debug("delete litter: puppies gained dependent records", "litter_id", id)
+			c.JSON(http.StatusConflict, gin.H{"error": "This litter has puppies with recorded sales, payments or reservations and can't be deleted"})
+			return
+		}
 		slog.Error("delete litter: database error", "litter_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete litter"})
 		return
 	}
 
+	recordAudit(c, auditActionDelete, "litters", id, before, nil)
+
 	slog.Info("delete litter: litter deleted", "litter_id", id)
 	c.JSON(http.StatusOK, gin.H{"message": "Litter deleted"})
 }
@@ -0,0 +1,412 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+const (
+	pedigreeDefaultGenerations = 4
+	pedigreeMaxGenerations     = 10
+
+	// pedigreeLoopDepth bounds the ancestry walk used to reject parent links
+	// that would make an animal its own ancestor.
+	pedigreeLoopDepth = 30
+)
+
+// pedigreeRef identifies an animal that can be a parent: a kennel dog or an
+// external ancestor.
+type pedigreeRef struct {
+	kind string
+	id   int
+}
+
+func pedigreeParentRef(id, externalID *int) *pedigreeRef {
+	if id != nil {
+		return &pedigreeRef{kind: "dog", id: *id}
+	}
+	if externalID != nil {
+		return &pedigreeRef{kind: "external", id: *externalID}
+	}
+	return nil
+}
+
+type pedigreeRecord struct {
+	node      models.PedigreeNode
+	sire, dam *pedigreeRef
+}
+
+// parentageError is a parent link the caller asked for that cannot be saved.
+type parentageError string
+
+func (e parentageError) Error() string { return string(e) }
+
+// loadPedigreeRecords fetches the given animals with their parent links in
+// at most two queries. Missing IDs are simply absent from the result.
+func loadPedigreeRecords(c *gin.Context, refs []pedigreeRef) (map[pedigreeRef]pedigreeRecord, error) {
+	var dogIDs, externalIDs []int
+	for _, ref := range refs {
+		if ref.kind == "dog" {
+			dogIDs = append(dogIDs, ref.id)
+		} else {
+			externalIDs = append(externalIDs, ref.id)
+		}
+	}
+
+	records := make(map[pedigreeRef]pedigreeRecord)
+
+	if len(dogIDs) > 0 {
+		rows, err := database.Pool.Query(c, `
+			SELECT id, name, gender, birth_date, COALESCE(registration_number, ''), profile_picture,
+				sire_id, sire_external_id, dam_id, dam_external_id
+			FROM dogs
+			WHERE id = ANY($1)`, dogIDs)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var rec pedigreeRecord
+			var birthDate time.Time
+			var ppRaw []byte
+			var sireID, sireExternalID, damID, damExternalID *int
+			if err := rows.Scan(
+				&rec.node.ID, &rec.node.Name, &rec.node.Gender, &birthDate, &rec.node.RegistrationNumber, &ppRaw,
+				&sireID, &sireExternalID, &damID, &damExternalID,
+			); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			rec.node.Type = "dog"
+			rec.node.BirthDate = &birthDate
+			if len(ppRaw) > 0 {
+				var pp models.Image
+				if err := json.Unmarshal(ppRaw, &pp); err == nil && pp.URL != "" {
+					rec.node.ProfilePicture = &pp
+				}
+			}
+			rec.sire, rec.dam = pedigreeParentRef(sireID, sireExternalID), pedigreeParentRef(damID, damExternalID)
+			records[pedigreeRef{kind: "dog", id: rec.node.ID}] = rec
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(externalIDs) > 0 {
+		rows, err := database.Pool.Query(c, `
+			SELECT id, name, gender, birth_date, COALESCE(registration_number, ''), COALESCE(registry, ''), COALESCE(color, ''),
+				sire_id, sire_external_id, dam_id, dam_external_id
+			FROM external_ancestors
+			WHERE id = ANY($1)`, externalIDs)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var rec pedigreeRecord
+			var sireID, sireExternalID, damID, damExternalID *int
+			if err := rows.Scan(
+				&rec.node.ID, &rec.node.Name, &rec.node.Gender, &rec.node.BirthDate, &rec.node.RegistrationNumber, &rec.node.Registry, &rec.node.Color,
+				&sireID, &sireExternalID, &damID, &damExternalID,
+			); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			rec.node.Type = "external"
+			rec.sire, rec.dam = pedigreeParentRef(sireID, sireExternalID), pedigreeParentRef(damID, damExternalID)
+			records[pedigreeRef{kind: "external", id: rec.node.ID}] = rec
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return records, nil
+}
+
+// buildPedigree fills in root's ancestors breadth-first, loading one
+// generation per round trip. An ancestor that appears more than once (line
+// breeding) is repeated in each position, as on a printed pedigree.
+func buildPedigree(c *gin.Context, root *models.PedigreeNode, sire, dam *pedigreeRef, generations int) error {
+	type pending struct {
+		node      *models.PedigreeNode
+		sire, dam *pedigreeRef
+	}
+
+	cache := make(map[pedigreeRef]pedigreeRecord)
+	level := []pending{{root, sire, dam}}
+
+	for gen := 1; gen <= generations && len(level) > 0; gen++ {
+		var missing []pedigreeRef
+		for _, p := range level {
+			for _, ref := range []*pedigreeRef{p.sire, p.dam} {
+				if ref != nil {
+					if _, ok := cache[*ref]; !ok {
+						missing = append(missing, *ref)
+					}
+				}
+			}
+		}
+		if len(missing) > 0 {
+			loaded, err := loadPedigreeRecords(c, missing)
+			if err != nil {
+				return err
+			}
+			for ref, rec := range loaded {
+				cache[ref] = rec
+			}
+		}
+
+		var next []pending
+		attach := func(ref *pedigreeRef) *models.PedigreeNode {
+			rec, ok := cache[*ref]
+			if !ok {
+				return nil
+			}
+			node := rec.node
+			next = append(next, pending{&node, rec.sire, rec.dam})
+			return &node
+		}
+
+		for _, p := range level {
+			if p.sire != nil {
+				p.node.Sire = attach(p.sire)
+			}
+			if p.dam != nil {
+				p.node.Dam = attach(p.dam)
+			}
+		}
+		level = next
+	}
+
+	return nil
+}
+
+// isPedigreeAncestor reports whether target is start or one of its ancestors.
+func isPedigreeAncestor(c *gin.Context, start, target pedigreeRef) (bool, error) {
+	seen := map[pedigreeRef]bool{start: true}
+	level := []pedigreeRef{start}
+
+	for depth := 0; depth <= pedigreeLoopDepth && len(level) > 0; depth++ {
+		for _, ref := range level {
+			if ref == target {
+				return true, nil
+			}
+		}
+
+		records, err := loadPedigreeRecords(c, level)
+		if err != nil {
+			return false, err
+		}
+
+		var next []pedigreeRef
+		for _, rec := range records {
+			for _, parent := range []*pedigreeRef{rec.sire, rec.dam} {
+				if parent != nil && !seen[*parent] {
+					seen[*parent] = true
+					next = append(next, *parent)
+				}
+			}
+		}
+		level = next
+	}
+
+	return false, nil
+}
+
+// validatePedigreeParent checks a sire or dam link before it is saved. child
+// is the animal being edited, or nil when it does not exist yet. Problems with
+// the request are returned as parentageError.
+func validatePedigreeParent(c *gin.Context, child *pedigreeRef, id, externalID *int, gender, role string) error {
+	if id != nil && externalID != nil {
+		return parentageError(role + " can be a kennel dog or an external ancestor, not both")
+	}
+
+	ref := pedigreeParentRef(id, externalID)
+	if ref == nil {
+		return nil
+	}
+
+	records, err := loadPedigreeRecords(c, []pedigreeRef{*ref})
+	if err != nil {
+		return err
+	}
+	rec, ok := records[*ref]
+	if !ok {
+		return parentageError(role + " not found")
+	}
+	if rec.node.Gender != gender {
+		return parentageError(fmt.Sprintf("%s must be %s", role, gender))
+	}
+
+	if child != nil {
+		if *ref == *child {
+			return parentageError(role + " cannot be the same animal")
+		}
+		loop, err := isPedigreeAncestor(c, *ref, *child)
+		if err != nil {
+			return err
+		}
+		if loop {
+			return parentageError(role + " is a descendant of this animal")
+		}
+	}
+
+	return nil
+}
+
+// checkPedigreeParents validates a sire and dam pair, writing the error
+// response itself when it returns false.
+func checkPedigreeParents(c *gin.Context, action string, child *pedigreeRef, sireID, sireExternalID, damID, damExternalID *int) bool {
+	for _, parent := range []struct {
+		id, externalID *int
+		gender, role   string
+	}{
+		{sireID, sireExternalID, "Male", "Sire"},
+		{damID, damExternalID, "Female", "Dam"},
+	} {
+		if err := validatePedigreeParent(c, child, parent.id, parent.externalID, parent.gender, parent.role); err != nil {
+			var invalid parentageError
+			if errors.As(err, &invalid) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": invalid.Error()})
+				return false
+			}
+			slog.Error(action+": failed to validate parents", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate pedigree"})
+			return false
+		}
+	}
+	return true
+}
+
+// formParentIDs reads a kennel/external parent pair from a form. The pair is
+// replaced together when either field is sent, so switching a sire from an
+// external record to a kennel dog clears the old link; otherwise the current
+// values are kept.
+func formParentIDs(c *gin.Context, idKey, externalKey string, currentID, currentExternalID *int) (*int, *int) {
+	idValue, hasID := c.GetPostForm(idKey)
+	externalValue, hasExternal := c.GetPostForm(externalKey)
+	if !hasID && !hasExternal {
+		return currentID, currentExternalID
+	}
+	return parseOptionalID(idValue), parseOptionalID(externalValue)
+}
+
+func parsePedigreeGenerations(c *gin.Context) (int, bool) {
+	generations, err := strconv.Atoi(c.DefaultQuery("generations", strconv.Itoa(pedigreeDefaultGenerations)))
+	if err != nil || generations < 1 || generations > pedigreeMaxGenerations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("generations must be between 1 and %d", pedigreeMaxGenerations)})
+		return 0, false
+	}
+	return generations, true
+}
+
+func GetDogPedigree(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dog ID"})
+		return
+	}
+	generations, ok := parsePedigreeGenerations(c)
+	if !ok {
+		return
+	}
+
+	ref := pedigreeRef{kind: "dog", id: id}
+	records, err := loadPedigreeRecords(c, []pedigreeRef{ref})
+	if err != nil {
+		slog.Error("get dog pedigree: database error", "dog_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pedigree"})
+		return
+	}
+	rec, found := records[ref]
+	if !found {
+		slog.Debug("get dog pedigree: not found", "dog_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dog not found"})
+		return
+	}
+
+	root := rec.node
+	if err := buildPedigree(c, &root, rec.sire, rec.dam, generations); err != nil {
+		slog.Error("get dog pedigree: failed to build tree", "dog_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pedigree"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"generations": generations, "pedigree": root})
+}
+
+// GetPuppyPedigree builds a puppy's pedigree from its litter's parents. A
+// parent recorded only by free-text name appears as an "unlinked" leaf.
+func GetPuppyPedigree(c *gin.Context) {
+	id := c.Param("id")
+	generations, ok := parsePedigreeGenerations(c)
+	if !ok {
+		return
+	}
+
+	var root models.PedigreeNode
+	var ppRaw []byte
+	var motherID, motherExternalID, fatherID, fatherExternalID *int
+	var extMother, extFather *string
+
+	query := `
+		SELECT p.id, p.name, p.gender, p.color, p.profile_picture, l.birth_date,
+			l.mother_id, l.mother_external_id, l.external_mother_name,
+			l.father_id, l.father_external_id, l.external_father_name
+		FROM puppies p
+		LEFT JOIN litters l ON p.litter_id = l.id
+		WHERE p.id = $1`
+
+	err := database.Pool.QueryRow(c, query, id).Scan(
+		&root.ID, &root.Name, &root.Gender, &root.Color, &ppRaw, &root.BirthDate,
+		&motherID, &motherExternalID, &extMother,
+		&fatherID, &fatherExternalID, &extFather,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("get puppy pedigree: not found", "puppy_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Puppy not found"})
+			return
+		}
+
+		slog.Error("get puppy pedigree: database error", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pedigree"})
+		return
+	}
+
+	root.Type = "puppy"
+	if len(ppRaw) > 0 {
+		var pp models.Image
+		if err := json.Unmarshal(ppRaw, &pp); err == nil && pp.URL != "" {
+			root.ProfilePicture = &pp
+		}
+	}
+
+	sire, dam := pedigreeParentRef(fatherID, fatherExternalID), pedigreeParentRef(motherID, motherExternalID)
+	if sire == nil && extFather != nil && *extFather != "" {
+		root.Sire = &models.PedigreeNode{Type: "unlinked", Name: *extFather, Gender: "Male"}
+	}
+	if dam == nil && extMother != nil && *extMother != "" {
+		root.Dam = &models.PedigreeNode{Type: "unlinked", Name: *extMother, Gender: "Female"}
+	}
+
+	if err := buildPedigree(c, &root, sire, dam, generations); err != nil {
+		slog.Error("get puppy pedigree: failed to build tree", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pedigree"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"generations": generations, "pedigree": root})
+}
@@ -1,33 +1,36 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
 )
 
+// GetPuppies lists puppies, filtered by litter and by the structured
+// attributes (see puppyFilters).
 func GetPuppies(c *gin.Context) {
-	litterID := c.Query("litter_id")
+	where, args, err := puppyFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	query := `
 		SELECT 
-			id, litter_id, name, color, gender, status, description,
+			id, litter_id, name, color, gender, status, description, promoted_dog_id,
+			` + puppyAttributeColumns(c) + `,
 			profile_picture, gallery, created_at, updated_at
-		FROM puppies`
-
-	args := []interface{}{}
-
-	if litterID != "" {
-		query += ` WHERE litter_id = $1`
-		args = append(args, litterID)
-	}
+		FROM puppies` + where
 
 	query += ` ORDER BY status ASC, name ASC`
 
@@ -45,7 +48,9 @@ func GetPuppies(c *gin.Context) {
 		var ppRaw, galleryRaw []byte
 
 		if err := rows.Scan(
-			&p.ID, &p.LitterID, &p.Name, &p.Color, &p.Gender, &p.Status, &p.Description,
+			&p.ID, &p.LitterID, &p.Name, &p.Color, &p.Gender, &p.Status, &p.Description, &p.PromotedDogID,
+			&p.ColorID, &p.ColorGenetics, &p.RegistrationBody, &p.RegistrationNumber, &p.Microchip,
+			&p.ExpectedWeight, &p.ExpectedWeightUnit, &p.ListPrice, &p.DepositAmount,
 			&ppRaw, &galleryRaw, &p.CreatedAt, &p.UpdatedAt,
 		); err != nil {
 			slog.Debug("get puppies: failed to scan row", "error", err)
@@ -82,13 +87,16 @@ func GetPuppy(c *gin.Context) {
 
 	query := `
 		SELECT 
-			id, litter_id, name, color, gender, status, description,
+			id, litter_id, name, color, gender, status, description, promoted_dog_id,
+			` + puppyAttributeColumns(c) + `,
 			profile_picture, gallery, created_at, updated_at
 		FROM puppies
 		WHERE id=$1`
 
 	err := database.Pool.QueryRow(c, query, id).Scan(
-		&p.ID, &p.LitterID, &p.Name, &p.Color, &p.Gender, &p.Status, &p.Description,
+		&p.ID, &p.LitterID, &p.Name, &p.Color, &p.Gender, &p.Status, &p.Description, &p.PromotedDogID,
+		&p.ColorID, &p.ColorGenetics, &p.RegistrationBody, &p.RegistrationNumber, &p.Microchip,
+		&p.ExpectedWeight, &p.ExpectedWeightUnit, &p.ListPrice, &p.DepositAmount,
 		&ppRaw, &galleryRaw, &p.CreatedAt, &p.UpdatedAt,
 	)
 
@@ -129,6 +137,12 @@ func CreatePuppy(c *gin.Context) {
 	status := c.PostForm("status")
 	desc := c.PostForm("description")
 
+	attrs, color, err := formPuppyAttributes(c, color, puppyAttributes{})
+	if err != nil {
+		respondAttributeError(c, "create puppy", err)
+		return
+	}
+
 	profilePic, err := utils.UploadAndCreateImage(c, "profile_picture", "puppies")
 	if err != nil {
 		slog.Warn("create puppy: failed to process profile picture", "error", err)
@@ -158,36 +172,58 @@ func CreatePuppy(c *gin.Context) {
 	query := `
 		INSERT INTO puppies (
 			litter_id, name, color, gender, status, description, 
-			profile_picture, gallery
+			profile_picture, gallery, color_id, color_genetics, registration_body,
+			registration_number, microchip, expected_adult_weight, expected_adult_weight_unit,
+			list_price, price_visible, deposit_amount
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id`
 
 	err = database.Pool.QueryRow(c, query,
 		litterID, name, color, gender, status, desc,
-		ppJSON, galleryJSON,
+		ppJSON, galleryJSON, attrs.colorID, attrs.genetics, attrs.registrationBody,
+		attrs.registrationNumber, attrs.microchip, attrs.expectedWeight, attrs.expectedWeightUnit,
+		attrs.listPrice, attrs.priceVisible, attrs.depositAmount,
 	).Scan(&newID)
 
 	if err != nil {
+		if respondDuplicatePuppyAttribute(c, err) {
+			return
+		}
 		slog.Error("create puppy: database error", "name", name, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	if litterID != nil {
-		updateLitterStatus(c, *litterID)
+		updateLitterStatus(c, database.Pool, *litterID)
 	}
 
+	recordAudit(c, auditActionCreate, "puppies", newID, nil, snapshotEntity(c, "puppies", newID))
+
 	slog.Info("create puppy: puppy created", "puppy_id", newID, "name", name)
 	c.JSON(http.StatusCreated, gin.H{"message": "Puppy created", "id": newID})
 }
 
 func UpdatePuppy(c *gin.Context) {
 	id := c.Param("id")
+	before := snapshotEntity(c, "puppies", id)
 
 	var oldLitterID *int
 	var oldPPRaw, oldGalleryRaw []byte
-	err := database.Pool.QueryRow(c, "SELECT litter_id, profile_picture, gallery FROM puppies WHERE id=$1", id).Scan(&oldLitterID, &oldPPRaw, &oldGalleryRaw)
+	var current puppyAttributes
+	var oldStatus string
+	var hasActiveReservation bool
+	err := database.Pool.QueryRow(c, `
+		SELECT litter_id, profile_picture, gallery, color_id, color_genetics, registration_body,
+			registration_number, microchip, expected_adult_weight::float8, expected_adult_weight_unit,
+			list_price::float8, price_visible, deposit_amount::float8, COALESCE(status::text, ''),
+			EXISTS (SELECT 1 FROM puppy_reservations r WHERE r.puppy_id = puppies.id AND r.status = 'Active')
+		FROM puppies WHERE id=$1`, id).Scan(
+		&oldLitterID, &oldPPRaw, &oldGalleryRaw, &current.colorID, &current.genetics, &current.registrationBody,
+		&current.registrationNumber, &current.microchip, &current.expectedWeight, &current.expectedWeightUnit,
+		&current.listPrice, &current.priceVisible, &current.depositAmount, &oldStatus, &hasActiveReservation,
+	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			slog.Debug("update puppy: not found", "puppy_id", id, "error", err)
@@ -229,6 +265,20 @@ func UpdatePuppy(c *gin.Context) {
 	status := c.PostForm("status")
 	desc := c.PostForm("description")
 
+	// A reserved puppy's status follows its reservation; cancelling or
+	// completing the reservation is what frees or sells it.
+	if hasActiveReservation && status != oldStatus {
+		slog.Debug("update puppy: status change blocked by active reservation", "puppy_id", id, "from", oldStatus, "to", status)
+		c.JSON(http.StatusConflict, gin.H{"error": "This puppy has an active reservation; cancel or complete the reservation to change its status"})
+		return
+	}
+
+	attrs, color, err := formPuppyAttributes(c, color, current)
+	if err != nil {
+		respondAttributeError(c, "update puppy", err)
+		return
+	}
+
 	newPP := currentPP
 	if uploadedImg, err := utils.UploadAndCreateImage(c, "profile_picture", "puppies"); err == nil && uploadedImg != nil {
 		newPP = uploadedImg
@@ -291,30 +341,39 @@ func UpdatePuppy(c *gin.Context) {
 	query := `
 		UPDATE puppies 
 		SET litter_id=$1, name=$2, color=$3, gender=$4, status=$5, description=$6, 
-			profile_picture=$7, gallery=$8, updated_at=NOW()
-		WHERE id=$9`
+			profile_picture=$7, gallery=$8, color_id=$9, color_genetics=$10, registration_body=$11,
+			registration_number=$12, microchip=$13, expected_adult_weight=$14, expected_adult_weight_unit=$15,
+			list_price=$16, price_visible=$17, deposit_amount=$18, updated_at=NOW()
+		WHERE id=$19`
 
 	_, err = database.Pool.Exec(c, query,
 		litterID, name, color, gender, status, desc,
-		ppJSON, galleryJSON, id,
+		ppJSON, galleryJSON, attrs.colorID, attrs.genetics, attrs.registrationBody,
+		attrs.registrationNumber, attrs.microchip, attrs.expectedWeight, attrs.expectedWeightUnit,
+		attrs.listPrice, attrs.priceVisible, attrs.depositAmount, id,
 	)
 
 	if err != nil {
+		if respondDuplicatePuppyAttribute(c, err) {
+			return
+		}
 		slog.Error("update puppy: database error", "puppy_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	if oldLitterID != nil {
-		updateLitterStatus(c, *oldLitterID)
+		updateLitterStatus(c, database.Pool, *oldLitterID)
 	}
 
 	if litterID != nil {
 		if oldLitterID == nil || *litterID != *oldLitterID {
-			updateLitterStatus(c, *litterID)
+			updateLitterStatus(c, database.Pool, *litterID)
 		}
 	}
 
+	recordAudit(c, auditActionUpdate, "puppies", id, before, snapshotEntity(c, "puppies", id))
+
 	slog.Info("update puppy: puppy updated", "puppy_id", id)
 	c.JSON(http.StatusOK, gin.H{"message": "Puppy updated"})
 }
@@ -322,6 +381,38 @@ func UpdatePuppy(c *gin.Context) {
 func DeletePuppy(c *gin.Context) {
 	id := c.Param("id")
 
+	// Sales, payments and reservations record what happened with a family;
+	// they must be dealt with on purpose before the puppy can go.
+	var hasSale, hasPayments, hasActiveReservation, hasReservations bool
+	err := database.Pool.QueryRow(c, `
+		SELECT EXISTS (SELECT 1 FROM puppy_sales WHERE puppy_id=$1),
+			EXISTS (SELECT 1 FROM puppy_payments WHERE puppy_id=$1),
+			EXISTS (SELECT 1 FROM puppy_reservations WHERE puppy_id=$1 AND status='Active'),
+			EXISTS (SELECT 1 FROM puppy_reservations WHERE puppy_id=$1)`, id,
+	).Scan(&hasSale, &hasPayments, &hasActiveReservation, &hasReservations)
+	if err != nil {
+		slog.Error("delete puppy: failed to check sales", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete puppy"})
+		return
+	}
+	if hasSale || hasPayments {
+		slog.Debug("delete puppy: puppy has sale records", "puppy_id", id)
+		c.JSON(http.StatusConflict, gin.H{"error": "This puppy has a recorded sale or payments and can't be deleted"})
+		return
+	}
+	if hasActiveReservation {
+		slog.Debug("delete puppy: puppy has an active reservation", "puppy_id", id)
+		c.JSON(http.StatusConflict, gin.H{"error": "This puppy has an active reservation; cancel it before deleting the puppy"})
+		return
+	}
+	if hasReservations {
+		slog.Debug("delete puppy: puppy has reservation history", "puppy_id", id)
+		c.JSON(http.StatusConflict, gin.H{"error": "This puppy has reservation history and can't be deleted"})
+		return
+	}
+
+	before := snapshotEntity(c, "puppies", id)
+
 	var litterID *int
 	var ppRaw, galleryRaw []byte
 	if err := database.Pool.QueryRow(c, "SELECT litter_id, profile_picture, gallery FROM puppies WHERE id=$1", id).Scan(&litterID, &ppRaw, &galleryRaw); err != nil {
@@ -356,38 +447,53 @@ func DeletePuppy(c *gin.Context) {
 		}
 	}
 
-	_, err := database.Pool.Exec(c, "DELETE FROM puppies WHERE id=$1", id)
+	_, err = database.Pool.Exec(c, "DELETE FROM puppies WHERE id=$1", id)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			slog.Debug("delete puppy: puppy gained dependent records", "puppy_id", id)
+			c.JSON(http.StatusConflict, gin.H{"error": "This puppy has sale or reservation records and can't be deleted"})
+			return
+		}
 		slog.Error("delete puppy: database error", "puppy_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete puppy"})
 		return
 	}
 
 	if litterID != nil {
-		updateLitterStatus(c, *litterID)
+		updateLitterStatus(c, database.Pool, *litterID)
 	}
 
+	recordAudit(c, auditActionDelete, "puppies", id, before, nil)
+
 	slog.Info("delete puppy: puppy deleted", "puppy_id", id)
 	c.JSON(http.StatusOK, gin.H{"message": "Puppy deleted"})
 }
 
-func updateLitterStatus(c *gin.Context, litterID int) {
+// dbExecutor is satisfied by both database.Pool and a pgx.Tx, so helpers can
+// run on their own or as part of a caller's transaction.
+type dbExecutor interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func updateLitterStatus(c *gin.Context, db dbExecutor, litterID int) error {
 	var total, notSold int
 	query := `
 		SELECT 
 			count(*),
-			count(*) filter (where status != 'Sold')
+			count(*) filter (where status NOT IN ('Sold', 'Retained'))
 		FROM puppies 
 		WHERE litter_id = $1`
 
-	err := database.Pool.QueryRow(c, query, litterID).Scan(&total, &notSold)
+	err := db.QueryRow(c, query, litterID).Scan(&total, &notSold)
 	if err != nil {
 		slog.Error("update litter status: failed to count puppies", "litter_id", litterID, "error", err)
-		return
+		return err
 	}
 
 	if total == 0 {
-		return
+		return nil
 	}
 
 	var newStatus string
@@ -397,9 +503,11 @@ func updateLitterStatus(c *gin.Context, litterID int) {
 		newStatus = "Available"
 	}
 
-	if _, err := database.Pool.Exec(c, "UPDATE litters SET status = $1 WHERE id = $2", newStatus, litterID); err != nil {
+	if _, err := db.Exec(c, "UPDATE litters SET status = $1 WHERE id = $2", newStatus, litterID); err != nil {
 		slog.Error("update litter status: failed to update status", "litter_id", litterID, "new_status", newStatus, "error", err)
-	} else {
-		slog.Info("update litter status: status updated", "litter_id", litterID, "new_status", newStatus)
+		return err
 	}
+
+	slog.Info("update litter status: status updated", "litter_id", litterID, "new_status", newStatus)
+	return nil
 }
@@ -0,0 +1,336 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+var (
+	genotypePattern  = regexp.MustCompile(`^[A-Za-z0-9]{1,4}/[A-Za-z0-9]{1,4}$`)
+	microchipPattern = regexp.MustCompile(`^[0-9A-Za-z]{9,15}$`)
+)
+
+// puppyAttributeColumns selects the structured attributes. Microchip and
+// registration numbers identify an owner's dog, so like hidden prices they
+// are only returned to callers signed in with puppies:read.
+func puppyAttributeColumns(c *gin.Context) string {
+	registration, microchip := `''`, `''`
+	if canReadPrivatePuppyDetails(c) {
+		registration, microchip = `COALESCE(registration_number, '')`, `COALESCE(microchip, '')`
+	}
+	return `color_id, color_genetics, COALESCE(registration_body, ''), ` + registration + `,
+			` + microchip + `, expected_adult_weight::float8, expected_adult_weight_unit,
+			CASE WHEN price_visible THEN list_price::float8 END, CASE WHEN price_visible THEN deposit_amount::float8 END`
+}
+
+// canReadPrivatePuppyDetails reports whether middleware.OptionalScope
+// recognised the caller.
+func canReadPrivatePuppyDetails(c *gin.Context) bool {
+	_, isUser := c.Get("user")
+	_, isKey := c.Get("api_key")
+	return isUser || isKey
+}
+
+// puppyAttributes are the structured details stored alongside a puppy's
+// color text, plus its asking price. Nil pointers are stored as NULL.
+type puppyAttributes struct {
+	colorID            *int
+	genetics           map[string]string
+	registrationBody   *string
+	registrationNumber *string
+	microchip          *string
+	expectedWeight     *float64
+	expectedWeightUnit *string
+	listPrice          *float64
+	priceVisible       bool
+	depositAmount      *float64
+}
+
+type attributeError string
+
+func (e attributeError) Error() string { return string(e) }
+
+func optionalText(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func respondAttributeError(c *gin.Context, action string, err error) {
+	var invalid attributeError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid.Error()})
+		return
+	}
+	slog.Error(action+": failed to read attributes", "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save puppy"})
+}
+
+// respondDuplicatePuppyAttribute reports a microchip or registration already
+// used by another puppy, returning false for any other error.
+func respondDuplicatePuppyAttribute(c *gin.Context, err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return false
+	}
+	if pgErr.ConstraintName == "puppies_microchip_idx" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another puppy already has this microchip"})
+	} else {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another puppy already has this registration number"})
+	}
+	return true
+}
+
+// parseColorGenetics reads a JSON object of locus to genotype, such as
+// {"E": "E/e", "K": "ky/ky"}. Loci are upper-cased; empty genotypes are
+// dropped so a form can clear a single locus.
+func parseColorGenetics(raw string) (map[string]string, error) {
+	genetics := map[string]string{}
+	if strings.TrimSpace(raw) == "" {
+		return genetics, nil
+	}
+
+	var parsed map[string]string
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, attributeError("Color genetics must be a JSON object of locus to genotype")
+	}
+	for locus, genotype := range parsed {
+		locus = strings.ToUpper(strings.TrimSpace(locus))
+		genotype = strings.TrimSpace(genotype)
+		if !slices.Contains(models.ColorLoci, locus) {
+			return nil, attributeError(fmt.Sprintf("Unknown color locus %q", locus))
+		}
+		if genotype == "" {
+			continue
+		}
+		if !genotypePattern.MatchString(genotype) {
+			return nil, attributeError(fmt.Sprintf("Genotype for locus %s must look like E/e", locus))
+		}
+		genetics[locus] = genotype
+	}
+	return genetics, nil
+}
+
+// formPuppyAttributes reads the structured attributes from a puppy form.
+// Fields that aren't sent keep their current values. A color picked from the
+// managed list by color_id replaces the color text; color text that matches
+// a listed color is linked to it, and anything else stays free text.
+func formPuppyAttributes(c *gin.Context, color string, current puppyAttributes) (puppyAttributes, string, error) {
+	attrs := current
+	if attrs.genetics == nil {
+		attrs.genetics = map[string]string{}
+	}
+
+	if value, ok := c.GetPostForm("color_id"); ok && value != "" {
+		colorID, err := strconv.Atoi(value)
+		if err != nil {
+			return attrs, color, attributeError("Invalid color ID")
+		}
+		if err := database.Pool.QueryRow(c, "SELECT name FROM coat_colors WHERE id=$1", colorID).Scan(&color); err != nil {
+			if err == pgx.ErrNoRows {
+				return attrs, color, attributeError("Color not found")
+			}
+			return attrs, color, err
+		}
+		attrs.colorID = &colorID
+	} else {
+		attrs.colorID = nil
+		var colorID int
+		var name string
+		err := database.Pool.QueryRow(c, "SELECT id, name FROM coat_colors WHERE lower(name) = lower($1)", strings.TrimSpace(color)).Scan(&colorID, &name)
+		if err == nil {
+			attrs.colorID, color = &colorID, name
+		} else if err != pgx.ErrNoRows {
+			return attrs, color, err
+		}
+	}
+
+	if value, ok := c.GetPostForm("color_genetics"); ok {
+		genetics, err := parseColorGenetics(value)
+		if err != nil {
+			return attrs, color, err
+		}
+		attrs.genetics = genetics
+	}
+
+	if value, ok := c.GetPostForm("registration_body"); ok {
+		attrs.registrationBody = optionalText(value)
+	}
+	if value, ok := c.GetPostForm("registration_number"); ok {
+		attrs.registrationNumber = optionalText(value)
+	}
+	if value, ok := c.GetPostForm("microchip"); ok {
+		attrs.microchip = optionalText(strings.ReplaceAll(value, " ", ""))
+		if attrs.microchip != nil && !microchipPattern.MatchString(*attrs.microchip) {
+			return attrs, color, attributeError("Microchip must be 9 to 15 letters or digits")
+		}
+	}
+	if attrs.registrationNumber != nil && attrs.registrationBody == nil {
+		return attrs, color, attributeError("A registration number needs a registration body")
+	}
+
+	if value, ok := c.GetPostForm("expected_adult_weight"); ok {
+		if strings.TrimSpace(value) == "" {
+			attrs.expectedWeight, attrs.expectedWeightUnit = nil, nil
+		} else {
+			weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || weight <= 0 {
+				return attrs, color, attributeError("Expected adult weight must be a positive number")
+			}
+			attrs.expectedWeight = &weight
+		}
+	}
+	if value, ok := c.GetPostForm("expected_adult_weight_unit"); ok && value != "" {
+		if !slices.Contains(models.WeightUnits, value) {
+			return attrs, color, attributeError("Expected adult weight unit must be one of g, kg, oz or lb")
+		}
+		attrs.expectedWeightUnit = &value
+	}
+	if attrs.expectedWeight != nil && attrs.expectedWeightUnit == nil {
+		unit := "lb"
+		attrs.expectedWeightUnit = &unit
+	}
+	if attrs.expectedWeight == nil {
+		attrs.expectedWeightUnit = nil
+	}
+
+	for _, price := range []struct {
+		key, label string
+		target     **float64
+	}{
+		{"list_price", "List price", &attrs.listPrice},
+		{"deposit_amount", "Deposit amount", &attrs.depositAmount},
+	} {
+		if value, ok := c.GetPostForm(price.key); ok {
+			value = strings.NewReplacer("$", "", ",", "").Replace(strings.TrimSpace(value))
+			if value == "" {
+				*price.target = nil
+				continue
+			}
+			amount, err := strconv.ParseFloat(value, 64)
+			if err != nil || amount < 0 {
+				return attrs, color, attributeError(price.label + " must be a positive amount")
+			}
+			*price.target = &amount
+		}
+	}
+	if value, ok := c.GetPostForm("price_visible"); ok {
+		visible, err := strconv.ParseBool(value)
+		if err != nil {
+			return attrs, color, attributeError("price_visible must be true or false")
+		}
+		attrs.priceVisible = visible
+	}
+
+	return attrs, color, nil
+}
+
+// puppyFilters builds the WHERE clause for GET /puppies from its query
+// parameters. It returns an attributeError for malformed values.
+func puppyFilters(c *gin.Context) (string, []interface{}, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		where += " AND " + fmt.Sprintf(clause, len(args))
+	}
+	list := func(value string) []string {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+
+	if value := c.Query("litter_id"); value != "" {
+		add("litter_id = $%d", value)
+	}
+	if value := c.Query("gender"); value != "" {
+		add("gender::text = ANY($%d)", list(value))
+	}
+	if value := c.Query("status"); value != "" {
+		add("status::text = ANY($%d)", list(value))
+	}
+	if value := c.Query("color"); value != "" {
+		lowered := list(strings.ToLower(value))
+		add("lower(color) = ANY($%d)", lowered)
+	}
+	if value := c.Query("color_id"); value != "" {
+		var ids []int
+		for _, item := range list(value) {
+			id, err := strconv.Atoi(item)
+			if err != nil {
+				return "", nil, attributeError("Invalid color ID")
+			}
+			ids = append(ids, id)
+		}
+		add("color_id = ANY($%d)", ids)
+	}
+	if value := c.Query("registration_body"); value != "" {
+		add("lower(registration_body) = lower($%d)", value)
+	}
+	for _, flag := range []struct{ param, column string }{
+		{"registered", "registration_number"},
+		{"microchipped", "microchip"},
+	} {
+		if value := c.Query(flag.param); value != "" {
+			has, err := strconv.ParseBool(value)
+			if err != nil {
+				return "", nil, attributeError(flag.param + " must be true or false")
+			}
+			if has {
+				where += " AND " + flag.column + " IS NOT NULL"
+			} else {
+				where += " AND " + flag.column + " IS NULL"
+			}
+		}
+	}
+
+	// Weight bounds are compared in grams, so puppies entered in any unit match.
+	unit := c.DefaultQuery("weight_unit", "lb")
+	if !slices.Contains(models.WeightUnits, unit) {
+		return "", nil, attributeError("weight_unit must be one of g, kg, oz or lb")
+	}
+	for _, bound := range []struct{ param, op string }{{"min_weight", ">="}, {"max_weight", "<="}} {
+		if value := c.Query(bound.param); value != "" {
+			weight, err := strconv.ParseFloat(value, 64)
+			if err != nil || weight < 0 {
+				return "", nil, attributeError(bound.param + " must be a positive number")
+			}
+			add("expected_adult_weight_grams "+bound.op+" $%d", weight*weightUnitGrams[unit])
+		}
+	}
+
+	// ?dna=E:e/e&dna=K:ky/ky matches puppies with every listed genotype.
+	if values := c.QueryArray("dna"); len(values) > 0 {
+		wanted := map[string]string{}
+		for _, value := range values {
+			locus, genotype, ok := strings.Cut(value, ":")
+			locus = strings.ToUpper(strings.TrimSpace(locus))
+			if !ok || !slices.Contains(models.ColorLoci, locus) || !genotypePattern.MatchString(strings.TrimSpace(genotype)) {
+				return "", nil, attributeError("dna must look like E:e/e")
+			}
+			wanted[locus] = strings.TrimSpace(genotype)
+		}
+		add("color_genetics @> $%d", wanted)
+	}
+
+	return where, args, nil
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
+)
+
+// PromotePuppy keeps a puppy for the breeding program. It creates a dog with
+// the puppy's details, copies of its images and health records, the litter's
+// birth date and the litter's parents as sire and dam, then marks the puppy
+// Retained.
+func PromotePuppy(c *gin.Context) {
+	id := c.Param("id")
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("promote puppy: failed to begin transaction", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to promote puppy"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	var p models.Puppy
+	var litterID *int
+	var birthDate *time.Time
+	var ppRaw, galleryRaw []byte
+	var motherID, motherExternalID, fatherID, fatherExternalID *int
+
+	query := `
+		SELECT p.name, p.gender, p.status, COALESCE(p.description, ''), p.promoted_dog_id, p.profile_picture, p.gallery,
+			COALESCE(p.registration_number, ''),
+			l.id, l.birth_date, l.mother_id, l.mother_external_id, l.father_id, l.father_external_id
+		FROM puppies p
+		LEFT JOIN litters l ON p.litter_id = l.id
+		WHERE p.id = $1
+		FOR UPDATE OF p`
+
+	err = tx.QueryRow(c, query, id).Scan(
+		&p.Name, &p.Gender, &p.Status, &p.Description, &p.PromotedDogID, &ppRaw, &galleryRaw, &p.RegistrationNumber,
+		&litterID, &birthDate, &motherID, &motherExternalID, &fatherID, &fatherExternalID,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("promote puppy: not found", "puppy_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Puppy not found"})
+			return
+		}
+
+		slog.Error("promote puppy: failed to fetch puppy", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to promote puppy"})
+		return
+	}
+
+	if p.PromotedDogID != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Puppy has already been promoted", "dogId": *p.PromotedDogID})
+		return
+	}
+	if p.Status == "Sold" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Puppy has already been sold"})
+		return
+	}
+	if birthDate == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Puppy must belong to a litter to be promoted"})
+		return
+	}
+
+	var reserved bool
+	if err := tx.QueryRow(c, "SELECT EXISTS (SELECT 1 FROM puppy_reservations WHERE puppy_id=$1 AND status='Active')", id).Scan(&reserved); err != nil {
+		slog.Error("promote puppy: failed to check reservations", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to promote puppy"})
+		return
+	}
+	if reserved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cancel the puppy's active reservation before promoting it"})
+		return
+	}
+
+	var puppyPP *models.Image
+	var puppyGallery []models.Image
+	if len(ppRaw) > 0 {
+		if err := json.Unmarshal(ppRaw, &puppyPP); err != nil {
+			slog.Debug("promote puppy: failed to unmarshal profile picture", "puppy_id", id, "error", err)
+		}
+	}
+	if len(galleryRaw) > 0 {
+		if err := json.Unmarshal(galleryRaw, &puppyGallery); err != nil {
+			slog.Debug("promote puppy: failed to unmarshal gallery", "puppy_id", id, "error", err)
+		}
+	}
+
+	// The dog gets its own copies so deleting the puppy later does not remove
+	// the dog's photos.
+	var copied []models.Image
+	var dogPP *models.Image
+	if puppyPP != nil && puppyPP.URL != "" {
+		if img, err := utils.CopyImage(*puppyPP, "dogs"); err == nil {
+			dogPP = img
+			copied = append(copied, *img)
+		} else {
+			slog.Warn("promote puppy: failed to copy profile picture", "puppy_id", id, "url", puppyPP.URL, "error", err)
+		}
+	}
+	dogGallery := []models.Image{}
+	for _, img := range puppyGallery {
+		if dogImg, err := utils.CopyImage(img, "dogs"); err == nil {
+			dogGallery = append(dogGallery, *dogImg)
+			copied = append(copied, *dogImg)
+		} else {
+			slog.Warn("promote puppy: failed to copy gallery image", "puppy_id", id, "url", img.URL, "error", err)
+		}
+	}
+
+	fail := func() {
+		for _, img := range copied {
+			if err := utils.DeleteImage(img.URL); err != nil {
+				slog.Warn("promote puppy: failed to clean up copied image", "url", img.URL, "error", err)
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to promote puppy"})
+	}
+
+	ppJSON, err := json.Marshal(dogPP)
+	if err != nil {
+		slog.Warn("promote puppy: failed to marshal profile picture", "puppy_id", id, "error", err)
+	}
+	galleryJSON, err := json.Marshal(dogGallery)
+	if err != nil {
+		slog.Warn("promote puppy: failed to marshal gallery", "puppy_id", id, "error", err)
+	}
+
+	var dogID int
+	err = tx.QueryRow(c, `
+		INSERT INTO dogs (
+			name, gender, description, birth_date, profile_picture, gallery,
+			sire_id, sire_external_id, dam_id, dam_external_id, litter_id, registration_number
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, '')) RETURNING id`,
+		p.Name, p.Gender, p.Description, *birthDate, ppJSON, galleryJSON,
+		fatherID, fatherExternalID, motherID, motherExternalID, litterID, p.RegistrationNumber,
+	).Scan(&dogID)
+	if err != nil {
+		slog.Error("promote puppy: failed to create dog", "puppy_id", id, "error", err)
+		fail()
+		return
+	}
+
+	before := snapshotEntity(c, "puppies", id)
+
+	if _, err := tx.Exec(c, "UPDATE puppies SET status='Retained', promoted_dog_id=$1, updated_at=NOW() WHERE id=$2", dogID, id); err != nil {
+		slog.Error("promote puppy: failed to mark puppy retained", "puppy_id", id, "error", err)
+		fail()
+		return
+	}
+
+	// The dog starts with the puppy's health history; the puppy keeps its own
+	// copy for its page.
+	_, err = tx.Exec(c, `
+		INSERT INTO health_records (
+			dog_id, type, title, record_date, next_due_date, veterinarian, result, lab,
+			certificate_file_id, notes, is_public, reminder_sent_at, created_by
+		)
+		SELECT $1, type, title, record_date, next_due_date, veterinarian, result, lab,
+			certificate_file_id, notes, is_public, reminder_sent_at, created_by
+		FROM health_records WHERE puppy_id = $2
+		ORDER BY record_date, id`, dogID, id)
+	if err != nil {
+		slog.Error("promote puppy: failed to copy health records", "puppy_id", id, "error", err)
+		fail()
+		return
+	}
+
+	if litterID != nil {
+		if err := updateLitterStatus(c, tx, *litterID); err != nil {
+			fail()
+			return
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("promote puppy: failed to commit transaction", "puppy_id", id, "error", err)
+		fail()
+		return
+	}
+
+	recordAudit(c, auditActionCreate, "dogs", dogID, nil, snapshotEntity(c, "dogs", dogID))
+	recordAudit(c, auditActionUpdate, "puppies", id, before, snapshotEntity(c, "puppies", id))
+
+	slog.Info("promote puppy: puppy promoted", "puppy_id", id, "dog_id", dogID)
+	c.JSON(http.StatusCreated, gin.H{"message": "Puppy promoted to dog", "dogId": dogID})
+}
@@ -0,0 +1,440 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+var weightUnitGrams = map[string]float64{"g": 1, "kg": 1000, "oz": 28.349523125, "lb": 453.59237}
+
+func convertGrams(grams float64, unit string) float64 {
+	return math.Round(grams/weightUnitGrams[unit]*100) / 100
+}
+
+type growthRow struct {
+	id, puppyID int
+	name        string
+	date        time.Time
+	grams       float64
+	unit        string
+	notes       string
+	percentile  *float64
+	mates       int
+}
+
+// loadGrowthRows returns every weigh-in for a litter, or for a single puppy
+// that has no litter, with each weight ranked against the litter mates
+// weighed on the same day.
+func loadGrowthRows(c *gin.Context, litterID *int, puppyID int) ([]growthRow, error) {
+	query := `
+		SELECT w.id, w.puppy_id, p.name, w.weighed_on, w.weight_grams::float8, w.unit, COALESCE(w.notes, ''),
+			CASE WHEN count(*) OVER d > 1 THEN round((percent_rank() OVER (d ORDER BY w.weight_grams) * 100)::numeric, 1)::float8 END,
+			count(*) OVER d
+		FROM puppy_weights w
+		JOIN puppies p ON w.puppy_id = p.id
+		WHERE p.litter_id = $1 OR w.puppy_id = $2
+		WINDOW d AS (PARTITION BY w.weighed_on)
+		ORDER BY w.weighed_on, p.name, w.puppy_id`
+
+	rows, err := database.Pool.Query(c, query, litterID, puppyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []growthRow
+	for rows.Next() {
+		var r growthRow
+		if err := rows.Scan(&r.id, &r.puppyID, &r.name, &r.date, &r.grams, &r.unit, &r.notes, &r.percentile, &r.mates); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// growthUnit is ?unit= when given, otherwise the unit of the latest weigh-in
+// so the chart matches what the breeder is typing in.
+func growthUnit(c *gin.Context, rows []growthRow) (string, bool) {
+	if unit := c.Query("unit"); unit != "" {
+		if !slices.Contains(models.WeightUnits, unit) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unit must be one of g, kg, oz or lb"})
+			return "", false
+		}
+		return unit, true
+	}
+	if len(rows) > 0 {
+		return rows[len(rows)-1].unit, true
+	}
+	return "g", true
+}
+
+func ageInDays(birthDate *time.Time, date time.Time) *int {
+	if birthDate == nil {
+		return nil
+	}
+	days := int(date.Sub(*birthDate).Hours() / 24)
+	return &days
+}
+
+func buildPuppyGrowth(puppyID int, name string, rows []growthRow, unit string, birthDate *time.Time) models.PuppyGrowth {
+	growth := models.PuppyGrowth{PuppyID: puppyID, Name: name, Unit: unit, BirthDate: birthDate, Series: []models.GrowthPoint{}}
+
+	var own []growthRow
+	for _, r := range rows {
+		if r.puppyID == puppyID {
+			own = append(own, r)
+			growth.Series = append(growth.Series, models.GrowthPoint{
+				ID:         r.id,
+				Date:       r.date,
+				AgeDays:    ageInDays(birthDate, r.date),
+				Weight:     convertGrams(r.grams, unit),
+				Notes:      r.notes,
+				Percentile: r.percentile,
+				MatesCount: r.mates,
+			})
+		}
+	}
+
+	if n := len(own); n > 0 {
+		latest := convertGrams(own[n-1].grams, unit)
+		growth.LatestWeight = &latest
+		growth.LatestPercentile = own[n-1].percentile
+
+		if n > 1 {
+			if days := own[n-1].date.Sub(own[n-2].date).Hours() / 24; days > 0 {
+				gain := convertGrams((own[n-1].grams-own[n-2].grams)/days, unit)
+				growth.DailyGain = &gain
+			}
+		}
+	}
+
+	return growth
+}
+
+func growthAverages(rows []growthRow, unit string, birthDate *time.Time) []models.GrowthAverage {
+	averages := []models.GrowthAverage{}
+	for i := 0; i < len(rows); {
+		j, total := i, 0.0
+		for ; j < len(rows) && rows[j].date.Equal(rows[i].date); j++ {
+			total += rows[j].grams
+		}
+		averages = append(averages, models.GrowthAverage{
+			Date:    rows[i].date,
+			AgeDays: ageInDays(birthDate, rows[i].date),
+			Weight:  convertGrams(total/float64(j-i), unit),
+		})
+		i = j
+	}
+	return averages
+}
+
+// GetPuppyWeights returns a puppy's weigh-ins as a chart series alongside the
+// litter's average weight on each weigh-in day.
+func GetPuppyWeights(c *gin.Context) {
+	puppyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid puppy ID"})
+		return
+	}
+
+	var name string
+	var litterID *int
+	var birthDate *time.Time
+	err = database.Pool.QueryRow(c, `
+		SELECT p.name, p.litter_id, l.birth_date
+		FROM puppies p LEFT JOIN litters l ON p.litter_id = l.id
+		WHERE p.id = $1`, puppyID).Scan(&name, &litterID, &birthDate)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("get puppy weights: not found", "puppy_id", puppyID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Puppy not found"})
+			return
+		}
+
+		slog.Error("get puppy weights: database error", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch weights"})
+		return
+	}
+
+	rows, err := loadGrowthRows(c, litterID, puppyID)
+	if err != nil {
+		slog.Error("get puppy weights: failed to load weights", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch weights"})
+		return
+	}
+
+	var own []growthRow
+	for _, r := range rows {
+		if r.puppyID == puppyID {
+			own = append(own, r)
+		}
+	}
+	unit, ok := growthUnit(c, own)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PuppyGrowthChart{
+		PuppyGrowth:   buildPuppyGrowth(puppyID, name, own, unit, birthDate),
+		LitterAverage: growthAverages(rows, unit, birthDate),
+	})
+}
+
+// GetLitterWeights returns every puppy's series for a side-by-side litter
+// chart, plus the litter average.
+func GetLitterWeights(c *gin.Context) {
+	litterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid litter ID"})
+		return
+	}
+
+	growth := models.LitterGrowth{LitterID: litterID, Puppies: []models.PuppyGrowth{}}
+	if err := database.Pool.QueryRow(c, "SELECT birth_date FROM litters WHERE id=$1", litterID).Scan(&growth.BirthDate); err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("get litter weights: not found", "litter_id", litterID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Litter not found"})
+			return
+		}
+
+		slog.Error("get litter weights: database error", "litter_id", litterID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch weights"})
+		return
+	}
+
+	rows, err := loadGrowthRows(c, &litterID, 0)
+	if err != nil {
+		slog.Error("get litter weights: failed to load weights", "litter_id", litterID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch weights"})
+		return
+	}
+
+	unit, ok := growthUnit(c, rows)
+	if !ok {
+		return
+	}
+	growth.Unit = unit
+
+	names := make(map[int]string)
+	var puppyIDs []int
+	for _, r := range rows {
+		if _, seen := names[r.puppyID]; !seen {
+			names[r.puppyID] = r.name
+			puppyIDs = append(puppyIDs, r.puppyID)
+		}
+	}
+	slices.SortFunc(puppyIDs, func(a, b int) int {
+		if names[a] != names[b] {
+			if names[a] < names[b] {
+				return -1
+			}
+			return 1
+		}
+		return a - b
+	})
+
+	for _, id := range puppyIDs {
+		growth.Puppies = append(growth.Puppies, buildPuppyGrowth(id, names[id], rows, unit, &growth.BirthDate))
+	}
+	growth.Average = growthAverages(rows, unit, &growth.BirthDate)
+
+	c.JSON(http.StatusOK, growth)
+}
+
+// upsertPuppyWeight replaces any earlier weigh-in for the same puppy and day,
+// so correcting a typo is just entering the weight again.
+func upsertPuppyWeight(c *gin.Context, db dbExecutor, puppyID int, date time.Time, weight float64, unit, notes string, createdBy *int) (int, bool, error) {
+	var id int
+	var inserted bool
+	err := db.QueryRow(c, `
+		INSERT INTO puppy_weights (puppy_id, weighed_on, weight, unit, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (puppy_id, weighed_on) DO UPDATE
+		SET weight = EXCLUDED.weight, unit = EXCLUDED.unit, notes = EXCLUDED.notes, updated_at = NOW()
+		RETURNING id, (xmax = 0)`,
+		puppyID, date, weight, unit, notes, createdBy,
+	).Scan(&id, &inserted)
+	return id, inserted, err
+}
+
+func CreatePuppyWeight(c *gin.Context) {
+	puppyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid puppy ID"})
+		return
+	}
+
+	var req models.PuppyWeightRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("create puppy weight: invalid request body", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date must be in YYYY-MM-DD format"})
+		return
+	}
+
+	var exists bool
+	if err := database.Pool.QueryRow(c, "SELECT EXISTS (SELECT 1 FROM puppies WHERE id=$1)", puppyID).Scan(&exists); err != nil {
+		slog.Error("create puppy weight: failed to check puppy", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record weight"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Puppy not found"})
+		return
+	}
+
+	var createdBy *int
+	if userVal, ok := c.Get("user"); ok {
+		user := userVal.(models.User)
+		createdBy = &user.ID
+	}
+
+	var before map[string]any
+	var existingID int
+	if err := database.Pool.QueryRow(c, "SELECT id FROM puppy_weights WHERE puppy_id=$1 AND weighed_on=$2", puppyID, date).Scan(&existingID); err == nil {
+		before = snapshotEntity(c, "puppy_weights", existingID)
+	}
+
+	id, inserted, err := upsertPuppyWeight(c, database.Pool, puppyID, date, req.Weight, req.Unit, req.Notes, createdBy)
+	if err != nil {
+		slog.Error("create puppy weight: database error", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record weight"})
+		return
+	}
+
+	if inserted {
+		recordAudit(c, auditActionCreate, "puppy_weights", id, nil, snapshotEntity(c, "puppy_weights", id))
+		slog.Info("create puppy weight: weight recorded", "puppy_id", puppyID, "weight_id", id)
+		c.JSON(http.StatusCreated, gin.H{"message": "Weight recorded", "id": id})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "puppy_weights", id, before, snapshotEntity(c, "puppy_weights", id))
+	slog.Info("create puppy weight: weight replaced", "puppy_id", puppyID, "weight_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Weight updated", "id": id})
+}
+
+func DeletePuppyWeight(c *gin.Context) {
+	puppyID, weightID := c.Param("id"), c.Param("weightId")
+	before := snapshotEntity(c, "puppy_weights", weightID)
+
+	result, err := database.Pool.Exec(c, "DELETE FROM puppy_weights WHERE id=$1 AND puppy_id=$2", weightID, puppyID)
+	if err != nil {
+		slog.Error("delete puppy weight: database error", "puppy_id", puppyID, "weight_id", weightID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete weight"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("delete puppy weight: not found", "puppy_id", puppyID, "weight_id", weightID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Weight not found"})
+		return
+	}
+
+	recordAudit(c, auditActionDelete, "puppy_weights", weightID, before, nil)
+
+	slog.Info("delete puppy weight: weight deleted", "puppy_id", puppyID, "weight_id", weightID)
+	c.JSON(http.StatusOK, gin.H{"message": "Weight deleted"})
+}
+
+// CreateLitterWeighIn records a whole litter's weigh-in in one transaction.
+// Every puppy must belong to the litter; nothing is saved if one doesn't.
+func CreateLitterWeighIn(c *gin.Context) {
+	litterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid litter ID"})
+		return
+	}
+
+	var req models.LitterWeighInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("create litter weigh-in: invalid request body", "litter_id", litterID, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date must be in YYYY-MM-DD format"})
+		return
+	}
+
+	rows, err := database.Pool.Query(c, "SELECT id FROM puppies WHERE litter_id=$1", litterID)
+	if err != nil {
+		slog.Error("create litter weigh-in: failed to fetch puppies", "litter_id", litterID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record weights"})
+		return
+	}
+	inLitter := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err == nil {
+			inLitter[id] = true
+		}
+	}
+	rows.Close()
+
+	seen := make(map[int]bool)
+	for _, entry := range req.Weights {
+		if !inLitter[entry.PuppyID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Puppy %d is not in this litter", entry.PuppyID)})
+			return
+		}
+		if seen[entry.PuppyID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Puppy %d is listed more than once", entry.PuppyID)})
+			return
+		}
+		seen[entry.PuppyID] = true
+	}
+
+	var createdBy *int
+	if userVal, ok := c.Get("user"); ok {
+		user := userVal.(models.User)
+		createdBy = &user.ID
+	}
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("create litter weigh-in: failed to begin transaction", "litter_id", litterID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record weights"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	ids := make([]int, 0, len(req.Weights))
+	for _, entry := range req.Weights {
+		id, _, err := upsertPuppyWeight(c, tx, entry.PuppyID, date, entry.Weight, req.Unit, entry.Notes, createdBy)
+		if err != nil {
+			slog.Error("create litter weigh-in: failed to record weight", "litter_id", litterID, "puppy_id", entry.PuppyID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record weights"})
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("create litter weigh-in: failed to commit transaction", "litter_id", litterID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record weights"})
+		return
+	}
+
+	for _, id := range ids {
+		recordAudit(c, auditActionCreate, "puppy_weights", id, nil, snapshotEntity(c, "puppy_weights", id))
+	}
+
+	slog.Info("create litter weigh-in: weights recorded", "litter_id", litterID, "count", len(ids))
+	c.JSON(http.StatusCreated, gin.H{"message": "Weights recorded", "ids": ids})
+}
@@ -0,0 +1,450 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+const reservationSelect = `
+	SELECT r.id, r.puppy_id, p.name, p.litter_id, r.waitlist_id, w.first_name || ' ' || w.last_name, w.email,
+		r.status, r.reserved_date, r.deposit_amount::float8, r.pickup_date, r.notes, r.created_by,
+		r.created_at, r.updated_at
+	FROM puppy_reservations r
+	JOIN puppies p ON p.id = r.puppy_id
+	JOIN waitlist w ON w.id = r.waitlist_id`
+
+func scanReservation(row pgx.Row) (models.Reservation, error) {
+	var r models.Reservation
+	err := row.Scan(
+		&r.ID, &r.PuppyID, &r.PuppyName, &r.LitterID, &r.WaitlistID, &r.FamilyName, &r.FamilyEmail,
+		&r.Status, &r.ReservedDate, &r.DepositAmount, &r.PickupDate, &r.Notes, &r.CreatedBy,
+		&r.CreatedAt, &r.UpdatedAt,
+	)
+	return r, err
+}
+
+func parseOptionalDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+func GetReservations(c *gin.Context) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+
+	for _, filter := range []struct{ param, column string }{
+		{"puppy_id", "r.puppy_id"},
+		{"waitlist_id", "r.waitlist_id"},
+		{"litter_id", "p.litter_id"},
+		{"status", "r.status"},
+	} {
+		if value := c.Query(filter.param); value != "" {
+			args = append(args, value)
+			where += fmt.Sprintf(" AND %s = $%d", filter.column, len(args))
+		}
+	}
+
+	rows, err := database.Pool.Query(c, reservationSelect+where+" ORDER BY r.reserved_date DESC, r.id DESC", args...)
+	if err != nil {
+		slog.Error("get reservations: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reservations"})
+		return
+	}
+	defer rows.Close()
+
+	reservations := []models.Reservation{}
+	for rows.Next() {
+		r, err := scanReservation(rows)
+		if err != nil {
+			slog.Debug("get reservations: failed to scan row", "error", err)
+			continue
+		}
+		reservations = append(reservations, r)
+	}
+
+	c.JSON(http.StatusOK, reservations)
+}
+
+func GetReservation(c *gin.Context) {
+	id := c.Param("id")
+
+	r, err := scanReservation(database.Pool.QueryRow(c, reservationSelect+" WHERE r.id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("get reservation: not found", "reservation_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found"})
+			return
+		}
+
+		slog.Error("get reservation: database error", "reservation_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reservation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, r)
+}
+
+// CreateReservation links a puppy to a waitlist family. The puppy becomes
+// Reserved, the family Matched, and the litter status is recalculated, all in
+// one transaction.
+func CreateReservation(c *gin.Context) {
+	var req models.CreateReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("create reservation: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	reservedDate, err := parseOptionalDate(req.ReservedDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reserved date must be in YYYY-MM-DD format"})
+		return
+	}
+	pickupDate, err := parseOptionalDate(req.PickupDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pickup date must be in YYYY-MM-DD format"})
+		return
+	}
+
+	var createdBy *int
+	if userVal, ok := c.Get("user"); ok {
+		user := userVal.(models.User)
+		createdBy = &user.ID
+	}
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("create reservation: failed to begin transaction", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reservation"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	var puppyStatus string
+	var litterID *int
+	err = tx.QueryRow(c, "SELECT status, litter_id FROM puppies WHERE id=$1 FOR UPDATE", req.PuppyID).Scan(&puppyStatus, &litterID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Puppy not found"})
+			return
+		}
+
+		slog.Error("create reservation: failed to fetch puppy", "puppy_id", req.PuppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reservation"})
+		return
+	}
+	if puppyStatus == "Sold" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Puppy has already been sold"})
+		return
+	}
+	if puppyStatus == "Retained" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Puppy has been kept for the breeding program"})
+		return
+	}
+
+	var waitlistStatus string
+	var waitlistDeposit *float64
+	err = tx.QueryRow(c, "SELECT status, deposit_amount::float8 FROM waitlist WHERE id=$1 FOR UPDATE", req.WaitlistID).Scan(&waitlistStatus, &waitlistDeposit)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+			return
+		}
+
+		slog.Error("create reservation: failed to fetch waitlist entry", "waitlist_id", req.WaitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reservation"})
+		return
+	}
+	if waitlistStatus == "Complete" || waitlistStatus == "Withdrawn" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Waitlist entry is " + waitlistStatus})
+		return
+	}
+
+	depositAmount := req.DepositAmount
+	if depositAmount == nil {
+		depositAmount = waitlistDeposit
+	}
+
+	var notes *string
+	if req.Notes != "" {
+		notes = &req.Notes
+	}
+
+	var newID int
+	err = tx.QueryRow(c, `
+		INSERT INTO puppy_reservations (
+			puppy_id, waitlist_id, reserved_date, deposit_amount, pickup_date, notes, previous_waitlist_status, created_by
+		)
+		VALUES ($1, $2, COALESCE($3, CURRENT_DATE), $4, $5, $6, $7, $8)
+		RETURNING id`,
+		req.PuppyID, req.WaitlistID, reservedDate, depositAmount, pickupDate, notes, waitlistStatus, createdBy,
+	).Scan(&newID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			slog.Debug("create reservation: puppy already reserved", "puppy_id", req.PuppyID)
+			c.JSON(http.StatusConflict, gin.H{"error": "Puppy already has an active reservation"})
+			return
+		}
+
+		slog.Error("create reservation: database error", "puppy_id", req.PuppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reservation"})
+		return
+	}
+
+	if _, err := tx.Exec(c, "UPDATE puppies SET status='Reserved', updated_at=NOW() WHERE id=$1", req.PuppyID); err != nil {
+		slog.Error("create reservation: failed to update puppy", "puppy_id", req.PuppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reservation"})
+		return
+	}
+
+	if err := setWaitlistStatusTx(c, tx, req.WaitlistID, waitlistStatus, "Matched", fmt.Sprintf("Reserved puppy #%d", req.PuppyID)); err != nil {
+		slog.Error("create reservation: failed to update waitlist entry", "waitlist_id", req.WaitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reservation"})
+		return
+	}
+
+	if litterID != nil {
+		if err := updateLitterStatus(c, tx, *litterID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reservation"})
+			return
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("create reservation: failed to commit transaction", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reservation"})
+		return
+	}
+
+	recordAudit(c, auditActionCreate, "puppy_reservations", newID, nil, snapshotEntity(c, "puppy_reservations", newID))
+
+	slog.Info("create reservation: reservation created", "reservation_id", newID, "puppy_id", req.PuppyID, "waitlist_id", req.WaitlistID)
+	c.JSON(http.StatusCreated, gin.H{"message": "Reservation created", "id": newID})
+}
+
+// setWaitlistStatusTx changes a waitlist entry's status and records the
+// transition. It is a no-op when the status is unchanged.
+func setWaitlistStatusTx(c *gin.Context, tx pgx.Tx, waitlistID int, from, to, note string) error {
+	if from == to {
+		return nil
+	}
+	if _, err := tx.Exec(c, "UPDATE waitlist SET status=$1, updated_at=NOW() WHERE id=$2", to, waitlistID); err != nil {
+		return err
+	}
+	return recordWaitlistStatusChange(c, tx, waitlistID, &from, to, note)
+}
+
+func UpdateReservation(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.UpdateReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("update reservation: invalid request body", "reservation_id", id, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	sets := "updated_at=NOW()"
+	args := []interface{}{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		sets += fmt.Sprintf(", %s=$%d", column, len(args))
+	}
+
+	if req.ReservedDate != nil {
+		date, err := time.Parse("2006-01-02", *req.ReservedDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reserved date must be in YYYY-MM-DD format"})
+			return
+		}
+		set("reserved_date", date)
+	}
+	if req.PickupDate != nil {
+		date, err := parseOptionalDate(*req.PickupDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pickup date must be in YYYY-MM-DD format"})
+			return
+		}
+		set("pickup_date", date)
+	}
+	if req.DepositAmount != nil {
+		set("deposit_amount", *req.DepositAmount)
+	}
+	if req.Notes != nil {
+		set("notes", *req.Notes)
+	}
+
+	before := snapshotEntity(c, "puppy_reservations", id)
+
+	args = append(args, id)
+	result, err := database.Pool.Exec(c, fmt.Sprintf("UPDATE puppy_reservations SET %s WHERE id=$%d", sets, len(args)), args...)
+	if err != nil {
+		slog.Error("update reservation: database error", "reservation_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("update reservation: not found", "reservation_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "puppy_reservations", id, before, snapshotEntity(c, "puppy_reservations", id))
+
+	slog.Info("update reservation: reservation updated", "reservation_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Reservation updated"})
+}
+
+// CancelReservation releases the puppy back to Available and restores the
+// family's status from before the reservation.
+func CancelReservation(c *gin.Context) {
+	finishReservation(c, "Cancelled")
+}
+
+// CompleteReservation marks the puppy Sold and the family Complete.
+func CompleteReservation(c *gin.Context) {
+	finishReservation(c, "Completed")
+}
+
+func finishReservation(c *gin.Context, outcome string) {
+	id := c.Param("id")
+	reservationID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reservation ID"})
+		return
+	}
+
+	action := "cancel reservation"
+	if outcome == "Completed" {
+		action = "complete reservation"
+	}
+
+	var req models.ReservationActionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			slog.Debug(action+": invalid request body", "reservation_id", id, "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	before := snapshotEntity(c, "puppy_reservations", id)
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error(action+": failed to begin transaction", "reservation_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	var puppyID, waitlistID int
+	var status string
+	var previousStatus *string
+	err = tx.QueryRow(c, `
+		SELECT puppy_id, waitlist_id, status, previous_waitlist_status
+		FROM puppy_reservations WHERE id=$1 FOR UPDATE`, reservationID,
+	).Scan(&puppyID, &waitlistID, &status, &previousStatus)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug(action+": not found", "reservation_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found"})
+			return
+		}
+
+		slog.Error(action+": database error", "reservation_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation"})
+		return
+	}
+	if status != "Active" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reservation is already " + status})
+		return
+	}
+
+	var litterID *int
+	if err := tx.QueryRow(c, "SELECT litter_id FROM puppies WHERE id=$1 FOR UPDATE", puppyID).Scan(&litterID); err != nil {
+		slog.Error(action+": failed to fetch puppy", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation"})
+		return
+	}
+
+	var waitlistStatus string
+	if err := tx.QueryRow(c, "SELECT status FROM waitlist WHERE id=$1 FOR UPDATE", waitlistID).Scan(&waitlistStatus); err != nil {
+		slog.Error(action+": failed to fetch waitlist entry", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation"})
+		return
+	}
+
+	puppyStatus, newWaitlistStatus := "Sold", "Complete"
+	if outcome == "Cancelled" {
+		puppyStatus, newWaitlistStatus = "Available", "Contacted"
+		if previousStatus != nil {
+			newWaitlistStatus = *previousStatus
+		}
+	}
+
+	note := req.Note
+	if note == "" {
+		note = fmt.Sprintf("Reservation #%d %s", reservationID, outcome)
+	}
+
+	if _, err := tx.Exec(c, "UPDATE puppy_reservations SET status=$1, updated_at=NOW() WHERE id=$2", outcome, reservationID); err != nil {
+		slog.Error(action+": failed to update reservation", "reservation_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation"})
+		return
+	}
+	if _, err := tx.Exec(c, "UPDATE puppies SET status=$1, updated_at=NOW() WHERE id=$2", puppyStatus, puppyID); err != nil {
+		slog.Error(action+": failed to update puppy", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation"})
+		return
+	}
+	// A family holding another puppy stays Matched until that one is
+	// finished too.
+	var otherActive int
+	if err := tx.QueryRow(c, "SELECT count(*) FROM puppy_reservations WHERE waitlist_id=$1 AND status='Active' AND id<>$2", waitlistID, reservationID).Scan(&otherActive); err != nil {
+		slog.Error(action+": failed to check other reservations", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation"})
+		return
+	}
+	if otherActive == 0 {
+		if err := setWaitlistStatusTx(c, tx, waitlistID, waitlistStatus, newWaitlistStatus, note); err != nil {
+			slog.Error(action+": failed to update waitlist entry", "waitlist_id", waitlistID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation"})
+			return
+		}
+	}
+	if litterID != nil {
+		if err := updateLitterStatus(c, tx, *litterID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation"})
+			return
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error(action+": failed to commit transaction", "reservation_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "puppy_reservations", id, before, snapshotEntity(c, "puppy_reservations", id))
+
+	slog.Info(action+": reservation updated", "reservation_id", id, "status", outcome)
+	c.JSON(http.StatusOK, gin.H{"message": "Reservation " + outcome})
+}
@@ -0,0 +1,494 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+const puppySaleColumns = `
+	id, puppy_id, reservation_id, sale_price::float8, sale_date, buyer_name, COALESCE(buyer_email, ''),
+	COALESCE(buyer_phone, ''), COALESCE(buyer_address, ''), COALESCE(notes, ''), contract_file_id, created_by, created_at, updated_at`
+
+func scanPuppySale(row pgx.Row) (models.PuppySale, error) {
+	var s models.PuppySale
+	err := row.Scan(
+		&s.ID, &s.PuppyID, &s.ReservationID, &s.SalePrice, &s.SaleDate, &s.BuyerName, &s.BuyerEmail,
+		&s.BuyerPhone, &s.BuyerAddress, &s.Notes, &s.ContractFileID, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt,
+	)
+	return s, err
+}
+
+// netPayment counts refunds against what has been paid.
+const netPayment = `CASE WHEN kind = 'Refund' THEN -amount ELSE amount END`
+
+// GetPuppySale returns the admin view of a puppy's price, sale and payments.
+func GetPuppySale(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid puppy ID"})
+		return
+	}
+
+	summary := models.PuppySaleSummary{PuppyID: id, Payments: []models.Payment{}}
+	err = database.Pool.QueryRow(c, `
+		SELECT name, status, list_price::float8, price_visible, deposit_amount::float8
+		FROM puppies WHERE id=$1`, id,
+	).Scan(&summary.PuppyName, &summary.Status, &summary.ListPrice, &summary.PriceVisible, &summary.DepositAmount)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("get puppy sale: not found", "puppy_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Puppy not found"})
+			return
+		}
+
+		slog.Error("get puppy sale: database error", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sale"})
+		return
+	}
+
+	sale, err := scanPuppySale(database.Pool.QueryRow(c, `SELECT `+puppySaleColumns+` FROM puppy_sales WHERE puppy_id=$1`, id))
+	if err == nil {
+		summary.Sale = &sale
+	} else if err != pgx.ErrNoRows {
+		slog.Error("get puppy sale: failed to fetch sale", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sale"})
+		return
+	}
+
+	rows, err := database.Pool.Query(c, `
+		SELECT id, puppy_id, kind, amount::float8, paid_on, COALESCE(method, ''), COALESCE(reference, ''),
+			COALESCE(notes, ''), created_by, created_at
+		FROM puppy_payments
+		WHERE puppy_id=$1
+		ORDER BY paid_on ASC, id ASC`, id)
+	if err != nil {
+		slog.Error("get puppy sale: failed to fetch payments", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sale"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Payment
+		if err := rows.Scan(&p.ID, &p.PuppyID, &p.Kind, &p.Amount, &p.PaidOn, &p.Method, &p.Reference, &p.Notes, &p.CreatedBy, &p.CreatedAt); err != nil {
+			slog.Debug("get puppy sale: failed to scan payment", "puppy_id", id, "error", err)
+			continue
+		}
+		if p.Kind == "Refund" {
+			summary.Paid -= p.Amount
+		} else {
+			summary.Paid += p.Amount
+		}
+		summary.Payments = append(summary.Payments, p)
+	}
+	summary.Paid = math.Round(summary.Paid*100) / 100
+
+	price := summary.ListPrice
+	if summary.Sale != nil {
+		price = &summary.Sale.SalePrice
+	}
+	if price != nil {
+		balance := math.Round((*price-summary.Paid)*100) / 100
+		summary.Balance = &balance
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// RecordPuppySale creates or replaces a puppy's sale record and marks the
+// puppy Sold. An active reservation is completed along the way, and its
+// family's details fill in any buyer contact left blank.
+func RecordPuppySale(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid puppy ID"})
+		return
+	}
+
+	var req models.PuppySaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("record puppy sale: invalid request body", "puppy_id", id, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	saleDate := time.Now().Truncate(24 * time.Hour)
+	if req.SaleDate != "" {
+		saleDate, err = time.Parse("2006-01-02", req.SaleDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Sale date must be in YYYY-MM-DD format"})
+			return
+		}
+	}
+
+	var createdBy *int
+	if userVal, ok := c.Get("user"); ok {
+		user := userVal.(models.User)
+		createdBy = &user.ID
+	}
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("record puppy sale: failed to begin transaction", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record sale"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	var puppyStatus string
+	var litterID *int
+	if err := tx.QueryRow(c, "SELECT status, litter_id FROM puppies WHERE id=$1 FOR UPDATE", id).Scan(&puppyStatus, &litterID); err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Puppy not found"})
+			return
+		}
+		slog.Error("record puppy sale: failed to fetch puppy", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record sale"})
+		return
+	}
+	if puppyStatus == "Retained" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Puppy has been retained for the breeding program"})
+		return
+	}
+
+	// Prefer the active reservation; otherwise link the most recently
+	// completed one so its family can fill in the buyer.
+	var reservationID, waitlistID *int
+	var reservationStatus, waitlistStatus, familyName, familyEmail, familyPhone string
+	err = tx.QueryRow(c, `
+		SELECT r.id, r.status, r.waitlist_id, w.status, w.first_name || ' ' || w.last_name, w.email, COALESCE(w.phone, '')
+		FROM puppy_reservations r
+		JOIN waitlist w ON r.waitlist_id = w.id
+		WHERE r.puppy_id = $1 AND r.status IN ('Active', 'Completed')
+		ORDER BY r.status = 'Active' DESC, r.updated_at DESC
+		LIMIT 1
+		FOR UPDATE OF r, w`, id,
+	).Scan(&reservationID, &reservationStatus, &waitlistID, &waitlistStatus, &familyName, &familyEmail, &familyPhone)
+	if err != nil && err != pgx.ErrNoRows {
+		slog.Error("record puppy sale: failed to fetch reservation", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record sale"})
+		return
+	}
+
+	if reservationID != nil {
+		if req.BuyerName == "" {
+			req.BuyerName = familyName
+		}
+		if req.BuyerEmail == "" {
+			req.BuyerEmail = familyEmail
+		}
+		if req.BuyerPhone == "" {
+			req.BuyerPhone = familyPhone
+		}
+	}
+	if req.BuyerName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Buyer name is required"})
+		return
+	}
+
+	var before map[string]any
+	var existingID int
+	if err := tx.QueryRow(c, "SELECT id FROM puppy_sales WHERE puppy_id=$1", id).Scan(&existingID); err == nil {
+		before = snapshotEntity(c, "puppy_sales", existingID)
+	}
+
+	var saleID int
+	var inserted bool
+	err = tx.QueryRow(c, `
+		INSERT INTO puppy_sales (
+			puppy_id, reservation_id, sale_price, sale_date, buyer_name, buyer_email, buyer_phone, buyer_address, notes, created_by
+		)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9, $10)
+		ON CONFLICT (puppy_id) DO UPDATE
+		SET reservation_id = COALESCE(EXCLUDED.reservation_id, puppy_sales.reservation_id),
+			sale_price = EXCLUDED.sale_price, sale_date = EXCLUDED.sale_date, buyer_name = EXCLUDED.buyer_name,
+			buyer_email = EXCLUDED.buyer_email, buyer_phone = EXCLUDED.buyer_phone,
+			buyer_address = EXCLUDED.buyer_address, notes = EXCLUDED.notes, updated_at = NOW()
+		RETURNING id, (xmax = 0)`,
+		id, reservationID, req.SalePrice, saleDate, req.BuyerName, req.BuyerEmail, req.BuyerPhone, req.BuyerAddress, req.Notes, createdBy,
+	).Scan(&saleID, &inserted)
+	if err != nil {
+		slog.Error("record puppy sale: failed to save sale", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record sale"})
+		return
+	}
+
+	completed := reservationID != nil && reservationStatus == "Active"
+	var reservationBefore map[string]any
+	if completed {
+		reservationBefore = snapshotEntity(c, "puppy_reservations", *reservationID)
+		if _, err := tx.Exec(c, "UPDATE puppy_reservations SET status='Completed', updated_at=NOW() WHERE id=$1", *reservationID); err != nil {
+			slog.Error("record puppy sale: failed to complete reservation", "reservation_id", *reservationID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record sale"})
+			return
+		}
+		// As in finishReservation, a family still holding another puppy
+		// stays Matched.
+		var otherActive int
+		if err := tx.QueryRow(c, "SELECT count(*) FROM puppy_reservations WHERE waitlist_id=$1 AND status='Active' AND id<>$2", *waitlistID, *reservationID).Scan(&otherActive); err != nil {
+			slog.Error("record puppy sale: failed to check other reservations", "waitlist_id", *waitlistID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record sale"})
+			return
+		}
+		note := fmt.Sprintf("Reservation #%d Completed", *reservationID)
+		if otherActive == 0 {
+			if err := setWaitlistStatusTx(c, tx, *waitlistID, waitlistStatus, "Complete", note); err != nil {
+				slog.Error("record puppy sale: failed to update waitlist entry", "waitlist_id", *waitlistID, "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record sale"})
+				return
+			}
+		}
+	}
+
+	puppyBefore := snapshotEntity(c, "puppies", id)
+	if puppyStatus != "Sold" {
+		if _, err := tx.Exec(c, "UPDATE puppies SET status='Sold', updated_at=NOW() WHERE id=$1", id); err != nil {
+			slog.Error("record puppy sale: failed to update puppy", "puppy_id", id, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record sale"})
+			return
+		}
+		if litterID != nil {
+			if err := updateLitterStatus(c, tx, *litterID); err != nil {
+				slog.Error("record puppy sale: failed to update litter status", "litter_id", *litterID, "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record sale"})
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("record puppy sale: failed to commit transaction", "puppy_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record sale"})
+		return
+	}
+
+	if inserted {
+		recordAudit(c, auditActionCreate, "puppy_sales", saleID, nil, snapshotEntity(c, "puppy_sales", saleID))
+	} else {
+		recordAudit(c, auditActionUpdate, "puppy_sales", saleID, before, snapshotEntity(c, "puppy_sales", saleID))
+	}
+	if completed {
+		recordAudit(c, auditActionUpdate, "puppy_reservations", *reservationID, reservationBefore, snapshotEntity(c, "puppy_reservations", *reservationID))
+	}
+	if puppyStatus != "Sold" {
+		recordAudit(c, auditActionUpdate, "puppies", id, puppyBefore, snapshotEntity(c, "puppies", id))
+	}
+
+	slog.Info("record puppy sale: sale recorded", "puppy_id", id, "sale_id", saleID, "reservation_completed", completed)
+	if inserted {
+		c.JSON(http.StatusCreated, gin.H{"message": "Sale recorded", "id": saleID})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sale updated", "id": saleID})
+}
+
+// DeletePuppySale removes a sale entered by mistake. The puppy's status and
+// payments are left alone.
+func DeletePuppySale(c *gin.Context) {
+	puppyID := c.Param("id")
+
+	var saleID int
+	if err := database.Pool.QueryRow(c, "SELECT id FROM puppy_sales WHERE puppy_id=$1", puppyID).Scan(&saleID); err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("delete puppy sale: not found", "puppy_id", puppyID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sale not found"})
+			return
+		}
+		slog.Error("delete puppy sale: database error", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete sale"})
+		return
+	}
+	before := snapshotEntity(c, "puppy_sales", saleID)
+
+	if _, err := database.Pool.Exec(c, "DELETE FROM puppy_sales WHERE id=$1", saleID); err != nil {
+		slog.Error("delete puppy sale: database error", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete sale"})
+		return
+	}
+
+	recordAudit(c, auditActionDelete, "puppy_sales", saleID, before, nil)
+
+	slog.Info("delete puppy sale: sale deleted", "puppy_id", puppyID, "sale_id", saleID)
+	c.JSON(http.StatusOK, gin.H{"message": "Sale deleted"})
+}
+
+func CreatePayment(c *gin.Context) {
+	puppyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid puppy ID"})
+		return
+	}
+
+	var req models.PaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("create payment: invalid request body", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if !slices.Contains(models.PaymentKinds, req.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kind must be Deposit, Payment or Refund"})
+		return
+	}
+	paidOn := time.Now().Truncate(24 * time.Hour)
+	if req.PaidOn != "" {
+		paidOn, err = time.Parse("2006-01-02", req.PaidOn)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Paid on must be in YYYY-MM-DD format"})
+			return
+		}
+	}
+
+	var exists bool
+	if err := database.Pool.QueryRow(c, "SELECT EXISTS (SELECT 1 FROM puppies WHERE id=$1)", puppyID).Scan(&exists); err != nil {
+		slog.Error("create payment: failed to check puppy", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Puppy not found"})
+		return
+	}
+
+	var createdBy *int
+	if userVal, ok := c.Get("user"); ok {
+		user := userVal.(models.User)
+		createdBy = &user.ID
+	}
+
+	var id int
+	err = database.Pool.QueryRow(c, `
+		INSERT INTO puppy_payments (puppy_id, kind, amount, paid_on, method, reference, notes, created_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8)
+		RETURNING id`,
+		puppyID, req.Kind, req.Amount, paidOn, req.Method, req.Reference, req.Notes, createdBy,
+	).Scan(&id)
+	if err != nil {
+		slog.Error("create payment: database error", "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+		return
+	}
+
+	recordAudit(c, auditActionCreate, "puppy_payments", id, nil, snapshotEntity(c, "puppy_payments", id))
+
+	slog.Info("create payment: payment recorded", "puppy_id", puppyID, "payment_id", id, "kind", req.Kind)
+	c.JSON(http.StatusCreated, gin.H{"message": "Payment recorded", "id": id})
+}
+
+func DeletePayment(c *gin.Context) {
+	puppyID, paymentID := c.Param("id"), c.Param("paymentId")
+	before := snapshotEntity(c, "puppy_payments", paymentID)
+
+	result, err := database.Pool.Exec(c, "DELETE FROM puppy_payments WHERE id=$1 AND puppy_id=$2", paymentID, puppyID)
+	if err != nil {
+		slog.Error("delete payment: database error", "puppy_id", puppyID, "payment_id", paymentID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete payment"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("delete payment: not found", "puppy_id", puppyID, "payment_id", paymentID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+
+	recordAudit(c, auditActionDelete, "puppy_payments", paymentID, before, nil)
+
+	slog.Info("delete payment: payment deleted", "puppy_id", puppyID, "payment_id", paymentID)
+	c.JSON(http.StatusOK, gin.H{"message": "Payment deleted"})
+}
+
+// GetLitterRevenue totals sales and payments per litter, newest first.
+// ?year limits it to litters born that year.
+func GetLitterRevenue(c *gin.Context) {
+	where := ""
+	args := []interface{}{}
+	if value := c.Query("year"); value != "" {
+		year, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid year"})
+			return
+		}
+		args = append(args, year)
+		where = " WHERE extract(year FROM l.birth_date) = $1"
+	}
+
+	query := `
+		SELECT
+			l.id, l.name, count(p.id), count(s.id),
+			COALESCE(sum(s.sale_price), 0)::float8,
+			COALESCE(sum(pay.net), 0)::float8,
+			COALESCE(sum(s.sale_price - COALESCE(pay.net, 0)), 0)::float8
+		FROM litters l
+		LEFT JOIN puppies p ON p.litter_id = l.id
+		LEFT JOIN puppy_sales s ON s.puppy_id = p.id
+		LEFT JOIN (
+			SELECT puppy_id, sum(` + netPayment + `) AS net FROM puppy_payments GROUP BY puppy_id
+		) pay ON pay.puppy_id = p.id` + where + `
+		GROUP BY l.id, l.name, l.birth_date
+		ORDER BY l.birth_date DESC, l.id DESC`
+
+	rows, err := database.Pool.Query(c, query, args...)
+	if err != nil {
+		slog.Error("get litter revenue: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revenue"})
+		return
+	}
+	defer rows.Close()
+
+	report := []models.LitterRevenue{}
+	for rows.Next() {
+		var r models.LitterRevenue
+		if err := rows.Scan(&r.LitterID, &r.LitterName, &r.Puppies, &r.Sold, &r.Booked, &r.Collected, &r.Outstanding); err != nil {
+			slog.Debug("get litter revenue: failed to scan row", "error", err)
+			continue
+		}
+		report = append(report, r)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetYearRevenue totals sales by the year they were made and payments by the
+// year they were received, newest first.
+func GetYearRevenue(c *gin.Context) {
+	query := `
+		WITH sales AS (
+			SELECT extract(year FROM sale_date)::int AS year, count(*) AS sold, sum(sale_price) AS booked
+			FROM puppy_sales GROUP BY 1
+		), payments AS (
+			SELECT extract(year FROM paid_on)::int AS year, sum(` + netPayment + `) AS collected
+			FROM puppy_payments GROUP BY 1
+		)
+		SELECT COALESCE(s.year, p.year), COALESCE(s.sold, 0), COALESCE(s.booked, 0)::float8, COALESCE(p.collected, 0)::float8
+		FROM sales s
+		FULL JOIN payments p ON s.year = p.year
+		ORDER BY 1 DESC`
+
+	rows, err := database.Pool.Query(c, query)
+	if err != nil {
+		slog.Error("get year revenue: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revenue"})
+		return
+	}
+	defer rows.Close()
+
+	report := []models.YearRevenue{}
+	for rows.Next() {
+		var r models.YearRevenue
+		if err := rows.Scan(&r.Year, &r.Sold, &r.Booked, &r.Collected); err != nil {
+			slog.Debug("get year revenue: failed to scan row", "error", err)
+			continue
+		}
+		report = append(report, r)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -55,6 +55,8 @@ func UpdateWaitlistStatus(c *gin.Context) {
 		return
 	}
 
+	before := snapshotEntity(c, "settings", 1)
+
 	query := `UPDATE settings SET waitlist_enabled=$1, updated_at=NOW() WHERE id=1`
 	_, err := database.Pool.Exec(c, query, *input.WaitlistEnabled)
 
@@ -64,6 +66,8 @@ func UpdateWaitlistStatus(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditActionUpdate, "settings", 1, before, snapshotEntity(c, "settings", 1))
+
 	slog.Info("update waitlist status: updated", "waitlist_enabled", *input.WaitlistEnabled)
 	c.JSON(http.StatusOK, gin.H{"message": "Waitlist setting updated", "waitlist_enabled": *input.WaitlistEnabled})
 }
@@ -85,6 +89,8 @@ func UpdateStreamStatus(c *gin.Context) {
 		return
 	}
 
+	before := snapshotEntity(c, "settings", 1)
+
 	if *input.StreamEnabled {
 		if err := stream.Global.Enable(); err != nil {
 			slog.Error("update stream status: failed to start listeners", "error", err)
@@ -109,6 +115,8 @@ func UpdateStreamStatus(c *gin.Context) {
 		stream.Global.Disable()
 	}
 
+	recordAudit(c, auditActionUpdate, "settings", 1, before, snapshotEntity(c, "settings", 1))
+
 	slog.Info("update stream status: updated", "stream_enabled", *input.StreamEnabled)
 	c.JSON(http.StatusOK, gin.H{"message": "Stream setting updated", "stream_enabled": *input.StreamEnabled})
 }
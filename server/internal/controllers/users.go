@@ -42,6 +42,15 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
+	token, err := issueUserToken(c, user.ID, tokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		slog.Warn("create user: failed to issue verification token", "user_id", user.ID, "error", err)
+	} else {
+		sendVerificationEmail(user, token)
+	}
+
+	recordAudit(c, auditActionCreate, "users", user.ID, nil, snapshotEntity(c, "users", user.ID))
+
 	slog.Info("create user: user created", "user_id", user.ID)
 	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "userId": user.ID})
 }
@@ -52,13 +61,13 @@ func GetUser(c *gin.Context) {
 
 	query := `
 		SELECT 
-			id, first_name, last_name, email, phone_number, created_at, updated_at
+			id, first_name, last_name, email, phone_number, email_verified_at, created_at, updated_at
 		FROM users
 		WHERE id = $1`
 
 	err := database.Pool.QueryRow(c, query, id).Scan(
 		&user.ID, &user.FirstName, &user.LastName, &user.Email,
-		&user.PhoneNumber, &user.CreatedAt, &user.UpdatedAt,
+		&user.PhoneNumber, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
@@ -98,6 +107,8 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	before := snapshotEntity(c, "users", idParam)
+
 	updateQuery := `
 		UPDATE users 
 		SET first_name=$1, last_name=$2, phone_number=$3, updated_at=NOW()
@@ -115,6 +126,8 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditActionUpdate, "users", idParam, before, snapshotEntity(c, "users", idParam))
+
 	slog.Info("update user: user updated", "user_id", updatedUser.ID)
 	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully", "user": updatedUser})
 }
@@ -134,6 +147,8 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
+	before := snapshotEntity(c, "users", idParam)
+
 	result, err := database.Pool.Exec(c, "DELETE FROM users WHERE id = $1", idParam)
 	if err != nil {
 		slog.Error("delete user: database error", "user_id", idParam, "error", err)
@@ -147,6 +162,8 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditActionDelete, "users", idParam, before, nil)
+
 	slog.Info("delete user: user deleted", "user_id", idParam)
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
@@ -2,12 +2,20 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/config"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
@@ -42,32 +50,27 @@ func sendWaitlistNotification(entry *models.Waitlist) {
 		return
 	}
 
-	subject := "New Waitlist Entry - April's Lil Pugs"
-
-	htmlBody := fmt.Sprintf(`
-		<h2>New Waitlist Entry Received</h2>
-		<p><strong>Name:</strong> %s %s</p>
-		<p><strong>Email:</strong> %s</p>
-		<p><strong>Phone Number:</strong> %s</p>
-		<p><strong>Status:</strong> %s</p>
-		<p><strong>Preferences/Notes:</strong> %s</p>
-		<p><strong>Date Added:</strong> %s</p>
-		<p>View the waitlist on the <a href="https://aprilslilpugs.com/admin">website</a>.</p>
-	`,
-		entry.FirstName,
-		entry.LastName,
-		entry.Email,
-		entry.Phone,
-		entry.Status,
-		entry.Preferences,
-		entry.CreatedAt.Format("2006-01-02 03:04 PM"),
-	)
+	subject, htmlBody, err := utils.RenderEmailTemplate(context.Background(), utils.EmailTemplateWaitlistAdmin, map[string]any{
+		"FirstName":   entry.FirstName,
+		"LastName":    entry.LastName,
+		"Email":       entry.Email,
+		"Phone":       entry.Phone,
+		"Status":      entry.Status,
+		"Preferences": entry.Preferences,
+		"LookingFor":  describeWaitlistPreferences(entry),
+		"CreatedAt":   entry.CreatedAt.Format("2006-01-02 03:04 PM"),
+		"AdminURL":    strings.TrimSuffix(config.Load().AppBaseURL, "/") + "/admin",
+	})
+	if err != nil {
+		slog.Error("waitlist notification: failed to render template", "error", err)
+		return
+	}
 
-	slog.Info("waitlist notification: dispatching emails", "recipient_count", len(recipients))
+	slog.Info("waitlist notification: queueing emails", "recipient_count", len(recipients))
 
 	for _, email := range recipients {
-		if err := utils.SendEmail([]string{email}, subject, htmlBody); err != nil {
-			slog.Error("waitlist notification: failed to send email", "recipient", email, "error", err)
+		if _, err := utils.QueueEmail(context.Background(), []string{email}, subject, htmlBody); err != nil {
+			slog.Error("waitlist notification: failed to queue email", "recipient", email, "error", err)
 			failureCount++
 			continue
 		}
@@ -75,14 +78,39 @@ func sendWaitlistNotification(entry *models.Waitlist) {
 		successCount++
 	}
 
-	slog.Info("waitlist notification: email dispatch finished", "recipient_count", len(recipients), "success_count", successCount, "failure_count", failureCount)
+	slog.Info("waitlist notification: emails queued", "recipient_count", len(recipients), "success_count", successCount, "failure_count", failureCount)
+}
+
+func sendWaitlistConfirmation(entry *models.Waitlist) {
+	portalURL := ""
+	if token, err := issueWaitlistToken(context.Background(), entry.ID); err != nil {
+		slog.Warn("waitlist confirmation: failed to issue portal link", "waitlist_id", entry.ID, "error", err)
+	} else {
+		portalURL = buildAppLink("/waitlist/portal", token)
+	}
+
+	subject, htmlBody, err := utils.RenderEmailTemplate(context.Background(), utils.EmailTemplateWaitlistConfirmation, map[string]any{
+		"FirstName":   entry.FirstName,
+		"LastName":    entry.LastName,
+		"Preferences": entry.Preferences,
+		"SiteURL":     config.Load().AppBaseURL,
+		"PortalURL":   portalURL,
+	})
+	if err != nil {
+		slog.Error("waitlist confirmation: failed to render template", "error", err)
+		return
+	}
+
+	if _, err := utils.QueueSensitiveEmail(context.Background(), []string{entry.Email}, subject, htmlBody); err != nil {
+		slog.Error("waitlist confirmation: failed to queue email", "waitlist_id", entry.ID, "error", err)
+		return
+	}
+
+	slog.Info("waitlist confirmation: email queued", "waitlist_id", entry.ID)
 }
 
 func GetWaitlist(c *gin.Context) {
-	query := `
-		SELECT id, first_name, last_name, email, phone, preferences, status, created_at, updated_at
-		FROM waitlist
-		ORDER BY created_at ASC`
+	query := `SELECT ` + waitlistColumns + ` FROM waitlist ORDER BY ` + waitlistQueueOrder
 
 	rows, err := database.Pool.Query(c, query)
 	if err != nil {
@@ -94,10 +122,8 @@ func GetWaitlist(c *gin.Context) {
 
 	var waitlist []models.Waitlist
 	for rows.Next() {
-		var w models.Waitlist
-		if err := rows.Scan(
-			&w.ID, &w.FirstName, &w.LastName, &w.Email, &w.Phone, &w.Preferences, &w.Status, &w.CreatedAt, &w.UpdatedAt,
-		); err != nil {
+		w, err := scanWaitlist(rows)
+		if err != nil {
 			slog.Debug("get waitlist: failed to scan row", "error", err)
 			continue
 		}
@@ -110,22 +136,285 @@ func GetWaitlist(c *gin.Context) {
 	c.JSON(http.StatusOK, waitlist)
 }
 
+const waitlistQueueOrder = `queue_position ASC NULLS LAST, created_at ASC, id ASC`
+
+const waitlistColumns = `
+	id, first_name, last_name, email, COALESCE(phone, ''), COALESCE(preferences, ''),
+	preferred_gender, preferred_colors, desired_from, desired_by, deposit_status,
+	deposit_amount::float8, deposit_date, deposit_method, queue_position,
+	status, created_at, updated_at`
+
+func scanWaitlist(row pgx.Row) (models.Waitlist, error) {
+	var w models.Waitlist
+	err := row.Scan(
+		&w.ID, &w.FirstName, &w.LastName, &w.Email, &w.Phone, &w.Preferences,
+		&w.PreferredGender, &w.PreferredColors, &w.DesiredFrom, &w.DesiredBy, &w.DepositStatus,
+		&w.DepositAmount, &w.DepositDate, &w.DepositMethod, &w.QueuePosition,
+		&w.Status, &w.CreatedAt, &w.UpdatedAt,
+	)
+	return w, err
+}
+
+type waitlistPreferenceInput struct {
+	gender      *string
+	colors      []string
+	desiredFrom *time.Time
+	desiredBy   *time.Time
+}
+
+// parseWaitlistPreferences reads the structured preference fields. Colors may
+// be sent as repeated preferred_colors values or as one comma-separated value.
+func parseWaitlistPreferences(c *gin.Context) (waitlistPreferenceInput, error) {
+	var prefs waitlistPreferenceInput
+
+	switch gender := strings.TrimSpace(c.PostForm("preferred_gender")); gender {
+	case "", "Any":
+	case "Male", "Female":
+		prefs.gender = &gender
+	default:
+		return prefs, fmt.Errorf("preferred gender must be Male, Female or Any")
+	}
+
+	prefs.colors = []string{}
+	seen := make(map[string]bool)
+	for _, value := range c.PostFormArray("preferred_colors") {
+		for _, color := range strings.Split(value, ",") {
+			color = strings.TrimSpace(color)
+			if color == "" || seen[strings.ToLower(color)] {
+				continue
+			}
+			if len(color) > 50 {
+				return prefs, fmt.Errorf("color names must be 50 characters or fewer")
+			}
+			seen[strings.ToLower(color)] = true
+			prefs.colors = append(prefs.colors, color)
+		}
+	}
+	if len(prefs.colors) > 10 {
+		return prefs, fmt.Errorf("choose at most 10 colors")
+	}
+
+	for _, field := range []struct {
+		key  string
+		dest **time.Time
+	}{{"desired_from", &prefs.desiredFrom}, {"desired_by", &prefs.desiredBy}} {
+		value := strings.TrimSpace(c.PostForm(field.key))
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return prefs, fmt.Errorf("%s must be a date in YYYY-MM-DD format", field.key)
+		}
+		*field.dest = &date
+	}
+	if prefs.desiredFrom != nil && prefs.desiredBy != nil && prefs.desiredBy.Before(*prefs.desiredFrom) {
+		return prefs, fmt.Errorf("desired_by must not be before desired_from")
+	}
+
+	return prefs, nil
+}
+
+// describeWaitlistPreferences summarizes the structured preferences for
+// emails, e.g. "Female, Fawn or Black, from 2025-03-01".
+func describeWaitlistPreferences(w *models.Waitlist) string {
+	var parts []string
+	if w.PreferredGender != nil {
+		parts = append(parts, *w.PreferredGender)
+	}
+	if len(w.PreferredColors) > 0 {
+		parts = append(parts, strings.Join(w.PreferredColors, " or "))
+	}
+	switch {
+	case w.DesiredFrom != nil && w.DesiredBy != nil:
+		parts = append(parts, "between "+w.DesiredFrom.Format("2006-01-02")+" and "+w.DesiredBy.Format("2006-01-02"))
+	case w.DesiredFrom != nil:
+		parts = append(parts, "from "+w.DesiredFrom.Format("2006-01-02"))
+	case w.DesiredBy != nil:
+		parts = append(parts, "by "+w.DesiredBy.Format("2006-01-02"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+const (
+	// waitlistHoneypotField is rendered off-screen on the public form. People
+	// never fill it in; naive bots fill in every field.
+	waitlistHoneypotField = "website"
+	// waitlistFormTokenField carries the token from GetWaitlistFormToken,
+	// which records when the server handed out the form.
+	waitlistFormTokenField = "form_token"
+	waitlistFormMaxAge     = 24 * time.Hour
+)
+
+// GetWaitlistFormToken is fetched when the public form loads. CreateWaitlist
+// measures the fill time from the token rather than the browser's clock.
+func GetWaitlistFormToken(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"token":          utils.SignFormToken("waitlist", time.Now()),
+		"minFillSeconds": int(waitlistMinFillTime().Seconds()),
+	})
+}
+
 func CreateWaitlist(c *gin.Context) {
-	firstName := c.PostForm("firstname")
-	lastName := c.PostForm("lastname")
-	email := c.PostForm("email")
-	phone := c.PostForm("phone")
-	preferences := c.PostForm("preferences")
+	firstName := strings.TrimSpace(c.PostForm("firstname"))
+	lastName := strings.TrimSpace(c.PostForm("lastname"))
+	email := strings.TrimSpace(c.PostForm("email"))
+	phone := strings.TrimSpace(c.PostForm("phone"))
+	preferences := strings.TrimSpace(c.PostForm("preferences"))
 	status := "New"
 
+	open, err := waitlistEnabled(c)
+	if err != nil {
+		slog.Error("create waitlist: failed to load settings", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create waitlist entry"})
+		return
+	}
+	if !open {
+		slog.Debug("create waitlist: waitlist is closed", "remote_addr", c.ClientIP())
+		c.JSON(http.StatusForbidden, gin.H{"error": "The waitlist is currently closed"})
+		return
+	}
+
+	if firstName == "" || lastName == "" || email == "" {
+		slog.Debug("create waitlist: missing required fields")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "First name, last name and email are required"})
+		return
+	}
+	if _, err := mail.ParseAddress(email); err != nil {
+		slog.Debug("create waitlist: invalid email", "email", email, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+		return
+	}
+
+	prefs, err := parseWaitlistPreferences(c)
+	if err != nil {
+		slog.Debug("create waitlist: invalid preferences", "email", email, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Bots get the normal success response so they have no signal to adapt to.
+	if c.PostForm(waitlistHoneypotField) != "" {
+		slog.Warn("create waitlist: honeypot field filled, discarding", "remote_addr", c.ClientIP(), "email", email)
+		c.JSON(http.StatusCreated, gin.H{"message": "Joined waitlist"})
+		return
+	}
+
+	issuedAt, err := utils.VerifyFormToken("waitlist", c.PostForm(waitlistFormTokenField))
+	if err != nil || time.Since(issuedAt) > waitlistFormMaxAge {
+		slog.Debug("create waitlist: missing or expired form token", "remote_addr", c.ClientIP(), "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This form has expired, please reload the page and try again"})
+		return
+	}
+	if fillTime := time.Since(issuedAt); fillTime < waitlistMinFillTime() {
+		slog.Warn("create waitlist: form submitted too quickly", "remote_addr", c.ClientIP(), "email", email, "fill_time", fillTime.String())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "That was quick! Please check your details and submit the form again"})
+		return
+	}
+
+	emailNormalized := utils.NormalizeEmail(email)
+	phoneNormalized := utils.NormalizePhone(phone)
+	if !utils.IsMatchablePhone(phoneNormalized) {
+		phoneNormalized = ""
+	}
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("create waitlist: failed to begin transaction", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create waitlist entry"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	// Serialize submissions for the same email or phone so a double-submit
+	// can't race past the duplicate check. The email lock is always taken
+	// first so two submissions can't deadlock on each other's keys.
+	lockKeys := []string{"waitlist:" + emailNormalized}
+	if phoneNormalized != "" {
+		lockKeys = append(lockKeys, "waitlist-phone:"+phoneNormalized)
+	}
+	for _, key := range lockKeys {
+		if _, err := tx.Exec(c, "SELECT pg_advisory_xact_lock(hashtext($1))", key); err != nil {
+			slog.Error("create waitlist: failed to acquire lock", "email", email, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create waitlist entry"})
+			return
+		}
+	}
+
+	// Only an exact email match is merged. Anyone can submit this form, so a
+	// phone-only match is saved as its own entry for an admin to merge from
+	// the duplicates report rather than letting a stranger attach to it.
+	// Closed entries are left alone so a returning family rejoins the queue.
+	var existing models.Waitlist
+	err = tx.QueryRow(c, `
+		SELECT id, COALESCE(phone, ''), COALESCE(preferences, '')
+		FROM waitlist
+		WHERE email_normalized = $1 AND status NOT IN ('Complete', 'Withdrawn')
+		ORDER BY created_at ASC
+		LIMIT 1
+		FOR UPDATE`, emailNormalized,
+	).Scan(&existing.ID, &existing.Phone, &existing.Preferences)
+
+	if err == nil {
+		// Repeat submissions keep their original place in the queue and
+		// don't trigger another round of notification emails. The stored
+		// name, email and phone are left alone; the family can change them
+		// from the portal.
+		_, err = tx.Exec(c, `
+			UPDATE waitlist
+			SET phone=$1, preferences=$2,
+				preferred_gender=COALESCE($3, preferred_gender),
+				preferred_colors=CASE WHEN cardinality($4::text[]) > 0 THEN $4 ELSE preferred_colors END,
+				desired_from=COALESCE($5, desired_from), desired_by=COALESCE($6, desired_by),
+				updated_at=NOW()
+			WHERE id=$7`,
+			preferOrKeep(existing.Phone, phone),
+			mergeWaitlistText(existing.Preferences, preferences),
+			prefs.gender, prefs.colors, prefs.desiredFrom, prefs.desiredBy, existing.ID,
+		)
+		if err != nil {
+			slog.Error("create waitlist: failed to merge duplicate entry", "waitlist_id", existing.ID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create waitlist entry"})
+			return
+		}
+
+		if err := tx.Commit(c); err != nil {
+			slog.Error("create waitlist: failed to commit merge", "waitlist_id", existing.ID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create waitlist entry"})
+			return
+		}
+
+		slog.Info("create waitlist: merged into existing entry", "waitlist_id", existing.ID, "email", email)
+		c.JSON(http.StatusCreated, gin.H{"message": "Joined waitlist"})
+		return
+	}
+	if err != pgx.ErrNoRows {
+		slog.Error("create waitlist: failed to check for duplicates", "email", email, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create waitlist entry"})
+		return
+	}
+
+	// The per-email locks don't stop two different families from reading
+	// the same MAX(queue_position), so the position is assigned under the
+	// same table lock the CSV import uses.
+	if _, err := tx.Exec(c, "LOCK TABLE waitlist IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		slog.Error("create waitlist: failed to lock queue", "email", email, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create waitlist entry"})
+		return
+	}
+
 	var newID int
 	query := `
-		INSERT INTO waitlist (first_name, last_name, email, phone, preferences, status)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO waitlist (
+			first_name, last_name, email, phone, preferences, status,
+			preferred_gender, preferred_colors, desired_from, desired_by, queue_position
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, (SELECT COALESCE(MAX(queue_position), 0) + 1 FROM waitlist))
 		RETURNING id`
 
-	err := database.Pool.QueryRow(c, query,
+	err = tx.QueryRow(c, query,
 		firstName, lastName, email, phone, preferences, status,
+		prefs.gender, prefs.colors, prefs.desiredFrom, prefs.desiredBy,
 	).Scan(&newID)
 
 	if err != nil {
@@ -134,61 +423,465 @@ func CreateWaitlist(c *gin.Context) {
 		return
 	}
 
+	if err := recordWaitlistStatusChange(c, tx, newID, nil, status, "Joined waitlist"); err != nil {
+		slog.Error("create waitlist: failed to record status history", "waitlist_id", newID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create waitlist entry"})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("create waitlist: failed to commit transaction", "email", email, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create waitlist entry"})
+		return
+	}
+
 	entry := models.Waitlist{
-		FirstName:   firstName,
-		LastName:    lastName,
-		Email:       email,
-		Phone:       phone,
-		Preferences: preferences,
-		Status:      status,
-		CreatedAt:   time.Now(),
+		ID:              newID,
+		FirstName:       firstName,
+		LastName:        lastName,
+		Email:           email,
+		Phone:           phone,
+		Preferences:     preferences,
+		PreferredGender: prefs.gender,
+		PreferredColors: prefs.colors,
+		DesiredFrom:     prefs.desiredFrom,
+		DesiredBy:       prefs.desiredBy,
+		DepositStatus:   "None",
+		Status:          status,
+		CreatedAt:       time.Now(),
 	}
 
-	go sendWaitlistNotification(&entry)
+	sendWaitlistNotification(&entry)
+	sendWaitlistConfirmation(&entry)
 
 	slog.Info("create waitlist: entry created", "waitlist_id", newID, "email", email)
-	c.JSON(http.StatusCreated, gin.H{"message": "Joined waitlist", "id": newID})
+	c.JSON(http.StatusCreated, gin.H{"message": "Joined waitlist"})
+}
+
+func waitlistEnabled(c *gin.Context) (bool, error) {
+	var enabled bool
+	err := database.Pool.QueryRow(c, "SELECT waitlist_enabled FROM settings WHERE id = 1").Scan(&enabled)
+	if err == pgx.ErrNoRows {
+		// GetSettings creates the row with the waitlist open.
+		return true, nil
+	}
+	return enabled, err
+}
+
+func waitlistMinFillTime() time.Duration {
+	seconds, err := strconv.Atoi(config.Load().WaitlistMinFill)
+	if err != nil || seconds < 0 {
+		seconds = 3
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// waitlistStatusRank orders the statuses that reflect money or a puppy
+// changing hands, so merging entries never drops a family back out of them.
+func waitlistStatusRank(status string) int {
+	switch status {
+	case "Matched":
+		return 2
+	case "Deposited":
+		return 1
+	}
+	return 0
+}
+
+func preferOrKeep(latest, existing string) string {
+	if latest != "" {
+		return latest
+	}
+	return existing
+}
+
+// mergeWaitlistText appends addition to existing unless it is empty or
+// already present, so repeated submissions don't pile up identical notes.
+func mergeWaitlistText(existing, addition string) string {
+	existing = strings.TrimSpace(existing)
+	addition = strings.TrimSpace(addition)
+
+	if addition == "" || strings.Contains(strings.ToLower(existing), strings.ToLower(addition)) {
+		return existing
+	}
+	if existing == "" {
+		return addition
+	}
+	return existing + "\n\n" + addition
+}
+
+func GetWaitlistDuplicates(c *gin.Context) {
+	query := `
+		SELECT 'email', email_normalized, array_agg(id ORDER BY created_at, id)
+		FROM waitlist
+		GROUP BY email_normalized
+		HAVING count(*) > 1
+		UNION ALL
+		SELECT 'phone', phone_normalized, array_agg(id ORDER BY created_at, id)
+		FROM waitlist
+		WHERE length(phone_normalized) >= 7
+		GROUP BY phone_normalized
+		HAVING count(*) > 1`
+
+	rows, err := database.Pool.Query(c, query)
+	if err != nil {
+		slog.Error("get waitlist duplicates: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist duplicates"})
+		return
+	}
+
+	type duplicateRow struct {
+		matchedOn string
+		value     string
+		ids       []int
+	}
+
+	var found []duplicateRow
+	var allIDs []int
+	for rows.Next() {
+		var d duplicateRow
+		if err := rows.Scan(&d.matchedOn, &d.value, &d.ids); err != nil {
+			slog.Debug("get waitlist duplicates: failed to scan row", "error", err)
+			continue
+		}
+		found = append(found, d)
+		allIDs = append(allIDs, d.ids...)
+	}
+	rows.Close()
+
+	groups := []models.WaitlistDuplicateGroup{}
+	if len(found) == 0 {
+		c.JSON(http.StatusOK, groups)
+		return
+	}
+
+	entryRows, err := database.Pool.Query(c, `SELECT `+waitlistColumns+` FROM waitlist WHERE id = ANY($1)`, allIDs)
+	if err != nil {
+		slog.Error("get waitlist duplicates: failed to fetch entries", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist duplicates"})
+		return
+	}
+	defer entryRows.Close()
+
+	entries := make(map[int]models.Waitlist)
+	for entryRows.Next() {
+		w, err := scanWaitlist(entryRows)
+		if err != nil {
+			slog.Debug("get waitlist duplicates: failed to scan entry", "error", err)
+			continue
+		}
+		entries[w.ID] = w
+	}
+
+	for _, d := range found {
+		group := models.WaitlistDuplicateGroup{MatchedOn: d.matchedOn, Value: d.value, Entries: []models.Waitlist{}}
+		for _, id := range d.ids {
+			if w, ok := entries[id]; ok {
+				group.Entries = append(group.Entries, w)
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// MergeWaitlist folds the source entries into the target. The merged entry
+// keeps the earliest created_at so nobody loses their place in the queue.
+func MergeWaitlist(c *gin.Context) {
+	id := c.Param("id")
+	targetID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid waitlist ID"})
+		return
+	}
+
+	var req models.MergeWaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("merge waitlist: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	for _, sourceID := range req.SourceIDs {
+		if sourceID == targetID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "An entry cannot be merged into itself"})
+			return
+		}
+	}
+
+	before := snapshotEntity(c, "waitlist", targetID)
+	sourceSnapshots := make(map[int]map[string]any)
+	for _, sourceID := range req.SourceIDs {
+		sourceSnapshots[sourceID] = snapshotEntity(c, "waitlist", sourceID)
+	}
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("merge waitlist: failed to begin transaction", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge waitlist entries"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+		SELECT `+waitlistColumns+`
+		FROM waitlist
+		WHERE id = $1 OR id = ANY($2)
+		ORDER BY `+waitlistQueueOrder+`
+		FOR UPDATE`, targetID, req.SourceIDs)
+	if err != nil {
+		slog.Error("merge waitlist: failed to load entries", "waitlist_id", targetID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge waitlist entries"})
+		return
+	}
+
+	var target *models.Waitlist
+	var sources []models.Waitlist
+	for rows.Next() {
+		w, err := scanWaitlist(rows)
+		if err != nil {
+			rows.Close()
+			slog.Error("merge waitlist: failed to scan entry", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge waitlist entries"})
+			return
+		}
+		if w.ID == targetID {
+			target = &w
+		} else {
+			sources = append(sources, w)
+		}
+	}
+	rows.Close()
+
+	if target == nil {
+		slog.Debug("merge waitlist: target not found", "waitlist_id", targetID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+		return
+	}
+	if len(sources) != len(req.SourceIDs) {
+		slog.Debug("merge waitlist: source entries not found", "waitlist_id", targetID, "source_ids", req.SourceIDs)
+		c.JSON(http.StatusNotFound, gin.H{"error": "One or more entries to merge were not found"})
+		return
+	}
+
+	merged := *target
+	for _, src := range sources {
+		merged.FirstName = preferOrKeep(merged.FirstName, src.FirstName)
+		merged.LastName = preferOrKeep(merged.LastName, src.LastName)
+		merged.Phone = preferOrKeep(merged.Phone, src.Phone)
+		merged.Preferences = mergeWaitlistText(merged.Preferences, src.Preferences)
+		if merged.PreferredGender == nil {
+			merged.PreferredGender = src.PreferredGender
+		}
+		if len(merged.PreferredColors) == 0 {
+			merged.PreferredColors = src.PreferredColors
+		}
+		if merged.DesiredFrom == nil && merged.DesiredBy == nil {
+			merged.DesiredFrom, merged.DesiredBy = src.DesiredFrom, src.DesiredBy
+		}
+		if merged.DepositStatus == "None" {
+			merged.DepositStatus = src.DepositStatus
+		}
+		if waitlistStatusRank(src.Status) > waitlistStatusRank(merged.Status) {
+			merged.Status = src.Status
+		}
+		if src.CreatedAt.Before(merged.CreatedAt) {
+			merged.CreatedAt = src.CreatedAt
+		}
+		if src.QueuePosition != nil && (merged.QueuePosition == nil || *src.QueuePosition < *merged.QueuePosition) {
+			merged.QueuePosition = src.QueuePosition
+		}
+		if merged.DepositAmount == nil && src.DepositAmount != nil {
+			merged.DepositAmount, merged.DepositDate, merged.DepositMethod = src.DepositAmount, src.DepositDate, src.DepositMethod
+		}
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE waitlist
+		SET first_name=$1, last_name=$2, phone=$3, preferences=$4, created_at=$5,
+			preferred_gender=$6, preferred_colors=$7, desired_from=$8, desired_by=$9, deposit_status=$10,
+			deposit_amount=$11, deposit_date=$12, deposit_method=$13, queue_position=$14, status=$15,
+			updated_at=NOW()
+		WHERE id=$16`,
+		merged.FirstName, merged.LastName, merged.Phone, merged.Preferences, merged.CreatedAt,
+		merged.PreferredGender, merged.PreferredColors, merged.DesiredFrom, merged.DesiredBy, merged.DepositStatus,
+		merged.DepositAmount, merged.DepositDate, merged.DepositMethod, merged.QueuePosition, merged.Status,
+		targetID,
+	)
+	if err != nil {
+		slog.Error("merge waitlist: failed to update target", "waitlist_id", targetID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge waitlist entries"})
+		return
+	}
+
+	if merged.Status != target.Status {
+		if err := recordWaitlistStatusChange(c, tx, targetID, &target.Status, merged.Status, "Merged duplicate entries"); err != nil {
+			slog.Error("merge waitlist: failed to record status history", "waitlist_id", targetID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge waitlist entries"})
+			return
+		}
+	}
+
+	// Reservations follow the family; the sources are deleted below.
+	if _, err := tx.Exec(c, "UPDATE puppy_reservations SET waitlist_id=$1, updated_at=NOW() WHERE waitlist_id = ANY($2)", targetID, req.SourceIDs); err != nil {
+		slog.Error("merge waitlist: failed to move reservations", "waitlist_id", targetID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge waitlist entries"})
+		return
+	}
+
+	if _, err := tx.Exec(c, "DELETE FROM waitlist WHERE id = ANY($1)", req.SourceIDs); err != nil {
+		slog.Error("merge waitlist: failed to delete merged entries", "waitlist_id", targetID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge waitlist entries"})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("merge waitlist: failed to commit transaction", "waitlist_id", targetID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge waitlist entries"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "waitlist", targetID, before, snapshotEntity(c, "waitlist", targetID))
+	for sourceID, snapshot := range sourceSnapshots {
+		recordAudit(c, auditActionDelete, "waitlist", sourceID, snapshot, nil)
+	}
+
+	slog.Info("merge waitlist: entries merged", "waitlist_id", targetID, "source_ids", req.SourceIDs)
+	c.JSON(http.StatusOK, gin.H{"message": "Waitlist entries merged", "id": targetID})
 }
 
 func UpdateWaitlist(c *gin.Context) {
 	id := c.Param("id")
+	waitlistID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid waitlist ID"})
+		return
+	}
+	before := snapshotEntity(c, "waitlist", id)
 
 	firstName := c.PostForm("firstname")
 	lastName := c.PostForm("lastname")
 	email := c.PostForm("email")
 	phone := c.PostForm("phone")
 	preferences := c.PostForm("preferences")
-	status := c.PostForm("status")
+	status, hasStatus := c.GetPostForm("status")
 
-	query := `
-		UPDATE waitlist 
-		SET first_name=$1, last_name=$2, email=$3, phone=$4, preferences=$5, status=$6, updated_at=NOW()
-		WHERE id=$7`
+	if hasStatus && !slices.Contains(models.WaitlistStatuses, status) {
+		slog.Debug("update waitlist: invalid status", "waitlist_id", id, "status", status)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
 
-	result, err := database.Pool.Exec(c, query,
-		firstName, lastName, email, phone, preferences, status, id,
-	)
+	prefs, err := parseWaitlistPreferences(c)
+	if err != nil {
+		slog.Debug("update waitlist: invalid preferences", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
+	sets := "first_name=$1, last_name=$2, email=$3, phone=$4, preferences=$5"
+	args := []interface{}{firstName, lastName, email, phone, preferences}
+
+	// Status and structured preferences are only touched when the form
+	// includes them, so older clients that don't know about them can't wipe
+	// them out.
+	setOptional := func(key, column string, value interface{}) {
+		if _, ok := c.GetPostFormArray(key); ok {
+			args = append(args, value)
+			sets += fmt.Sprintf(", %s=$%d", column, len(args))
+		}
+	}
+	setOptional("status", "status", status)
+	setOptional("preferred_gender", "preferred_gender", prefs.gender)
+	setOptional("preferred_colors", "preferred_colors", prefs.colors)
+	setOptional("desired_from", "desired_from", prefs.desiredFrom)
+	setOptional("desired_by", "desired_by", prefs.desiredBy)
+
+	if depositStatus, ok := c.GetPostForm("deposit_status"); ok {
+		switch depositStatus {
+		case "None", "Requested", "Paid", "Refunded":
+			setOptional("deposit_status", "deposit_status", depositStatus)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deposit status"})
+			return
+		}
+	}
+
+	args = append(args, id)
+	query := fmt.Sprintf(`UPDATE waitlist SET %s, updated_at=NOW() WHERE id=$%d`, sets, len(args))
+
+	tx, err := database.Pool.Begin(c)
 	if err != nil {
+		slog.Error("update waitlist: failed to begin transaction", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update waitlist entry"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	var previousStatus string
+	if err := tx.QueryRow(c, "SELECT status FROM waitlist WHERE id=$1 FOR UPDATE", id).Scan(&previousStatus); err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("update waitlist: not found", "waitlist_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+			return
+		}
+
+		slog.Error("update waitlist: failed to fetch entry", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update waitlist entry"})
+		return
+	}
+
+	if _, err := tx.Exec(c, query, args...); err != nil {
 		slog.Error("update waitlist: database error", "waitlist_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update waitlist entry"})
 		return
 	}
 
-	if result.RowsAffected() == 0 {
-		slog.Debug("update waitlist: not found", "waitlist_id", id)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+	if hasStatus && status != previousStatus {
+		if err := recordWaitlistStatusChange(c, tx, waitlistID, &previousStatus, status, c.PostForm("status_note")); err != nil {
+			slog.Error("update waitlist: failed to record status history", "waitlist_id", id, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update waitlist entry"})
+			return
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("update waitlist: failed to commit transaction", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update waitlist entry"})
 		return
 	}
 
+	recordAudit(c, auditActionUpdate, "waitlist", id, before, snapshotEntity(c, "waitlist", id))
+
 	slog.Info("update waitlist: entry updated", "waitlist_id", id, "status", status)
 	c.JSON(http.StatusOK, gin.H{"message": "Waitlist entry updated"})
 }
 
 func DeleteWaitlist(c *gin.Context) {
 	id := c.Param("id")
+	before := snapshotEntity(c, "waitlist", id)
+
+	var activeReservations int
+	if err := database.Pool.QueryRow(c, "SELECT count(*) FROM puppy_reservations WHERE waitlist_id=$1 AND status='Active'", id).Scan(&activeReservations); err != nil {
+		slog.Error("delete waitlist: failed to check reservations", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete waitlist entry"})
+		return
+	}
+	if activeReservations > 0 {
+		slog.Debug("delete waitlist: entry has an active reservation", "waitlist_id", id)
+		c.JSON(http.StatusConflict, gin.H{"error": "Cancel this family's active reservation before deleting the entry"})
+		return
+	}
+
 	result, err := database.Pool.Exec(c, "DELETE FROM waitlist WHERE id=$1", id)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			slog.Debug("delete waitlist: entry has reservation history", "waitlist_id", id)
+			c.JSON(http.StatusConflict, gin.H{"error": "This family has reservation history; withdraw the entry instead of deleting it"})
+			return
+		}
 		slog.Error("delete waitlist: database error", "waitlist_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete waitlist entry"})
 		return
@@ -200,6 +893,8 @@ func DeleteWaitlist(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditActionDelete, "waitlist", id, before, nil)
+
 	slog.Info("delete waitlist: entry deleted", "waitlist_id", id)
 	c.JSON(http.StatusOK, gin.H{"message": "Waitlist entry deleted"})
 }
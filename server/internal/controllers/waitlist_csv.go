@@ -0,0 +1,599 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
+)
+
+const (
+	waitlistImportMaxBytes = 5 << 20
+	waitlistImportMaxRows  = 5000
+)
+
+// waitlistCSVColumns is both the export header and the set of fields an
+// import can map to, so an exported file can be re-imported as-is.
+var waitlistCSVColumns = []string{
+	"queue_position", "first_name", "last_name", "email", "phone", "status",
+	"preferred_gender", "preferred_colors", "desired_from", "desired_by",
+	"deposit_status", "deposit_amount", "deposit_date", "deposit_method",
+	"preferences", "created_at",
+}
+
+// waitlistImportAliases maps normalized spreadsheet headers to import fields.
+// "name" is split into first and last name when those aren't mapped.
+var waitlistImportAliases = map[string]string{
+	"first": "first_name", "firstname": "first_name", "given_name": "first_name",
+	"last": "last_name", "lastname": "last_name", "surname": "last_name", "family_name": "last_name",
+	"name": "name", "full_name": "name",
+	"email_address": "email", "e_mail": "email",
+	"phone_number": "phone", "telephone": "phone", "mobile": "phone", "cell": "phone",
+	"notes": "preferences", "comments": "preferences",
+	"gender": "preferred_gender", "sex": "preferred_gender",
+	"color": "preferred_colors", "colors": "preferred_colors", "colour": "preferred_colors", "colours": "preferred_colors",
+	"deposit": "deposit_amount", "deposit_paid": "deposit_amount",
+	"date_added": "created_at", "signup_date": "created_at", "date": "created_at", "timestamp": "created_at",
+}
+
+var csvHeaderCleaner = regexp.MustCompile(`[^a-z0-9]+`)
+
+func normalizeCSVHeader(header string) string {
+	header = strings.TrimPrefix(header, "\ufeff")
+	return strings.Trim(csvHeaderCleaner.ReplaceAllString(strings.ToLower(header), "_"), "_")
+}
+
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// spreadsheetSafe stops spreadsheet apps from treating user-entered text as
+// a formula when the export is opened. Both formats get it, since a plain
+// CSV opens in Excel just the same.
+func spreadsheetSafe(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// unspreadsheetSafe undoes spreadsheetSafe so an export can be re-imported
+// as is.
+func unspreadsheetSafe(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+func ExportWaitlist(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "excel" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be csv or excel"})
+		return
+	}
+
+	where := " WHERE 1=1"
+	args := []interface{}{}
+
+	if statusParam := c.Query("status"); statusParam != "" {
+		statuses := strings.Split(statusParam, ",")
+		for _, status := range statuses {
+			if !slices.Contains(models.WaitlistStatuses, status) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status: " + status})
+				return
+			}
+		}
+		args = append(args, statuses)
+		where += fmt.Sprintf(" AND status::text = ANY($%d)", len(args))
+	}
+	if from := c.Query("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be in YYYY-MM-DD format"})
+			return
+		}
+		args = append(args, date)
+		where += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be in YYYY-MM-DD format"})
+			return
+		}
+		args = append(args, date.AddDate(0, 0, 1))
+		where += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+
+	rows, err := database.Pool.Query(c, `SELECT `+waitlistColumns+` FROM waitlist`+where+` ORDER BY `+waitlistQueueOrder, args...)
+	if err != nil {
+		slog.Error("export waitlist: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export waitlist"})
+		return
+	}
+	defer rows.Close()
+
+	var entries []models.Waitlist
+	for rows.Next() {
+		w, err := scanWaitlist(rows)
+		if err != nil {
+			slog.Debug("export waitlist: failed to scan row", "error", err)
+			continue
+		}
+		entries = append(entries, w)
+	}
+
+	filename := fmt.Sprintf("waitlist-%s.csv", time.Now().Format("2006-01-02"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	if format == "excel" {
+		// Excel needs the BOM to read UTF-8 and expects CRLF line endings.
+		c.Writer.WriteString("\ufeff")
+		w.UseCRLF = true
+	}
+
+	w.Write(waitlistCSVColumns)
+	for _, e := range entries {
+		record := []string{
+			optionalInt(e.QueuePosition), e.FirstName, e.LastName, e.Email, e.Phone, e.Status,
+			optionalString(e.PreferredGender), strings.Join(e.PreferredColors, "; "), optionalDate(e.DesiredFrom), optionalDate(e.DesiredBy),
+			e.DepositStatus, optionalAmount(e.DepositAmount), optionalDate(e.DepositDate), optionalString(e.DepositMethod),
+			e.Preferences, e.CreatedAt.Format(time.RFC3339),
+		}
+		for i := range record {
+			record[i] = spreadsheetSafe(record[i])
+		}
+		w.Write(record)
+	}
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		slog.Error("export waitlist: failed to write csv", "error", err)
+		return
+	}
+
+	slog.Info("export waitlist: exported", "rows", len(entries), "format", format)
+}
+
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func optionalString(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+func optionalDate(v *time.Time) string {
+	if v == nil {
+		return ""
+	}
+	return v.Format("2006-01-02")
+}
+
+func optionalAmount(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', 2, 64)
+}
+
+// importedWaitlistEntry is one validated CSV row ready to insert.
+type importedWaitlistEntry struct {
+	models.Waitlist
+	createdAt *time.Time
+}
+
+// ImportWaitlist reads a CSV upload and creates waitlist entries in file
+// order. dry_run (the default) validates and reports without writing.
+// mapping is an optional JSON object of {"field": "CSV header"} overriding
+// the automatic header matching. Rows whose email is already on the
+// waitlist, or repeated earlier in the file, are skipped.
+func ImportWaitlist(c *gin.Context) {
+	dryRun := c.DefaultPostForm("dry_run", "true") != "false"
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	if fileHeader.Size > waitlistImportMaxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "CSV file must be 5MB or smaller"})
+		return
+	}
+
+	var overrides map[string]string
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object of field to column header"})
+			return
+		}
+		for field := range overrides {
+			if field != "name" && !slices.Contains(waitlistCSVColumns, field) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown mapping field: " + field})
+				return
+			}
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		slog.Error("import waitlist: failed to open upload", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read CSV file"})
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	headers, err := reader.Read()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is empty or unreadable"})
+		return
+	}
+
+	columnIndex, report, err := mapWaitlistColumns(headers, overrides)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	report.DryRun = dryRun
+	report.Rows = []models.WaitlistImportRow{}
+
+	existing, err := existingWaitlistEmails(c)
+	if err != nil {
+		slog.Error("import waitlist: failed to load existing emails", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import waitlist"})
+		return
+	}
+
+	var toCreate []importedWaitlistEntry
+	var toCreateRows []int
+	rowNumber := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		rowNumber++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				report.Rows = append(report.Rows, models.WaitlistImportRow{Row: rowNumber, Result: "error", Messages: []string{parseErr.Err.Error()}})
+				report.Failed++
+				continue
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read CSV file"})
+			return
+		}
+
+		if isBlankRecord(record) {
+			continue
+		}
+		report.TotalRows++
+		if report.TotalRows > waitlistImportMaxRows {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("CSV files are limited to %d rows", waitlistImportMaxRows)})
+			return
+		}
+
+		entry, messages := parseWaitlistImportRecord(record, columnIndex)
+		row := models.WaitlistImportRow{Row: rowNumber, Email: entry.Email, Messages: messages}
+
+		switch {
+		case len(messages) > 0:
+			row.Result = "error"
+			report.Failed++
+		case existing[utils.NormalizeEmail(entry.Email)]:
+			row.Result = "duplicate"
+			row.Messages = []string{"Email is already on the waitlist or earlier in this file"}
+			report.Skipped++
+		default:
+			row.Result = "create"
+			row.Messages = []string{}
+			existing[utils.NormalizeEmail(entry.Email)] = true
+			toCreate = append(toCreate, entry)
+			toCreateRows = append(toCreateRows, len(report.Rows))
+		}
+
+		report.Rows = append(report.Rows, row)
+	}
+
+	if dryRun || len(toCreate) == 0 {
+		if dryRun {
+			report.Created = len(toCreate)
+		}
+		slog.Info("import waitlist: validated without writing", "dry_run", dryRun, "rows", report.TotalRows, "valid", len(toCreate), "failed", report.Failed)
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	ids, err := insertImportedWaitlist(c, toCreate)
+	if err != nil {
+		slog.Error("import waitlist: failed to insert entries", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import waitlist"})
+		return
+	}
+
+	for i, id := range ids {
+		report.Rows[toCreateRows[i]].ID = &id
+		recordAudit(c, auditActionCreate, "waitlist", id, nil, snapshotEntity(c, "waitlist", id))
+	}
+	report.Created = len(ids)
+
+	slog.Info("import waitlist: entries imported", "created", report.Created, "skipped", report.Skipped, "failed", report.Failed)
+	c.JSON(http.StatusOK, report)
+}
+
+func mapWaitlistColumns(headers []string, overrides map[string]string) (map[string]int, models.WaitlistImportReport, error) {
+	report := models.WaitlistImportReport{Mapping: map[string]string{}, Unmapped: []string{}}
+	columnIndex := make(map[string]int)
+
+	mappedColumns := make(map[int]bool)
+	for field, header := range overrides {
+		idx := slices.IndexFunc(headers, func(h string) bool {
+			return strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")), strings.TrimSpace(header))
+		})
+		if idx < 0 {
+			return nil, report, fmt.Errorf("mapped column %q not found in CSV header", header)
+		}
+		columnIndex[field] = idx
+		mappedColumns[idx] = true
+	}
+
+	for i, header := range headers {
+		normalized := normalizeCSVHeader(header)
+		field := normalized
+		if alias, ok := waitlistImportAliases[normalized]; ok {
+			field = alias
+		}
+
+		if mappedColumns[i] {
+			continue
+		}
+		if _, taken := columnIndex[field]; !taken && (field == "name" || slices.Contains(waitlistCSVColumns, field)) {
+			columnIndex[field] = i
+			continue
+		}
+		report.Unmapped = append(report.Unmapped, header)
+	}
+
+	_, hasFirst := columnIndex["first_name"]
+	_, hasName := columnIndex["name"]
+	if _, ok := columnIndex["email"]; !ok {
+		return nil, report, fmt.Errorf("CSV must have an email column, or map one with the mapping field")
+	}
+	if !hasFirst && !hasName {
+		return nil, report, fmt.Errorf("CSV must have a first_name or name column, or map one with the mapping field")
+	}
+
+	for field, idx := range columnIndex {
+		report.Mapping[field] = headers[idx]
+	}
+
+	return columnIndex, report, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+var importDateLayouts = []string{"2006-01-02", "01/02/2006", "1/2/2006", "1/2/06", time.RFC3339, "2006-01-02 15:04:05", "1/2/2006 15:04:05", "1/2/2006 3:04 PM"}
+
+func parseImportDate(value string) (*time.Time, error) {
+	for _, layout := range importDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("unrecognized date %q", value)
+}
+
+func parseWaitlistImportRecord(record []string, columnIndex map[string]int) (importedWaitlistEntry, []string) {
+	get := func(field string) string {
+		idx, ok := columnIndex[field]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(unspreadsheetSafe(record[idx]))
+	}
+
+	var entry importedWaitlistEntry
+	var messages []string
+
+	entry.FirstName, entry.LastName = get("first_name"), get("last_name")
+	if entry.FirstName == "" && entry.LastName == "" {
+		if full := get("name"); full != "" {
+			if i := strings.LastIndex(full, " "); i > 0 {
+				entry.FirstName, entry.LastName = strings.TrimSpace(full[:i]), strings.TrimSpace(full[i+1:])
+			} else {
+				entry.FirstName = full
+			}
+		}
+	}
+	if entry.FirstName == "" {
+		messages = append(messages, "First name is required")
+	}
+	if entry.LastName == "" {
+		messages = append(messages, "Last name is required")
+	}
+
+	entry.Email = get("email")
+	if _, err := mail.ParseAddress(entry.Email); err != nil {
+		messages = append(messages, "Invalid email address")
+	}
+
+	entry.Phone = get("phone")
+	entry.Preferences = get("preferences")
+
+	entry.Status = get("status")
+	if entry.Status == "" {
+		entry.Status = "New"
+	} else if i := slices.IndexFunc(models.WaitlistStatuses, func(s string) bool { return strings.EqualFold(s, entry.Status) }); i >= 0 {
+		entry.Status = models.WaitlistStatuses[i]
+	} else {
+		messages = append(messages, "Unknown status "+entry.Status)
+	}
+
+	switch gender := strings.ToLower(get("preferred_gender")); gender {
+	case "", "any", "either", "no preference":
+	case "male", "m", "boy":
+		g := "Male"
+		entry.PreferredGender = &g
+	case "female", "f", "girl":
+		g := "Female"
+		entry.PreferredGender = &g
+	default:
+		messages = append(messages, "Unknown gender "+get("preferred_gender"))
+	}
+
+	entry.PreferredColors = []string{}
+	for _, color := range strings.FieldsFunc(get("preferred_colors"), func(r rune) bool { return r == ';' || r == ',' || r == '/' }) {
+		if color = strings.TrimSpace(color); color != "" {
+			entry.PreferredColors = append(entry.PreferredColors, color)
+		}
+	}
+
+	for _, field := range []struct {
+		key  string
+		dest **time.Time
+	}{
+		{"desired_from", &entry.DesiredFrom},
+		{"desired_by", &entry.DesiredBy},
+		{"deposit_date", &entry.DepositDate},
+		{"created_at", &entry.createdAt},
+	} {
+		if value := get(field.key); value != "" {
+			date, err := parseImportDate(value)
+			if err != nil {
+				messages = append(messages, field.key+": "+err.Error())
+				continue
+			}
+			*field.dest = date
+		}
+	}
+
+	if amount := strings.NewReplacer("$", "", ",", "").Replace(get("deposit_amount")); amount != "" {
+		value, err := strconv.ParseFloat(amount, 64)
+		if err != nil || value < 0 {
+			messages = append(messages, "Invalid deposit amount "+get("deposit_amount"))
+		} else {
+			entry.DepositAmount = &value
+		}
+	}
+	if method := get("deposit_method"); method != "" {
+		entry.DepositMethod = &method
+	}
+
+	entry.DepositStatus = "None"
+	if status := get("deposit_status"); status != "" {
+		depositStatuses := []string{"None", "Requested", "Paid", "Refunded"}
+		if i := slices.IndexFunc(depositStatuses, func(s string) bool { return strings.EqualFold(s, status) }); i >= 0 {
+			entry.DepositStatus = depositStatuses[i]
+		} else {
+			messages = append(messages, "Unknown deposit status "+status)
+		}
+	} else if entry.DepositAmount != nil {
+		entry.DepositStatus = "Paid"
+	}
+
+	return entry, messages
+}
+
+func existingWaitlistEmails(c *gin.Context) (map[string]bool, error) {
+	rows, err := database.Pool.Query(c, "SELECT email_normalized FROM waitlist")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := make(map[string]bool)
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails[email] = true
+	}
+	return emails, rows.Err()
+}
+
+// insertImportedWaitlist writes all entries in one transaction, appending
+// them to the end of the queue in file order.
+func insertImportedWaitlist(c *gin.Context, entries []importedWaitlistEntry) ([]int, error) {
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	if _, err := tx.Exec(c, "LOCK TABLE waitlist IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return nil, err
+	}
+
+	var nextPosition int
+	if err := tx.QueryRow(c, "SELECT COALESCE(MAX(queue_position), 0) + 1 FROM waitlist").Scan(&nextPosition); err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO waitlist (
+			first_name, last_name, email, phone, preferences, status,
+			preferred_gender, preferred_colors, desired_from, desired_by,
+			deposit_status, deposit_amount, deposit_date, deposit_method,
+			queue_position, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, COALESCE($16, NOW()))
+		RETURNING id`
+
+	ids := make([]int, 0, len(entries))
+	for i, e := range entries {
+		var id int
+		err := tx.QueryRow(c, query,
+			e.FirstName, e.LastName, e.Email, e.Phone, e.Preferences, e.Status,
+			e.PreferredGender, e.PreferredColors, e.DesiredFrom, e.DesiredBy,
+			e.DepositStatus, e.DepositAmount, e.DepositDate, e.DepositMethod,
+			nextPosition+i, e.createdAt,
+		).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("row for %s: %w", e.Email, err)
+		}
+
+		if err := recordWaitlistStatusChange(c, tx, id, nil, e.Status, "Imported from CSV"); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+// matchTarget is what a waitlist entry is scored against: a single puppy, or
+// every available puppy in a litter. A planned litter may have no puppies
+// recorded yet, in which case gender and color are unknown.
+type matchTarget struct {
+	genders       map[string]bool
+	colors        map[string]bool
+	availableDate *time.Time
+}
+
+const (
+	matchWeightGender    = 30
+	matchWeightColor     = 30
+	matchWeightTimeframe = 25
+	matchWeightDeposit   = 15
+)
+
+func GetWaitlistMatches(c *gin.Context) {
+	litterID := c.Query("litter_id")
+	puppyID := c.Query("puppy_id")
+	if (litterID == "") == (puppyID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either litter_id or puppy_id"})
+		return
+	}
+
+	target, err := loadMatchTarget(c, litterID, puppyID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("get waitlist matches: target not found", "litter_id", litterID, "puppy_id", puppyID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Litter or puppy not found"})
+			return
+		}
+
+		slog.Error("get waitlist matches: failed to load target", "litter_id", litterID, "puppy_id", puppyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to match waitlist"})
+		return
+	}
+
+	query := `
+		SELECT ` + waitlistColumns + `
+		FROM waitlist
+		WHERE status NOT IN ('Complete', 'Withdrawn')
+		ORDER BY ` + waitlistQueueOrder
+
+	rows, err := database.Pool.Query(c, query)
+	if err != nil {
+		slog.Error("get waitlist matches: database error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to match waitlist"})
+		return
+	}
+	defer rows.Close()
+
+	matches := []models.WaitlistMatch{}
+	position := 0
+	for rows.Next() {
+		w, err := scanWaitlist(rows)
+		if err != nil {
+			slog.Debug("get waitlist matches: failed to scan row", "error", err)
+			continue
+		}
+		position++
+		matches = append(matches, scoreWaitlistMatch(w, position, target))
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Eligible != matches[j].Eligible {
+			return matches[i].Eligible
+		}
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].QueuePosition < matches[j].QueuePosition
+	})
+
+	c.JSON(http.StatusOK, matches)
+}
+
+func loadMatchTarget(c *gin.Context, litterID, puppyID string) (matchTarget, error) {
+	target := matchTarget{genders: map[string]bool{}, colors: map[string]bool{}}
+
+	if puppyID != "" {
+		var gender, color string
+		err := database.Pool.QueryRow(c, `
+			SELECT p.gender, p.color, l.available_date
+			FROM puppies p
+			LEFT JOIN litters l ON l.id = p.litter_id
+			WHERE p.id = $1`, puppyID,
+		).Scan(&gender, &color, &target.availableDate)
+		if err != nil {
+			return target, err
+		}
+		target.genders[gender] = true
+		target.colors[strings.ToLower(strings.TrimSpace(color))] = true
+		return target, nil
+	}
+
+	if err := database.Pool.QueryRow(c, "SELECT available_date FROM litters WHERE id = $1", litterID).Scan(&target.availableDate); err != nil {
+		return target, err
+	}
+
+	rows, err := database.Pool.Query(c, "SELECT gender, color FROM puppies WHERE litter_id = $1 AND status = 'Available'", litterID)
+	if err != nil {
+		return target, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var gender, color string
+		if err := rows.Scan(&gender, &color); err != nil {
+			return target, err
+		}
+		target.genders[gender] = true
+		target.colors[strings.ToLower(strings.TrimSpace(color))] = true
+	}
+
+	return target, rows.Err()
+}
+
+func scoreWaitlistMatch(w models.Waitlist, position int, target matchTarget) models.WaitlistMatch {
+	match := models.WaitlistMatch{Entry: w, QueuePosition: position, Eligible: true, Reasons: []string{}}
+	half := func(weight int) int { return weight / 2 }
+
+	switch {
+	case w.PreferredGender == nil:
+		match.Score += matchWeightGender
+		match.Reasons = append(match.Reasons, "No gender preference")
+	case len(target.genders) == 0:
+		match.Score += half(matchWeightGender)
+		match.Reasons = append(match.Reasons, "Wants "+*w.PreferredGender+"; genders not known yet")
+	case target.genders[*w.PreferredGender]:
+		match.Score += matchWeightGender
+		match.Reasons = append(match.Reasons, "Wants "+*w.PreferredGender)
+	default:
+		match.Eligible = false
+		match.Reasons = append(match.Reasons, "Wants "+*w.PreferredGender+"; none available")
+	}
+
+	switch {
+	case len(w.PreferredColors) == 0:
+		match.Score += matchWeightColor
+		match.Reasons = append(match.Reasons, "No color preference")
+	case len(target.colors) == 0:
+		match.Score += half(matchWeightColor)
+		match.Reasons = append(match.Reasons, "Wants "+strings.Join(w.PreferredColors, " or ")+"; colors not known yet")
+	default:
+		matched := ""
+		for _, color := range w.PreferredColors {
+			if target.colors[strings.ToLower(color)] {
+				matched = color
+				break
+			}
+		}
+		if matched != "" {
+			match.Score += matchWeightColor
+			match.Reasons = append(match.Reasons, "Wants "+matched)
+		} else {
+			match.Reasons = append(match.Reasons, "Wants "+strings.Join(w.PreferredColors, " or ")+"; no match")
+		}
+	}
+
+	// A puppy without a litter has no availability date to compare against.
+	var available time.Time
+	if target.availableDate != nil {
+		available = *target.availableDate
+	}
+	switch {
+	case w.DesiredFrom == nil && w.DesiredBy == nil:
+		match.Score += matchWeightTimeframe
+		match.Reasons = append(match.Reasons, "Flexible timeframe")
+	case target.availableDate == nil:
+		match.Score += half(matchWeightTimeframe)
+		match.Reasons = append(match.Reasons, "Availability date not known yet")
+	case w.DesiredFrom != nil && available.Before(*w.DesiredFrom):
+		days := int(w.DesiredFrom.Sub(available).Hours() / 24)
+		match.Score += max(0, matchWeightTimeframe-days/7)
+		match.Reasons = append(match.Reasons, fmt.Sprintf("Available %d days before desired start", days))
+	case w.DesiredBy != nil && available.After(*w.DesiredBy):
+		days := int(available.Sub(*w.DesiredBy).Hours() / 24)
+		match.Score += max(0, matchWeightTimeframe-days/7)
+		match.Reasons = append(match.Reasons, fmt.Sprintf("Available %d days after desired date", days))
+	default:
+		match.Score += matchWeightTimeframe
+		match.Reasons = append(match.Reasons, "Within desired timeframe")
+	}
+
+	switch w.DepositStatus {
+	case "Paid":
+		match.Score += matchWeightDeposit
+		match.Reasons = append(match.Reasons, "Deposit paid")
+	case "Requested":
+		match.Score += half(matchWeightDeposit)
+		match.Reasons = append(match.Reasons, "Deposit requested")
+	}
+
+	return match
+}
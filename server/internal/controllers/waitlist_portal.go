@@ -0,0 +1,491 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
+)
+
+const (
+	waitlistPortalTokenTTL = 7 * 24 * time.Hour
+	waitlistEmailChangeTTL = 24 * time.Hour
+)
+
+func issueWaitlistToken(ctx context.Context, waitlistID int) (string, error) {
+	token, tokenHash, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	// Expired links are only kept around long enough to be useless.
+	if _, err := database.Pool.Exec(ctx, "DELETE FROM waitlist_tokens WHERE waitlist_id = $1 AND expires_at < NOW()", waitlistID); err != nil {
+		slog.Warn("waitlist portal: failed to prune expired tokens", "waitlist_id", waitlistID, "error", err)
+	}
+
+	insertQuery := `
+		INSERT INTO waitlist_tokens (waitlist_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)`
+
+	if _, err := database.Pool.Exec(ctx, insertQuery, waitlistID, tokenHash, time.Now().Add(waitlistPortalTokenTTL)); err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+
+	return token, nil
+}
+
+func sendWaitlistPortalLink(entry *models.Waitlist, token string) {
+	subject, htmlBody, err := utils.RenderEmailTemplate(context.Background(), utils.EmailTemplateWaitlistPortal, map[string]any{
+		"FirstName": entry.FirstName,
+		"PortalURL": buildAppLink("/waitlist/portal", token),
+		"ExpiresIn": "7 days",
+	})
+	if err != nil {
+		slog.Error("waitlist portal link: failed to render template", "error", err)
+		return
+	}
+
+	if _, err := utils.QueueSensitiveEmail(context.Background(), []string{entry.Email}, subject, htmlBody); err != nil {
+		slog.Error("waitlist portal link: failed to queue email", "waitlist_id", entry.ID, "error", err)
+		return
+	}
+
+	slog.Info("waitlist portal link: email queued", "waitlist_id", entry.ID)
+}
+
+// RequestWaitlistPortalLink emails a magic link to the address on file. The
+// response is the same whether or not the address is on the waitlist.
+func RequestWaitlistPortalLink(c *gin.Context) {
+	email := strings.TrimSpace(c.PostForm("email"))
+	if _, err := mail.ParseAddress(email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+		return
+	}
+
+	response := gin.H{"message": "If that email is on our waitlist, a link to manage your entry is on its way"}
+
+	query := `
+		SELECT id, first_name, email
+		FROM waitlist
+		WHERE email_normalized = $1
+		ORDER BY ` + waitlistQueueOrder + `
+		LIMIT 1`
+
+	var entry models.Waitlist
+	err := database.Pool.QueryRow(c, query, utils.NormalizeEmail(email)).Scan(&entry.ID, &entry.FirstName, &entry.Email)
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			slog.Error("request waitlist link: database error", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send link"})
+			return
+		}
+
+		slog.Debug("request waitlist link: no matching entry", "remote_addr", c.ClientIP())
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, err := issueWaitlistToken(c, entry.ID)
+	if err != nil {
+		slog.Error("request waitlist link: failed to issue token", "waitlist_id", entry.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send link"})
+		return
+	}
+
+	sendWaitlistPortalLink(&entry, token)
+
+	slog.Info("request waitlist link: link issued", "waitlist_id", entry.ID, "remote_addr", c.ClientIP())
+	c.JSON(http.StatusOK, response)
+}
+
+func portalWaitlistID(c *gin.Context) (int, bool) {
+	idVal, exists := c.Get("waitlist_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing waitlist link token"})
+		return 0, false
+	}
+	return idVal.(int), true
+}
+
+func GetWaitlistPortal(c *gin.Context) {
+	waitlistID, ok := portalWaitlistID(c)
+	if !ok {
+		return
+	}
+
+	w, err := scanWaitlist(database.Pool.QueryRow(c, `SELECT `+waitlistColumns+` FROM waitlist WHERE id = $1`, waitlistID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+			return
+		}
+
+		slog.Error("get waitlist portal: database error", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist entry"})
+		return
+	}
+
+	entry := models.WaitlistPortalEntry{
+		FirstName:       w.FirstName,
+		LastName:        w.LastName,
+		Email:           w.Email,
+		Phone:           w.Phone,
+		Preferences:     w.Preferences,
+		PreferredGender: w.PreferredGender,
+		PreferredColors: w.PreferredColors,
+		DesiredFrom:     w.DesiredFrom,
+		DesiredBy:       w.DesiredBy,
+		DepositStatus:   w.DepositStatus,
+		Status:          w.Status,
+		CreatedAt:       w.CreatedAt,
+	}
+
+	// Position counts only families still waiting, so it matches what the
+	// applicant would expect "number N in line" to mean.
+	query := `
+		WITH active AS (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY ` + waitlistQueueOrder + `)::int AS position
+			FROM waitlist
+			WHERE status NOT IN ('Complete', 'Withdrawn')
+		)
+		SELECT (SELECT position FROM active WHERE id = $1), (SELECT count(*)::int FROM active)`
+
+	if err := database.Pool.QueryRow(c, query, waitlistID).Scan(&entry.QueuePosition, &entry.QueueLength); err != nil {
+		slog.Error("get waitlist portal: failed to compute queue position", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist entry"})
+		return
+	}
+
+	pendingQuery := `
+		SELECT new_email FROM waitlist_email_changes
+		WHERE waitlist_id = $1 AND confirmed_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	var pending string
+	if err := database.Pool.QueryRow(c, pendingQuery, waitlistID).Scan(&pending); err == nil {
+		entry.PendingEmail = &pending
+	} else if err != pgx.ErrNoRows {
+		slog.Warn("get waitlist portal: failed to fetch pending email change", "waitlist_id", waitlistID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func UpdateWaitlistPortal(c *gin.Context) {
+	waitlistID, ok := portalWaitlistID(c)
+	if !ok {
+		return
+	}
+
+	prefs, err := parseWaitlistPreferences(c)
+	if err != nil {
+		slog.Debug("update waitlist portal: invalid preferences", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sets := "updated_at=NOW()"
+	args := []interface{}{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		sets += fmt.Sprintf(", %s=$%d", column, len(args))
+	}
+
+	for _, field := range []struct{ key, column string }{
+		{"firstname", "first_name"},
+		{"lastname", "last_name"},
+		{"phone", "phone"},
+		{"preferences", "preferences"},
+	} {
+		if value, ok := c.GetPostForm(field.key); ok {
+			value = strings.TrimSpace(value)
+			if value == "" && (field.key == "firstname" || field.key == "lastname") {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "First and last name are required"})
+				return
+			}
+			set(field.column, value)
+		}
+	}
+
+	// A new email only takes effect once it is confirmed from that address,
+	// so a forwarded or leaked link can't quietly move the entry elsewhere.
+	newEmail := ""
+	if email, ok := c.GetPostForm("email"); ok {
+		email = strings.TrimSpace(email)
+		if _, err := mail.ParseAddress(email); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+			return
+		}
+		newEmail = email
+	}
+
+	if _, ok := c.GetPostForm("preferred_gender"); ok {
+		set("preferred_gender", prefs.gender)
+	}
+	if _, ok := c.GetPostFormArray("preferred_colors"); ok {
+		set("preferred_colors", prefs.colors)
+	}
+	if _, ok := c.GetPostForm("desired_from"); ok {
+		set("desired_from", prefs.desiredFrom)
+	}
+	if _, ok := c.GetPostForm("desired_by"); ok {
+		set("desired_by", prefs.desiredBy)
+	}
+
+	before := snapshotEntity(c, "waitlist", waitlistID)
+
+	args = append(args, waitlistID)
+	query := fmt.Sprintf(`
+		UPDATE waitlist SET %s
+		WHERE id=$%d AND status NOT IN ('Complete', 'Withdrawn')`, sets, len(args))
+
+	result, err := database.Pool.Exec(c, query, args...)
+	if err != nil {
+		slog.Error("update waitlist portal: database error", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update waitlist entry"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("update waitlist portal: entry is closed", "waitlist_id", waitlistID)
+		c.JSON(http.StatusConflict, gin.H{"error": "This waitlist entry can no longer be changed"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "waitlist", waitlistID, before, snapshotEntity(c, "waitlist", waitlistID))
+
+	response := gin.H{"message": "Waitlist entry updated"}
+	if newEmail != "" {
+		requested, err := requestWaitlistEmailChange(c, waitlistID, newEmail)
+		if err != nil {
+			slog.Error("update waitlist portal: failed to request email change", "waitlist_id", waitlistID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update email address"})
+			return
+		}
+		if requested {
+			response = gin.H{
+				"message":      "Waitlist entry updated. Check " + newEmail + " for a link to confirm your new email",
+				"pendingEmail": newEmail,
+			}
+		}
+	}
+
+	slog.Info("update waitlist portal: entry updated by applicant", "waitlist_id", waitlistID)
+	c.JSON(http.StatusOK, response)
+}
+
+// requestWaitlistEmailChange records newEmail as pending, emails a
+// confirmation link to it and lets the current address know. It returns
+// false when newEmail is already the address on file.
+func requestWaitlistEmailChange(c *gin.Context, waitlistID int, newEmail string) (bool, error) {
+	var entry models.Waitlist
+	err := database.Pool.QueryRow(c, "SELECT id, first_name, email FROM waitlist WHERE id = $1", waitlistID).
+		Scan(&entry.ID, &entry.FirstName, &entry.Email)
+	if err != nil {
+		return false, err
+	}
+	if utils.NormalizeEmail(entry.Email) == utils.NormalizeEmail(newEmail) {
+		return false, nil
+	}
+
+	token, tokenHash, err := utils.GenerateSecureToken()
+	if err != nil {
+		return false, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(c)
+
+	// Only the latest request can be confirmed.
+	if _, err := tx.Exec(c, "DELETE FROM waitlist_email_changes WHERE waitlist_id = $1 AND confirmed_at IS NULL", waitlistID); err != nil {
+		return false, fmt.Errorf("failed to clear previous requests: %w", err)
+	}
+
+	insertQuery := `
+		INSERT INTO waitlist_email_changes (waitlist_id, new_email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)`
+
+	if _, err := tx.Exec(c, insertQuery, waitlistID, newEmail, tokenHash, time.Now().Add(waitlistEmailChangeTTL)); err != nil {
+		return false, fmt.Errorf("failed to store request: %w", err)
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return false, err
+	}
+
+	sendWaitlistEmailChange(&entry, newEmail, token)
+
+	slog.Info("waitlist email change: confirmation requested", "waitlist_id", waitlistID, "remote_addr", c.ClientIP())
+	return true, nil
+}
+
+func sendWaitlistEmailChange(entry *models.Waitlist, newEmail, token string) {
+	subject, htmlBody, err := utils.RenderEmailTemplate(context.Background(), utils.EmailTemplateWaitlistEmailChange, map[string]any{
+		"FirstName":  entry.FirstName,
+		"ConfirmURL": buildAppLink("/waitlist/email/confirm", token),
+		"ExpiresIn":  "24 hours",
+	})
+	if err != nil {
+		slog.Error("waitlist email change: failed to render confirmation", "error", err)
+	} else if _, err := utils.QueueSensitiveEmail(context.Background(), []string{newEmail}, subject, htmlBody); err != nil {
+		slog.Error("waitlist email change: failed to queue confirmation", "waitlist_id", entry.ID, "error", err)
+	}
+
+	subject, htmlBody, err = utils.RenderEmailTemplate(context.Background(), utils.EmailTemplateWaitlistEmailNotice, map[string]any{
+		"FirstName": entry.FirstName,
+		"NewEmail":  newEmail,
+	})
+	if err != nil {
+		slog.Error("waitlist email change: failed to render notice", "error", err)
+		return
+	}
+	if _, err := utils.QueueEmail(context.Background(), []string{entry.Email}, subject, htmlBody); err != nil {
+		slog.Error("waitlist email change: failed to queue notice", "waitlist_id", entry.ID, "error", err)
+	}
+}
+
+// ConfirmWaitlistEmailChange applies a pending email change once the link
+// sent to the new address is followed.
+func ConfirmWaitlistEmailChange(c *gin.Context) {
+	var req models.ConfirmWaitlistEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("confirm waitlist email: invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("confirm waitlist email: failed to begin transaction", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm email"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	consumeQuery := `
+		UPDATE waitlist_email_changes SET confirmed_at = NOW()
+		WHERE token_hash = $1 AND confirmed_at IS NULL AND expires_at > NOW()
+		RETURNING waitlist_id, new_email`
+
+	var waitlistID int
+	var newEmail string
+	if err := tx.QueryRow(c, consumeQuery, utils.HashSecureToken(req.Token)).Scan(&waitlistID, &newEmail); err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("confirm waitlist email: invalid or expired token", "remote_addr", c.ClientIP())
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This link is invalid or has expired"})
+			return
+		}
+
+		slog.Error("confirm waitlist email: failed to consume token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm email"})
+		return
+	}
+
+	before := snapshotEntity(c, "waitlist", waitlistID)
+
+	result, err := tx.Exec(c, `
+		UPDATE waitlist SET email=$1, updated_at=NOW()
+		WHERE id=$2 AND status NOT IN ('Complete', 'Withdrawn')`, newEmail, waitlistID)
+	if err != nil {
+		slog.Error("confirm waitlist email: database error", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm email"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		slog.Debug("confirm waitlist email: entry is closed", "waitlist_id", waitlistID)
+		c.JSON(http.StatusConflict, gin.H{"error": "This waitlist entry can no longer be changed"})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("confirm waitlist email: failed to commit transaction", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm email"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "waitlist", waitlistID, before, snapshotEntity(c, "waitlist", waitlistID))
+
+	slog.Info("confirm waitlist email: email changed", "waitlist_id", waitlistID)
+	c.JSON(http.StatusOK, gin.H{"message": "Your email has been updated"})
+}
+
+func WithdrawWaitlistPortal(c *gin.Context) {
+	waitlistID, ok := portalWaitlistID(c)
+	if !ok {
+		return
+	}
+
+	reason := strings.TrimSpace(c.PostForm("reason"))
+	note := "Withdrawn by applicant"
+	if reason != "" {
+		note += ": " + reason
+	}
+
+	before := snapshotEntity(c, "waitlist", waitlistID)
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("withdraw waitlist: failed to begin transaction", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw from waitlist"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	var status string
+	if err := tx.QueryRow(c, "SELECT status FROM waitlist WHERE id=$1 FOR UPDATE", waitlistID).Scan(&status); err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+			return
+		}
+
+		slog.Error("withdraw waitlist: failed to fetch entry", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw from waitlist"})
+		return
+	}
+	if status == "Complete" || status == "Withdrawn" {
+		c.JSON(http.StatusConflict, gin.H{"error": "This waitlist entry is already " + status})
+		return
+	}
+
+	// A family holding a reservation needs to talk to us about their puppy
+	// and deposit rather than silently dropping off the list.
+	var activeReservations int
+	if err := tx.QueryRow(c, "SELECT count(*) FROM puppy_reservations WHERE waitlist_id=$1 AND status='Active'", waitlistID).Scan(&activeReservations); err != nil {
+		slog.Error("withdraw waitlist: failed to check reservations", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw from waitlist"})
+		return
+	}
+	if activeReservations > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have a puppy reserved, please contact us to withdraw"})
+		return
+	}
+
+	if err := setWaitlistStatusTx(c, tx, waitlistID, status, "Withdrawn", note); err != nil {
+		slog.Error("withdraw waitlist: failed to update status", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw from waitlist"})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("withdraw waitlist: failed to commit transaction", "waitlist_id", waitlistID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw from waitlist"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "waitlist", waitlistID, before, snapshotEntity(c, "waitlist", waitlistID))
+
+	slog.Info("withdraw waitlist: applicant withdrew", "waitlist_id", waitlistID)
+	c.JSON(http.StatusOK, gin.H{"message": "You have been removed from the waitlist"})
+}
@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+// recordWaitlistStatusChange appends to the entry's status history inside
+// the caller's transaction, attributing the change to the signed-in user.
+func recordWaitlistStatusChange(c *gin.Context, tx pgx.Tx, waitlistID int, from *string, to string, note string) error {
+	var changedBy *int
+	if userVal, ok := c.Get("user"); ok {
+		user := userVal.(models.User)
+		changedBy = &user.ID
+	}
+
+	var notePtr *string
+	if note != "" {
+		notePtr = &note
+	}
+
+	_, err := tx.Exec(c, `
+		INSERT INTO waitlist_status_history (waitlist_id, from_status, to_status, changed_by, note)
+		VALUES ($1, $2, $3, $4, $5)`,
+		waitlistID, from, to, changedBy, notePtr,
+	)
+	return err
+}
+
+func GetWaitlistHistory(c *gin.Context) {
+	id := c.Param("id")
+
+	var exists bool
+	if err := database.Pool.QueryRow(c, "SELECT EXISTS (SELECT 1 FROM waitlist WHERE id=$1)", id).Scan(&exists); err != nil {
+		slog.Error("get waitlist history: database error", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist history"})
+		return
+	}
+	if !exists {
+		slog.Debug("get waitlist history: not found", "waitlist_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+		return
+	}
+
+	query := `
+		SELECT h.id, h.waitlist_id, h.from_status, h.to_status, h.changed_by,
+			CASE WHEN u.id IS NULL THEN NULL ELSE u.first_name || ' ' || u.last_name END,
+			h.note, h.created_at
+		FROM waitlist_status_history h
+		LEFT JOIN users u ON u.id = h.changed_by
+		WHERE h.waitlist_id = $1
+		ORDER BY h.created_at ASC, h.id ASC`
+
+	rows, err := database.Pool.Query(c, query, id)
+	if err != nil {
+		slog.Error("get waitlist history: database error", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist history"})
+		return
+	}
+	defer rows.Close()
+
+	history := []models.WaitlistStatusChange{}
+	for rows.Next() {
+		var h models.WaitlistStatusChange
+		if err := rows.Scan(&h.ID, &h.WaitlistID, &h.FromStatus, &h.ToStatus, &h.ChangedBy, &h.ChangedByName, &h.Note, &h.CreatedAt); err != nil {
+			slog.Debug("get waitlist history: failed to scan row", "error", err)
+			continue
+		}
+		history = append(history, h)
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// RecordWaitlistDeposit stores the deposit details, marks the deposit paid
+// and moves entries that haven't progressed further to Deposited.
+func RecordWaitlistDeposit(c *gin.Context) {
+	id := c.Param("id")
+	waitlistID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid waitlist ID"})
+		return
+	}
+
+	var req models.WaitlistDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("record waitlist deposit: invalid request body", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	depositDate, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date must be in YYYY-MM-DD format"})
+		return
+	}
+
+	before := snapshotEntity(c, "waitlist", id)
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("record waitlist deposit: failed to begin transaction", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record deposit"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	var status string
+	if err := tx.QueryRow(c, "SELECT status FROM waitlist WHERE id=$1 FOR UPDATE", waitlistID).Scan(&status); err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("record waitlist deposit: not found", "waitlist_id", id)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+			return
+		}
+
+		slog.Error("record waitlist deposit: failed to fetch entry", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record deposit"})
+		return
+	}
+
+	newStatus := status
+	if status == "New" || status == "Contacted" {
+		newStatus = "Deposited"
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE waitlist
+		SET deposit_amount=$1, deposit_date=$2, deposit_method=$3, deposit_status='Paid', status=$4, updated_at=NOW()
+		WHERE id=$5`,
+		req.Amount, depositDate, req.Method, newStatus, waitlistID,
+	)
+	if err != nil {
+		slog.Error("record waitlist deposit: database error", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record deposit"})
+		return
+	}
+
+	if newStatus != status {
+		if err := recordWaitlistStatusChange(c, tx, waitlistID, &status, newStatus, req.Note); err != nil {
+			slog.Error("record waitlist deposit: failed to record status history", "waitlist_id", id, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record deposit"})
+			return
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("record waitlist deposit: failed to commit transaction", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record deposit"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "waitlist", id, before, snapshotEntity(c, "waitlist", id))
+
+	slog.Info("record waitlist deposit: deposit recorded", "waitlist_id", id, "amount", req.Amount, "status", newStatus)
+	c.JSON(http.StatusOK, gin.H{"message": "Deposit recorded", "status": newStatus})
+}
+
+// MoveWaitlistEntry moves an entry to the given 1-based position and
+// renumbers the whole queue so positions stay contiguous.
+func MoveWaitlistEntry(c *gin.Context) {
+	id := c.Param("id")
+	waitlistID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid waitlist ID"})
+		return
+	}
+
+	var req models.WaitlistPositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Debug("move waitlist entry: invalid request body", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	before := snapshotEntity(c, "waitlist", id)
+
+	tx, err := database.Pool.Begin(c)
+	if err != nil {
+		slog.Error("move waitlist entry: failed to begin transaction", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move waitlist entry"})
+		return
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `SELECT id FROM waitlist ORDER BY `+waitlistQueueOrder+` FOR UPDATE`)
+	if err != nil {
+		slog.Error("move waitlist entry: failed to load queue", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move waitlist entry"})
+		return
+	}
+
+	var order []int
+	for rows.Next() {
+		var entryID int
+		if err := rows.Scan(&entryID); err != nil {
+			rows.Close()
+			slog.Error("move waitlist entry: failed to scan queue", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move waitlist entry"})
+			return
+		}
+		order = append(order, entryID)
+	}
+	rows.Close()
+
+	current := slices.Index(order, waitlistID)
+	if current < 0 {
+		slog.Debug("move waitlist entry: not found", "waitlist_id", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+		return
+	}
+
+	position := min(req.Position, len(order))
+	order = slices.Delete(order, current, current+1)
+	order = slices.Insert(order, position-1, waitlistID)
+
+	_, err = tx.Exec(c, `
+		UPDATE waitlist w SET queue_position = q.position
+		FROM unnest($1::int[]) WITH ORDINALITY AS q(id, position)
+		WHERE w.id = q.id AND w.queue_position IS DISTINCT FROM q.position`, order)
+	if err != nil {
+		slog.Error("move waitlist entry: failed to renumber queue", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move waitlist entry"})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		slog.Error("move waitlist entry: failed to commit transaction", "waitlist_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move waitlist entry"})
+		return
+	}
+
+	recordAudit(c, auditActionUpdate, "waitlist", id, before, snapshotEntity(c, "waitlist", id))
+
+	slog.Info("move waitlist entry: entry moved", "waitlist_id", id, "from_position", current+1, "to_position", position)
+	c.JSON(http.StatusOK, gin.H{"message": "Waitlist entry moved", "position": position})
+}
@@ -1,7 +1,7 @@
 package middleware
 
 import (
-	"fmt"
+	"crypto/subtle"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -10,67 +10,118 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5"
-	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/config"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
 )
 
+// RequireAuth admits interactive user sessions only. Routes that automations
+// may call are guarded with RequireScope instead.
 func RequireAuth(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		slog.Debug("auth: missing Authorization header", "route_path", c.FullPath())
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+	authenticate(c, "")
+}
+
+// RequireScope admits user sessions as well as API keys granted scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticate(c, scope)
+	}
+}
+
+// authFailure is why a request could not be authenticated, and the
+// response RequireScope and friends answer with.
+type authFailure struct {
+	status  int
+	message string
+}
+
+func authenticate(c *gin.Context, scope string) {
+	if failure := resolveAuth(c, scope); failure != nil {
+		c.AbortWithStatusJSON(failure.status, gin.H{"error": failure.message})
 		return
 	}
+	c.Next()
+}
+
+// OptionalScope lets anonymous visitors through while still recognising a
+// session or an API key granted scope, so public routes can show signed-in
+// callers more. Missing or bad credentials get the public view rather than
+// an error, since anyone may load these routes.
+func OptionalScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if failure := resolveAuth(c, scope); failure != nil {
+			slog.Debug("auth: serving public view", "route_path", c.FullPath(), "reason", failure.message)
+		}
+		c.Next()
+	}
+}
 
+// resolveAuth checks the session or API key on the request and stores the
+// caller in the context, returning nil when the request may proceed.
+func resolveAuth(c *gin.Context, scope string) *authFailure {
 	tokenString := ""
-	if len(authHeader) > 7 && strings.ToUpper(authHeader[0:6]) == "BEARER" {
-		tokenString = authHeader[7:]
+	fromCookie := false
+
+	authHeader := c.GetHeader("Authorization")
+	if authHeader != "" {
+		if len(authHeader) > 7 && strings.ToUpper(authHeader[0:6]) == "BEARER" {
+			tokenString = authHeader[7:]
+		} else {
+			slog.Debug("auth: malformed Authorization header", "route_path", c.FullPath())
+			return &authFailure{http.StatusUnauthorized, "Invalid token format. Format: Bearer <token>"}
+		}
+	} else if cookie, err := c.Cookie(utils.SessionCookieName); err == nil && cookie != "" {
+		tokenString = cookie
+		fromCookie = true
 	} else {
-		slog.Debug("auth: malformed Authorization header", "route_path", c.FullPath())
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format. Format: Bearer <token>"})
-		return
+		slog.Debug("auth: missing Authorization header and session cookie", "route_path", c.FullPath())
+		return &authFailure{http.StatusUnauthorized, "Authorization header is required"}
 	}
 
-	cfg := config.Load()
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	if !fromCookie && utils.IsAPIKey(tokenString) {
+		if scope == "" {
+			slog.Debug("auth: api key rejected on session-only route", "route_path", c.FullPath())
+			return &authFailure{http.StatusForbidden, "API keys cannot access this route"}
 		}
-		return []byte(cfg.JWTSecret), nil
-	})
+
+		return authenticateAPIKey(c, tokenString, scope, false)
+	}
+
+	// Cookies are sent by the browser automatically, so cookie-authenticated
+	// requests that change state must prove they can read the CSRF cookie.
+	if fromCookie && !isSafeMethod(c.Request.Method) && !validCSRFToken(c) {
+		slog.Warn("auth: csrf token missing or mismatched", "route_path", c.FullPath(), "method", c.Request.Method)
+		return &authFailure{http.StatusForbidden, "Invalid CSRF token"}
+	}
+
+	token, err := utils.ParseToken(tokenString)
 
 	if err != nil || !token.Valid {
 		slog.Debug("auth: invalid or expired token", "route_path", c.FullPath(), "error", err)
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-		return
+		return &authFailure{http.StatusUnauthorized, "Invalid or expired token"}
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
 		slog.Warn("auth: failed to extract token claims", "route_path", c.FullPath())
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-		return
+		return &authFailure{http.StatusUnauthorized, "Invalid token claims"}
 	}
 
 	expValue, ok := claims["exp"].(float64)
 	if !ok {
 		slog.Warn("auth: token missing or invalid exp claim", "route_path", c.FullPath())
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token expiration"})
-		return
+		return &authFailure{http.StatusUnauthorized, "Invalid token expiration"}
 	}
 
 	if float64(time.Now().Unix()) > expValue {
 		slog.Debug("auth: token expired", "route_path", c.FullPath())
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
-		return
+		return &authFailure{http.StatusUnauthorized, "Token expired"}
 	}
 
 	sessionIDFloat, ok := claims["sid"].(float64)
 	if !ok {
 		slog.Warn("auth: token missing sid claim", "route_path", c.FullPath())
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token session"})
-		return
+		return &authFailure{http.StatusUnauthorized, "Invalid token session"}
 	}
 	sessionID := int(sessionIDFloat)
 
@@ -82,13 +133,11 @@ func RequireAuth(c *gin.Context) {
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			slog.Debug("auth: session not found or expired", "session_id", sessionID, "route_path", c.FullPath())
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session expired or invalid"})
-			return
+			return &authFailure{http.StatusUnauthorized, "Session expired or invalid"}
 		}
 
 		slog.Error("auth: failed to validate session", "session_id", sessionID, "route_path", c.FullPath(), "error", err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate session"})
-		return
+		return &authFailure{http.StatusInternalServerError, "Failed to validate session"}
 	}
 
 	slog.Debug("auth: request authorized", "user_id", user.ID, "session_id", sessionID, "route_path", c.FullPath())
@@ -96,5 +145,27 @@ func RequireAuth(c *gin.Context) {
 	c.Set("user", user)
 	c.Set("session_id", sessionID)
 
-	c.Next()
+	return nil
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func validCSRFToken(c *gin.Context) bool {
+	cookieToken, err := c.Cookie(utils.CSRFCookieName)
+	if err != nil || cookieToken == "" {
+		return false
+	}
+
+	headerToken := c.GetHeader(utils.CSRFHeaderName)
+	if headerToken == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookieToken), []byte(headerToken)) == 1
 }
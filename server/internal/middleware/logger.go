@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedQueryParams are credentials that may arrive in a URL, such as
+// calendar feed tokens, which can't be sent any other way.
+var redactedQueryParams = []string{"token"}
+
+// RequestLogFormatter is gin's default access log line with credentials in
+// the query string masked out.
+func RequestLogFormatter(param gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor = param.StatusCodeColor()
+		methodColor = param.MethodColor()
+		resetColor = param.ResetColor()
+	}
+
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		redactPath(param.Path),
+		param.ErrorMessage,
+	)
+}
+
+func redactPath(path string) string {
+	u, err := url.Parse(path)
+	if err != nil {
+		// Drop a query we can't parse rather than risk logging a credential.
+		before, _, _ := strings.Cut(path, "?")
+		return before
+	}
+	if u.RawQuery == "" {
+		return path
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return u.Path
+	}
+	redacted := false
+	for _, key := range redactedQueryParams {
+		if query.Has(key) {
+			query.Set(key, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String()
+}
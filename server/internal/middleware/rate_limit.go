@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	hits      map[string]*rateWindow
+	lastSweep time.Time
+}
+
+// RateLimit allows each client IP at most limit requests per window. Counts
+// are kept in memory, so they reset when the server restarts.
+func RateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	rl := &rateLimiter{
+		limit:     limit,
+		window:    window,
+		hits:      make(map[string]*rateWindow),
+		lastSweep: time.Now(),
+	}
+
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		allowed, retryAfter := rl.allow(ip, time.Now())
+		if !allowed {
+			slog.Warn("rate limit: request blocked", "ip", ip, "path", c.FullPath(), "retry_after", retryAfter.String())
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please try again later"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (rl *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) > rl.window {
+		for k, w := range rl.hits {
+			if now.Sub(w.start) >= rl.window {
+				delete(rl.hits, k)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	w, ok := rl.hits[key]
+	if !ok || now.Sub(w.start) >= rl.window {
+		rl.hits[key] = &rateWindow{start: now, count: 1}
+		return true, 0
+	}
+
+	if w.count >= rl.limit {
+		return false, rl.window - now.Sub(w.start)
+	}
+
+	w.count++
+	return true, 0
+}
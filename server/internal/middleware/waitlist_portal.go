@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/utils"
+)
+
+const WaitlistTokenHeader = "X-Waitlist-Token"
+
+// RequireWaitlistToken authenticates an applicant by the magic-link token
+// emailed to them and scopes the request to their own waitlist entry.
+func RequireWaitlistToken(c *gin.Context) {
+	token := c.GetHeader(WaitlistTokenHeader)
+	if token == "" {
+		slog.Debug("waitlist portal: missing token", "path", c.FullPath())
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing waitlist link token"})
+		return
+	}
+
+	query := `
+		UPDATE waitlist_tokens SET last_used_at = NOW()
+		WHERE token_hash = $1 AND expires_at > NOW()
+		RETURNING waitlist_id`
+
+	var waitlistID int
+	if err := database.Pool.QueryRow(c, query, utils.HashSecureToken(token)).Scan(&waitlistID); err != nil {
+		if err == pgx.ErrNoRows {
+			slog.Debug("waitlist portal: invalid or expired token", "remote_addr", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "This link is invalid or has expired"})
+			return
+		}
+
+		slog.Error("waitlist portal: failed to validate token", "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate link"})
+		return
+	}
+
+	c.Set("waitlist_id", waitlistID)
+	c.Next()
+}
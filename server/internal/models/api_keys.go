@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+const (
+	ScopeStreamRead    = "stream:read"
+	ScopeStreamWrite   = "stream:write"
+	ScopeWaitlistRead  = "waitlist:read"
+	ScopeWaitlistWrite = "waitlist:write"
+	ScopeSettingsWrite = "settings:write"
+	ScopeBreederWrite  = "breeder:write"
+	ScopeDogsWrite     = "dogs:write"
+	ScopeLittersWrite  = "litters:write"
+	ScopePuppiesRead   = "puppies:read"
+	ScopePuppiesWrite  = "puppies:write"
+	ScopeFilesRead     = "files:read"
+	ScopeFilesWrite    = "files:write"
+	ScopeHealthRead    = "health:read"
+	ScopeHealthWrite   = "health:write"
+	ScopeBreedingRead  = "breeding:read"
+	ScopeBreedingWrite = "breeding:write"
+	ScopeCalendarRead  = "calendar:read"
+	ScopeSalesRead     = "sales:read"
+	ScopeSalesWrite    = "sales:write"
+)
+
+var APIKeyScopes = []string{
+	ScopeStreamRead,
+	ScopeStreamWrite,
+	ScopeWaitlistRead,
+	ScopeWaitlistWrite,
+	ScopeSettingsWrite,
+	ScopeBreederWrite,
+	ScopeDogsWrite,
+	ScopeLittersWrite,
+	ScopePuppiesRead,
+	ScopePuppiesWrite,
+	ScopeFilesRead,
+	ScopeFilesWrite,
+	ScopeHealthRead,
+	ScopeHealthWrite,
+	ScopeBreedingRead,
+	ScopeBreedingWrite,
+	ScopeCalendarRead,
+	ScopeSalesRead,
+	ScopeSalesWrite,
+}
+
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  *int       `json:"createdBy"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	LastUsedIP *string    `json:"lastUsedIp"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type CalendarFeedTokenRequest struct {
+	Name      string     `json:"name" binding:"max=100"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// CalendarFeedToken is an API key limited to calendar:read along with the
+// subscription URL that carries it. It is listed and revoked like any other
+// key.
+type CalendarFeedToken struct {
+	CreateAPIKeyResponse
+	FeedURL string `json:"feedUrl"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditEntry struct {
+	ID             int64           `json:"id"`
+	ActorUserID    *int            `json:"actorUserId"`
+	ActorName      *string         `json:"actorName"`
+	ActorSessionID *int            `json:"actorSessionId"`
+	ActorAPIKeyID  *int            `json:"actorApiKeyId"`
+	Action         string          `json:"action"`
+	EntityType     string          `json:"entityType"`
+	EntityID       *string         `json:"entityId"`
+	Before         json.RawMessage `json:"before"`
+	After          json.RawMessage `json:"after"`
+	Changes        json.RawMessage `json:"changes"`
+	IPAddress      *string         `json:"ipAddress"`
+	UserAgent      *string         `json:"userAgent"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
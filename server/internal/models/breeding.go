@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+var BreedingEventTypes = []string{"Heat", "Progesterone", "Breeding"}
+
+// BreedingEvent is one entry on a female's breeding calendar: the start (and
+// optionally end) of a heat cycle, a progesterone test in ng/mL, or a
+// breeding. Breedings get a DueDate 63 days out and can be turned into a
+// Planned litter, which is then linked through LitterID.
+type BreedingEvent struct {
+	ID             int        `json:"id"`
+	DogID          int        `json:"dogId"`
+	DogName        string     `json:"dogName"`
+	Type           string     `json:"type"`
+	EventDate      time.Time  `json:"eventDate"`
+	EndDate        *time.Time `json:"endDate"`
+	Progesterone   *float64   `json:"progesterone"`
+	SireID         *int       `json:"sireId"`
+	SireExternalID *int       `json:"sireExternalId"`
+	SireName       string     `json:"sireName"`
+	DueDate        *time.Time `json:"dueDate"`
+	LitterID       *int       `json:"litterId"`
+	Notes          string     `json:"notes"`
+	CreatedBy      *int       `json:"createdBy"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+type CreateBreedingEventRequest struct {
+	DogID          int      `json:"dogId" binding:"required"`
+	Type           string   `json:"type" binding:"required"`
+	EventDate      string   `json:"eventDate" binding:"required"`
+	EndDate        string   `json:"endDate"`
+	Progesterone   *float64 `json:"progesterone" binding:"omitempty,gte=0"`
+	SireID         *int     `json:"sireId"`
+	SireExternalID *int     `json:"sireExternalId"`
+	SireName       string   `json:"sireName"`
+	Notes          string   `json:"notes"`
+}
+
+type UpdateBreedingEventRequest struct {
+	EventDate      *string  `json:"eventDate"`
+	EndDate        *string  `json:"endDate"`
+	Progesterone   *float64 `json:"progesterone" binding:"omitempty,gte=0"`
+	SireID         *int     `json:"sireId"`
+	SireExternalID *int     `json:"sireExternalId"`
+	SireName       *string  `json:"sireName"`
+	ClearSire      bool     `json:"clearSire"`
+	Notes          *string  `json:"notes"`
+}
+
+type PlanLitterRequest struct {
+	Name          string `json:"name"`
+	AvailableDate string `json:"availableDate"`
+}
+
+// CalendarEvent is a single upcoming date on the breeding calendar. Estimated
+// is set for predictions, such as a next heat worked out from past cycles.
+type CalendarEvent struct {
+	Date            time.Time `json:"date"`
+	Type            string    `json:"type"`
+	Title           string    `json:"title"`
+	DogID           *int      `json:"dogId,omitempty"`
+	LitterID        *int      `json:"litterId,omitempty"`
+	BreedingEventID *int      `json:"breedingEventId,omitempty"`
+	Estimated       bool      `json:"estimated"`
+}
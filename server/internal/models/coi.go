@@ -0,0 +1,26 @@
+package models
+
+// COIPath is one independent path through a common ancestor. SirePath and
+// DamPath run from each parent up to, and including, the ancestor.
+type COIPath struct {
+	SirePath     []string `json:"sirePath"`
+	DamPath      []string `json:"damPath"`
+	Length       int      `json:"length"`
+	Contribution float64  `json:"contribution"`
+}
+
+type COICommonAncestor struct {
+	Ancestor     PedigreeNode `json:"ancestor"`
+	AncestorCOI  float64      `json:"ancestorCoi"`
+	Contribution float64      `json:"contribution"`
+	Paths        []COIPath    `json:"paths"`
+}
+
+type COIResult struct {
+	Sire            PedigreeNode        `json:"sire"`
+	Dam             PedigreeNode        `json:"dam"`
+	Generations     int                 `json:"generations"`
+	COI             float64             `json:"coi"`
+	COIPercent      float64             `json:"coiPercent"`
+	CommonAncestors []COICommonAncestor `json:"commonAncestors"`
+}
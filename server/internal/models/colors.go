@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// ColorLoci are the coat color loci a DNA panel may report, keyed by their
+// usual single-letter names: Agouti, Brown, Dilute, Extension, K (dominant
+// black), Merle and Spotting.
+var ColorLoci = []string{"A", "B", "D", "E", "K", "M", "S"}
+
+// CoatColor is an entry in the managed color list offered when adding a
+// puppy. Puppies keep their color name as text, so renaming or deleting a
+// color never rewrites past litters.
+type CoatColor struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	SortOrder int       `json:"sort_order"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CoatColorRequest struct {
+	Name      string `json:"name" binding:"required,max=50"`
+	SortOrder *int   `json:"sort_order"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ContractTemplate is a buyer contract with {{.Placeholder}} fields filled in
+// from the puppy's sale. HealthGuarantee is the text for {{.HealthGuarantee}}
+// so the terms can be edited without touching the rest of the contract.
+type ContractTemplate struct {
+	ID              int       `json:"id"`
+	Name            string    `json:"name"`
+	Body            string    `json:"body"`
+	HealthGuarantee string    `json:"healthGuarantee"`
+	IsDefault       bool      `json:"isDefault"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+type ContractTemplateRequest struct {
+	Name            string `json:"name" binding:"required,max=100"`
+	Body            string `json:"body" binding:"required"`
+	HealthGuarantee string `json:"healthGuarantee"`
+	IsDefault       bool   `json:"isDefault"`
+}
+
+// GenerateContractRequest picks a template (the default when TemplateID is
+// nil) and optionally emails the PDF to the buyer.
+type GenerateContractRequest struct {
+	TemplateID *int   `json:"templateId"`
+	Email      bool   `json:"email"`
+	Subject    string `json:"subject"`
+	Message    string `json:"message"`
+}
@@ -10,6 +10,12 @@ type Dog struct {
 	BirthDate      time.Time `json:"birthDate" form:"birthDate" db:"birth_date"`
 	ProfilePicture *Image 	 `json:"profilePicture,omitempty" db:"profile_picture"`
 	Gallery 			 []Image 	 `json:"gallery,omitempty" db:"gallery"`
+	RegistrationNumber string `json:"registrationNumber" form:"registrationNumber" db:"registration_number"`
+	SireID         *int      `json:"sireId" db:"sire_id"`
+	SireExternalID *int      `json:"sireExternalId" db:"sire_external_id"`
+	DamID          *int      `json:"damId" db:"dam_id"`
+	DamExternalID  *int      `json:"damExternalId" db:"dam_external_id"`
+	LitterID       *int      `json:"litterId" db:"litter_id"`
 	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt      time.Time `json:"updatedAt" db:"updated_at"`
 }
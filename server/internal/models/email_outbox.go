@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type OutboxEmail struct {
+	ID            int64             `json:"id"`
+	Recipients    []string          `json:"recipients"`
+	Cc            []string          `json:"cc"`
+	Bcc           []string          `json:"bcc"`
+	Subject       string            `json:"subject"`
+	TextBody      *string           `json:"textBody"`
+	HTMLBody      string            `json:"htmlBody"`
+	Sensitive     bool              `json:"sensitive"`
+	Attachments   []EmailAttachment `json:"attachments"`
+	Status        string            `json:"status"`
+	Attempts      int               `json:"attempts"`
+	MaxAttempts   int               `json:"maxAttempts"`
+	NextAttemptAt time.Time         `json:"nextAttemptAt"`
+	LastError     *string           `json:"lastError"`
+	SentAt        *time.Time        `json:"sentAt"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	UpdatedAt     time.Time         `json:"updatedAt"`
+}
+
+// EmailAttachment references a stored upload by URL; Data is loaded from
+// storage just before the message is sent.
+type EmailAttachment struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType,omitempty"`
+	Data        []byte `json:"-"`
+}
+
+type EmailFileRequest struct {
+	To      []string `json:"to" binding:"required,min=1,dive,email"`
+	Cc      []string `json:"cc" binding:"dive,email"`
+	Bcc     []string `json:"bcc" binding:"dive,email"`
+	Subject string   `json:"subject" binding:"required"`
+	Message string   `json:"message"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type EmailTemplate struct {
+	Key         string     `json:"key"`
+	Description string     `json:"description"`
+	Subject     string     `json:"subject"`
+	HTMLBody    string     `json:"htmlBody"`
+	Variables   []string   `json:"variables"`
+	IsCustom    bool       `json:"isCustom"`
+	UpdatedAt   *time.Time `json:"updatedAt"`
+}
+
+type UpdateEmailTemplateRequest struct {
+	Subject  string `json:"subject" binding:"required"`
+	HTMLBody string `json:"htmlBody" binding:"required"`
+}
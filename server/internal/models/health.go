@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+var HealthRecordTypes = []string{"Vaccination", "Deworming", "Vet Visit", "Genetic Test", "Other"}
+
+// HealthRecord is one vaccination, deworming, vet visit or genetic test for a
+// dog or a puppy. NextDueDate schedules the following dose or check-up and
+// drives reminder emails until a later record of the same kind is entered.
+//
+// Only public records are shown on the website, and only the fields in
+// PublicHealthRecord; the vet, notes and schedule always stay private.
+type HealthRecord struct {
+	ID                int        `json:"id"`
+	DogID             *int       `json:"dogId"`
+	PuppyID           *int       `json:"puppyId"`
+	AnimalName        string     `json:"animalName"`
+	Type              string     `json:"type"`
+	Title             string     `json:"title"`
+	RecordDate        time.Time  `json:"recordDate"`
+	NextDueDate       *time.Time `json:"nextDueDate"`
+	Veterinarian      string     `json:"veterinarian"`
+	Result            string     `json:"result"`
+	Lab               string     `json:"lab"`
+	CertificateFileID *int       `json:"certificateFileId"`
+	Certificate       *File      `json:"certificate,omitempty"`
+	Notes             string     `json:"notes"`
+	IsPublic          bool       `json:"isPublic"`
+	ReminderSentAt    *time.Time `json:"reminderSentAt"`
+	CreatedBy         *int       `json:"createdBy"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+}
+
+type PublicHealthRecord struct {
+	ID          int       `json:"id"`
+	Type        string    `json:"type"`
+	Title       string    `json:"title"`
+	RecordDate  time.Time `json:"recordDate"`
+	Result      string    `json:"result,omitempty"`
+	Lab         string    `json:"lab,omitempty"`
+	Certificate *File     `json:"certificate,omitempty"`
+}
+
+type CreateHealthRecordRequest struct {
+	DogID             *int   `json:"dogId"`
+	PuppyID           *int   `json:"puppyId"`
+	Type              string `json:"type" binding:"required"`
+	Title             string `json:"title" binding:"required"`
+	RecordDate        string `json:"recordDate" binding:"required"`
+	NextDueDate       string `json:"nextDueDate"`
+	Veterinarian      string `json:"veterinarian"`
+	Result            string `json:"result"`
+	Lab               string `json:"lab"`
+	CertificateFileID *int   `json:"certificateFileId"`
+	Notes             string `json:"notes"`
+	IsPublic          bool   `json:"isPublic"`
+}
+
+type UpdateHealthRecordRequest struct {
+	Type              *string `json:"type"`
+	Title             *string `json:"title"`
+	RecordDate        *string `json:"recordDate"`
+	NextDueDate       *string `json:"nextDueDate"`
+	Veterinarian      *string `json:"veterinarian"`
+	Result            *string `json:"result"`
+	Lab               *string `json:"lab"`
+	CertificateFileID *int    `json:"certificateFileId"`
+	ClearCertificate  bool    `json:"clearCertificate"`
+	Notes             *string `json:"notes"`
+	IsPublic          *bool   `json:"isPublic"`
+}
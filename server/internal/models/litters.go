@@ -9,6 +9,9 @@ type Litter struct {
 	FatherID       *int      `json:"father_id" db:"father_id"`
 	ExternalMother string    `json:"external_mother_name" form:"external_mother_name" db:"external_mother_name"`
 	ExternalFather string    `json:"external_father_name" form:"external_father_name" db:"external_father_name"`
+	MotherExternalID *int    `json:"mother_external_id" db:"mother_external_id"`
+	FatherExternalID *int    `json:"father_external_id" db:"father_external_id"`
+	COI            *float64  `json:"coi" db:"coi"`
 	MotherName     string    `json:"mother_name" db:"-"` 
 	FatherName     string    `json:"father_name" db:"-"` 
 	BirthDate      time.Time `json:"birth_date" form:"birth_date" db:"birth_date"`
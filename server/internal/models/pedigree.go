@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// ExternalAncestor is a dog outside the kennel that appears in a pedigree,
+// such as an outside stud or a grandparent. Its own parents may be other
+// external ancestors or kennel dogs.
+type ExternalAncestor struct {
+	ID                 int        `json:"id"`
+	Name               string     `json:"name"`
+	Gender             string     `json:"gender"`
+	RegistrationNumber string     `json:"registrationNumber"`
+	Registry           string     `json:"registry"`
+	BirthDate          *time.Time `json:"birthDate"`
+	Color              string     `json:"color"`
+	Breeder            string     `json:"breeder"`
+	SireID             *int       `json:"sireId"`
+	SireExternalID     *int       `json:"sireExternalId"`
+	DamID              *int       `json:"damId"`
+	DamExternalID      *int       `json:"damExternalId"`
+	Notes              string     `json:"notes"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
+}
+
+type ExternalAncestorRequest struct {
+	Name               string `json:"name" binding:"required"`
+	Gender             string `json:"gender" binding:"required,oneof=Male Female"`
+	RegistrationNumber string `json:"registrationNumber"`
+	Registry           string `json:"registry"`
+	BirthDate          string `json:"birthDate"`
+	Color              string `json:"color"`
+	Breeder            string `json:"breeder"`
+	SireID             *int   `json:"sireId"`
+	SireExternalID     *int   `json:"sireExternalId"`
+	DamID              *int   `json:"damId"`
+	DamExternalID      *int   `json:"damExternalId"`
+	Notes              string `json:"notes"`
+}
+
+// PedigreeNode is one animal in a pedigree tree. Type is "dog" for kennel
+// dogs, "external" for external ancestors, "puppy" for a puppy at the root,
+// and "unlinked" for a litter parent known only by its free-text name.
+type PedigreeNode struct {
+	Type               string        `json:"type"`
+	ID                 int           `json:"id,omitempty"`
+	Name               string        `json:"name"`
+	Gender             string        `json:"gender,omitempty"`
+	BirthDate          *time.Time    `json:"birthDate,omitempty"`
+	RegistrationNumber string        `json:"registrationNumber,omitempty"`
+	Registry           string        `json:"registry,omitempty"`
+	Color              string        `json:"color,omitempty"`
+	ProfilePicture     *Image        `json:"profilePicture,omitempty"`
+	Sire               *PedigreeNode `json:"sire"`
+	Dam                *PedigreeNode `json:"dam"`
+}
@@ -7,11 +7,21 @@ type Puppy struct {
 	LitterID       int       `json:"litter_id" form:"litter_id" binding:"required" db:"litter_id"`
 	Name           string    `json:"name" form:"name" binding:"required" db:"name"`
 	Color          string    `json:"color" form:"color" binding:"required" db:"color"`
+	ColorID        *int      `json:"color_id" db:"color_id"`
+	ColorGenetics  map[string]string `json:"color_genetics" db:"color_genetics"`
 	Gender         string    `json:"gender" form:"gender" binding:"required,oneof=Male Female" db:"gender"`
-	Status         string    `json:"status" form:"status" binding:"required,oneof=Available Reserved Sold" db:"status"`
+	Status         string    `json:"status" form:"status" binding:"required,oneof=Available Reserved Sold Retained" db:"status"`
 	Description    string    `json:"description" form:"description" db:"description"`
 	ProfilePicture *Image 	 `json:"profilePicture,omitempty" db:"profile_picture"`
 	Gallery 			 []Image 	 `json:"gallery,omitempty" db:"gallery"`
+	PromotedDogID  *int      `json:"promoted_dog_id" db:"promoted_dog_id"`
+	RegistrationBody   string   `json:"registration_body" db:"registration_body"`
+	RegistrationNumber string   `json:"registration_number" db:"registration_number"`
+	Microchip          string   `json:"microchip" db:"microchip"`
+	ExpectedWeight     *float64 `json:"expected_adult_weight" db:"expected_adult_weight"`
+	ExpectedWeightUnit *string  `json:"expected_adult_weight_unit" db:"expected_adult_weight_unit"`
+	ListPrice          *float64 `json:"list_price,omitempty" db:"list_price"`
+	DepositAmount      *float64 `json:"deposit_amount,omitempty" db:"deposit_amount"`
 	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt      time.Time `json:"updatedAt" db:"updated_at"`
 }
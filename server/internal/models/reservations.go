@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+type Reservation struct {
+	ID            int        `json:"id"`
+	PuppyID       int        `json:"puppyId"`
+	PuppyName     string     `json:"puppyName"`
+	LitterID      *int       `json:"litterId"`
+	WaitlistID    int        `json:"waitlistId"`
+	FamilyName    string     `json:"familyName"`
+	FamilyEmail   string     `json:"familyEmail"`
+	Status        string     `json:"status"`
+	ReservedDate  time.Time  `json:"reservedDate"`
+	DepositAmount *float64   `json:"depositAmount"`
+	PickupDate    *time.Time `json:"pickupDate"`
+	Notes         *string    `json:"notes"`
+	CreatedBy     *int       `json:"createdBy"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+type CreateReservationRequest struct {
+	PuppyID       int      `json:"puppyId" binding:"required"`
+	WaitlistID    int      `json:"waitlistId" binding:"required"`
+	ReservedDate  string   `json:"reservedDate"`
+	DepositAmount *float64 `json:"depositAmount" binding:"omitempty,gte=0"`
+	PickupDate    string   `json:"pickupDate"`
+	Notes         string   `json:"notes"`
+}
+
+type UpdateReservationRequest struct {
+	ReservedDate  *string  `json:"reservedDate"`
+	DepositAmount *float64 `json:"depositAmount" binding:"omitempty,gte=0"`
+	PickupDate    *string  `json:"pickupDate"`
+	Notes         *string  `json:"notes"`
+}
+
+type ReservationActionRequest struct {
+	Note string `json:"note"`
+}
@@ -0,0 +1,95 @@
+package models
+
+import "time"
+
+var PaymentKinds = []string{"Deposit", "Payment", "Refund"}
+
+// PuppySale records who bought a puppy and for how much. There is at most
+// one per puppy; payments are tracked separately so deposits and instalments
+// can be entered as they come in.
+type PuppySale struct {
+	ID             int       `json:"id"`
+	PuppyID        int       `json:"puppyId"`
+	ReservationID  *int      `json:"reservationId"`
+	SalePrice      float64   `json:"salePrice"`
+	SaleDate       time.Time `json:"saleDate"`
+	BuyerName      string    `json:"buyerName"`
+	BuyerEmail     string    `json:"buyerEmail"`
+	BuyerPhone     string    `json:"buyerPhone"`
+	BuyerAddress   string    `json:"buyerAddress"`
+	Notes          string    `json:"notes"`
+	ContractFileID *int      `json:"contractFileId"`
+	CreatedBy      *int      `json:"createdBy"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+type Payment struct {
+	ID        int       `json:"id"`
+	PuppyID   int       `json:"puppyId"`
+	Kind      string    `json:"kind"`
+	Amount    float64   `json:"amount"`
+	PaidOn    time.Time `json:"paidOn"`
+	Method    string    `json:"method"`
+	Reference string    `json:"reference"`
+	Notes     string    `json:"notes"`
+	CreatedBy *int      `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PuppySaleSummary is the admin view of a puppy's pricing, sale and
+// payments. Paid is net of refunds; Balance is what the buyer still owes on
+// the sale price, or on the list price before a sale is recorded.
+type PuppySaleSummary struct {
+	PuppyID       int        `json:"puppyId"`
+	PuppyName     string     `json:"puppyName"`
+	Status        string     `json:"status"`
+	ListPrice     *float64   `json:"listPrice"`
+	PriceVisible  bool       `json:"priceVisible"`
+	DepositAmount *float64   `json:"depositAmount"`
+	Sale          *PuppySale `json:"sale"`
+	Payments      []Payment  `json:"payments"`
+	Paid          float64    `json:"paid"`
+	Balance       *float64   `json:"balance"`
+}
+
+type PuppySaleRequest struct {
+	SalePrice    float64 `json:"salePrice" binding:"gte=0"`
+	SaleDate     string  `json:"saleDate"`
+	BuyerName    string  `json:"buyerName" binding:"max=200"`
+	BuyerEmail   string  `json:"buyerEmail" binding:"omitempty,email,max=150"`
+	BuyerPhone   string  `json:"buyerPhone" binding:"max=50"`
+	BuyerAddress string  `json:"buyerAddress"`
+	Notes        string  `json:"notes"`
+}
+
+type PaymentRequest struct {
+	Kind      string  `json:"kind" binding:"required"`
+	Amount    float64 `json:"amount" binding:"gt=0"`
+	PaidOn    string  `json:"paidOn"`
+	Method    string  `json:"method" binding:"max=50"`
+	Reference string  `json:"reference" binding:"max=100"`
+	Notes     string  `json:"notes"`
+}
+
+// LitterRevenue totals a litter's sales. Booked is the sum of sale prices,
+// Collected the payments received net of refunds, and Outstanding what sold
+// puppies' buyers still owe.
+type LitterRevenue struct {
+	LitterID    int     `json:"litterId"`
+	LitterName  string  `json:"litterName"`
+	Puppies     int     `json:"puppies"`
+	Sold        int     `json:"sold"`
+	Booked      float64 `json:"booked"`
+	Collected   float64 `json:"collected"`
+	Outstanding float64 `json:"outstanding"`
+}
+
+// YearRevenue groups sales by sale date and payments by the date received,
+// so Collected can include deposits for puppies sold the following year.
+type YearRevenue struct {
+	Year      int     `json:"year"`
+	Sold      int     `json:"sold"`
+	Booked    float64 `json:"booked"`
+	Collected float64 `json:"collected"`
+}
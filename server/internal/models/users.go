@@ -3,29 +3,45 @@ package models
 import "time"
 
 type User struct {
-	ID             int       `json:"id"`
-	FirstName      string    `json:"firstName" binding:"required"`
-	LastName       string    `json:"lastName" binding:"required"`
-	Email          string    `json:"email" binding:"required,email"`
-	Password       string    `json:"password,omitempty"`
-	PasswordHash   string    `json:"-"`
-	PhoneNumber    string    `json:"phoneNumber" binding:"required"`
-	CreatedAt      time.Time `json:"createdAt"`
-	UpdatedAt      time.Time `json:"updatedAt"`
+	ID              int        `json:"id"`
+	FirstName       string     `json:"firstName" binding:"required"`
+	LastName        string     `json:"lastName" binding:"required"`
+	Email           string     `json:"email" binding:"required,email"`
+	Password        string     `json:"password,omitempty"`
+	PasswordHash    string     `json:"-"`
+	PhoneNumber     string     `json:"phoneNumber" binding:"required"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password" binding:"required"`
+	UseCookie bool   `json:"useCookie"`
 }
 
 type LoginResponse struct {
-	Token     string `json:"token"`
+	Token     string `json:"token,omitempty"`
+	CSRFToken string `json:"csrfToken,omitempty"`
 	Email     string `json:"email"`
 	FirstName string `json:"firstName"`
 	LastName  string `json:"lastName"`
 }
 
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
 type Session struct {
 	ID        int       `json:"id"`
 	UserID    int       `json:"userId"`
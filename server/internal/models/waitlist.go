@@ -3,13 +3,110 @@ package models
 import "time"
 
 type Waitlist struct {
-	ID          int       `json:"id"`
-	FirstName   string    `json:"firstname" form:"firstname" binding:"required"`
-	LastName    string    `json:"lastname" form:"lastname" binding:"required"`
-	Email       string    `json:"email" form:"email" binding:"required,email"`
-	Phone       string    `json:"phone" form:"phone"`
-	Preferences string    `json:"preferences" form:"preferences"`
-	Status      string    `json:"status" form:"status"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID              int        `json:"id"`
+	FirstName       string     `json:"firstname" form:"firstname" binding:"required"`
+	LastName        string     `json:"lastname" form:"lastname" binding:"required"`
+	Email           string     `json:"email" form:"email" binding:"required,email"`
+	Phone           string     `json:"phone" form:"phone"`
+	Preferences     string     `json:"preferences" form:"preferences"`
+	PreferredGender *string    `json:"preferred_gender" form:"preferred_gender"`
+	PreferredColors []string   `json:"preferred_colors" form:"preferred_colors"`
+	DesiredFrom     *time.Time `json:"desired_from" form:"desired_from"`
+	DesiredBy       *time.Time `json:"desired_by" form:"desired_by"`
+	DepositStatus   string     `json:"deposit_status" form:"deposit_status"`
+	DepositAmount   *float64   `json:"deposit_amount"`
+	DepositDate     *time.Time `json:"deposit_date"`
+	DepositMethod   *string    `json:"deposit_method"`
+	QueuePosition   *int       `json:"queue_position"`
+	Status          string     `json:"status" form:"status"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+// WaitlistMatch is a waitlist entry scored against a litter or puppy. Score
+// runs from 0 to 100; Eligible is false when a hard preference (gender)
+// rules the entry out.
+type WaitlistMatch struct {
+	Entry         Waitlist `json:"entry"`
+	QueuePosition int      `json:"queuePosition"`
+	Score         int      `json:"score"`
+	Eligible      bool     `json:"eligible"`
+	Reasons       []string `json:"reasons"`
+}
+
+type WaitlistDuplicateGroup struct {
+	MatchedOn string     `json:"matchedOn"`
+	Value     string     `json:"value"`
+	Entries   []Waitlist `json:"entries"`
+}
+
+type MergeWaitlistRequest struct {
+	SourceIDs []int `json:"sourceIds" binding:"required,min=1"`
+}
+
+var WaitlistStatuses = []string{"New", "Contacted", "Deposited", "Matched", "Complete", "Withdrawn"}
+
+type WaitlistStatusChange struct {
+	ID            int64     `json:"id"`
+	WaitlistID    int       `json:"waitlistId"`
+	FromStatus    *string   `json:"fromStatus"`
+	ToStatus      string    `json:"toStatus"`
+	ChangedBy     *int      `json:"changedBy"`
+	ChangedByName *string   `json:"changedByName"`
+	Note          *string   `json:"note"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type WaitlistDepositRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Date   string  `json:"date" binding:"required"`
+	Method string  `json:"method" binding:"required,max=50"`
+	Note   string  `json:"note"`
+}
+
+type WaitlistPositionRequest struct {
+	Position int `json:"position" binding:"required,min=1"`
+}
+
+// WaitlistPortalEntry is what an applicant sees of their own entry. Internal
+// fields such as admin notes on deposits are left out.
+type WaitlistPortalEntry struct {
+	FirstName       string     `json:"firstname"`
+	LastName        string     `json:"lastname"`
+	Email           string     `json:"email"`
+	PendingEmail    *string    `json:"pendingEmail"`
+	Phone           string     `json:"phone"`
+	Preferences     string     `json:"preferences"`
+	PreferredGender *string    `json:"preferred_gender"`
+	PreferredColors []string   `json:"preferred_colors"`
+	DesiredFrom     *time.Time `json:"desired_from"`
+	DesiredBy       *time.Time `json:"desired_by"`
+	DepositStatus   string     `json:"deposit_status"`
+	Status          string     `json:"status"`
+	QueuePosition   *int       `json:"queuePosition"`
+	QueueLength     int        `json:"queueLength"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+type ConfirmWaitlistEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type WaitlistImportRow struct {
+	Row      int      `json:"row"`
+	Email    string   `json:"email"`
+	Result   string   `json:"result"`
+	ID       *int     `json:"id,omitempty"`
+	Messages []string `json:"messages"`
+}
+
+type WaitlistImportReport struct {
+	DryRun    bool                `json:"dryRun"`
+	Mapping   map[string]string   `json:"mapping"`
+	Unmapped  []string            `json:"unmappedColumns"`
+	TotalRows int                 `json:"totalRows"`
+	Created   int                 `json:"created"`
+	Skipped   int                 `json:"skipped"`
+	Failed    int                 `json:"failed"`
+	Rows      []WaitlistImportRow `json:"rows"`
 }
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+var WeightUnits = []string{"g", "kg", "oz", "lb"}
+
+type PuppyWeight struct {
+	ID        int       `json:"id"`
+	PuppyID   int       `json:"puppyId"`
+	Date      time.Time `json:"date"`
+	Weight    float64   `json:"weight"`
+	Unit      string    `json:"unit"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type PuppyWeightRequest struct {
+	Date   string  `json:"date" binding:"required"`
+	Weight float64 `json:"weight" binding:"required,gt=0"`
+	Unit   string  `json:"unit" binding:"required,oneof=g kg oz lb"`
+	Notes  string  `json:"notes"`
+}
+
+type LitterWeighInEntry struct {
+	PuppyID int     `json:"puppyId" binding:"required"`
+	Weight  float64 `json:"weight" binding:"required,gt=0"`
+	Notes   string  `json:"notes"`
+}
+
+// LitterWeighInRequest records one weigh-in for several puppies of a litter
+// on the same day and in the same unit.
+type LitterWeighInRequest struct {
+	Date    string               `json:"date" binding:"required"`
+	Unit    string               `json:"unit" binding:"required,oneof=g kg oz lb"`
+	Weights []LitterWeighInEntry `json:"weights" binding:"required,min=1,dive"`
+}
+
+// GrowthPoint is one weigh-in converted to the requested unit. Percentile
+// ranks the puppy against litter mates weighed the same day (0 is the
+// lightest, 100 the heaviest) and is null when it was weighed alone.
+type GrowthPoint struct {
+	ID         int       `json:"id"`
+	Date       time.Time `json:"date"`
+	AgeDays    *int      `json:"ageDays"`
+	Weight     float64   `json:"weight"`
+	Notes      string    `json:"notes"`
+	Percentile *float64  `json:"percentile"`
+	MatesCount int       `json:"matesWeighed"`
+}
+
+type GrowthAverage struct {
+	Date    time.Time `json:"date"`
+	AgeDays *int      `json:"ageDays"`
+	Weight  float64   `json:"weight"`
+}
+
+type PuppyGrowth struct {
+	PuppyID          int           `json:"puppyId"`
+	Name             string        `json:"name"`
+	Unit             string        `json:"unit"`
+	BirthDate        *time.Time    `json:"birthDate"`
+	Series           []GrowthPoint `json:"series"`
+	LatestWeight     *float64      `json:"latestWeight"`
+	LatestPercentile *float64      `json:"latestPercentile"`
+	DailyGain        *float64      `json:"dailyGain"`
+}
+
+type PuppyGrowthChart struct {
+	PuppyGrowth
+	LitterAverage []GrowthAverage `json:"litterAverage"`
+}
+
+type LitterGrowth struct {
+	LitterID  int             `json:"litterId"`
+	Unit      string          `json:"unit"`
+	BirthDate time.Time       `json:"birthDate"`
+	Puppies   []PuppyGrowth   `json:"puppies"`
+	Average   []GrowthAverage `json:"average"`
+}
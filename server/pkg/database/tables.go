@@ -41,6 +41,50 @@ func CreateTables() {
 					created_at TIMESTAMPTZ DEFAULT NOW()
 				);`,
 		},
+		{
+			Name: "users email_verified_at",
+			Query: `
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;`,
+		},
+		{
+			Name: "user_token_purpose Enum",
+			Query: `
+				DO $$ BEGIN
+					CREATE TYPE user_token_purpose AS ENUM ('PasswordReset', 'EmailVerification');
+				EXCEPTION
+					WHEN duplicate_object THEN null;
+				END $$;`,
+		},
+		{
+			Name: "user_tokens",
+			Query: `
+				CREATE TABLE IF NOT EXISTS user_tokens (
+					id SERIAL PRIMARY KEY,
+					user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					purpose user_token_purpose NOT NULL,
+					token_hash CHAR(64) UNIQUE NOT NULL,
+					expires_at TIMESTAMPTZ NOT NULL,
+					used_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ DEFAULT NOW()
+				);`,
+		},
+		{
+			Name: "api_keys",
+			Query: `
+				CREATE TABLE IF NOT EXISTS api_keys (
+					id SERIAL PRIMARY KEY,
+					name VARCHAR(100) NOT NULL,
+					key_prefix VARCHAR(20) NOT NULL,
+					key_hash CHAR(64) UNIQUE NOT NULL,
+					scopes TEXT[] NOT NULL DEFAULT '{}',
+					created_by INT REFERENCES users(id) ON DELETE SET NULL,
+					last_used_at TIMESTAMPTZ,
+					last_used_ip VARCHAR(45),
+					expires_at TIMESTAMPTZ,
+					revoked_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ DEFAULT NOW()
+				);`,
+		},
 		{
 			Name: "breeders",
 			Query: `
@@ -190,6 +234,467 @@ func CreateTables() {
 					updated_at TIMESTAMPTZ DEFAULT NOW()
 				);`,
 		},
+		{
+			Name: "email_templates",
+			Query: `
+				CREATE TABLE IF NOT EXISTS email_templates (
+					id SERIAL PRIMARY KEY,
+					key VARCHAR(100) UNIQUE NOT NULL,
+					subject TEXT NOT NULL,
+					html_body TEXT NOT NULL,
+					created_at TIMESTAMPTZ DEFAULT NOW(),
+					updated_at TIMESTAMPTZ DEFAULT NOW()
+				);`,
+		},
+		{
+			Name: "email_status Enum",
+			Query: `
+				DO $$ BEGIN
+					CREATE TYPE email_status AS ENUM ('Pending', 'Sent', 'Dead');
+				EXCEPTION
+					WHEN duplicate_object THEN null;
+				END $$;`,
+		},
+		{
+			Name: "email_outbox",
+			Query: `
+				CREATE TABLE IF NOT EXISTS email_outbox (
+					id BIGSERIAL PRIMARY KEY,
+					recipients TEXT[] NOT NULL,
+					subject TEXT NOT NULL,
+					html_body TEXT NOT NULL,
+					status email_status NOT NULL DEFAULT 'Pending',
+					attempts INT NOT NULL DEFAULT 0,
+					max_attempts INT NOT NULL DEFAULT 8,
+					next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					last_error TEXT,
+					sent_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ DEFAULT NOW(),
+					updated_at TIMESTAMPTZ DEFAULT NOW()
+				);`,
+		},
+		{
+			Name: "email_outbox indexes",
+			Query: `
+				CREATE INDEX IF NOT EXISTS email_outbox_pending_idx ON email_outbox (next_attempt_at) WHERE status = 'Pending';`,
+		},
+		{
+			Name: "audit_log",
+			Query: `
+				CREATE TABLE IF NOT EXISTS audit_log (
+					id BIGSERIAL PRIMARY KEY,
+					actor_user_id INT REFERENCES users(id) ON DELETE SET NULL,
+					actor_session_id INT,
+					actor_api_key_id INT REFERENCES api_keys(id) ON DELETE SET NULL,
+					action VARCHAR(20) NOT NULL,
+					entity_type VARCHAR(50) NOT NULL,
+					entity_id VARCHAR(50),
+					before JSONB,
+					after JSONB,
+					changes JSONB,
+					ip_address VARCHAR(45),
+					user_agent VARCHAR(255),
+					created_at TIMESTAMPTZ DEFAULT NOW()
+				);`,
+		},
+		{
+			Name: "audit_log indexes",
+			Query: `
+				CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (entity_type, entity_id);
+				CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log (created_at DESC);`,
+		},
+		{
+			Name: "email_outbox mime columns",
+			Query: `
+				ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS cc TEXT[] NOT NULL DEFAULT '{}';
+				ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS bcc TEXT[] NOT NULL DEFAULT '{}';
+				ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS text_body TEXT;
+				ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS attachments JSONB NOT NULL DEFAULT '[]';
+				ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS sensitive BOOLEAN NOT NULL DEFAULT FALSE;`,
+		},
+		{
+			Name: "email_outbox sensitive backfill",
+			Query: `
+				UPDATE email_outbox SET sensitive = TRUE WHERE NOT sensitive AND html_body LIKE '%?token=%';
+				UPDATE audit_log
+				SET before = before - 'html_body' - 'text_body',
+					after = after - 'html_body' - 'text_body',
+					changes = changes - 'html_body' - 'text_body'
+				WHERE entity_type = 'email_outbox'
+					AND (before ? 'html_body' OR after ? 'html_body' OR changes ? 'html_body'
+						OR before ? 'text_body' OR after ? 'text_body' OR changes ? 'text_body');`,
+		},
+		{
+			Name: "waitlist normalized contact",
+			Query: `
+				ALTER TABLE waitlist ADD COLUMN IF NOT EXISTS email_normalized TEXT
+					GENERATED ALWAYS AS (lower(btrim(email))) STORED;
+				ALTER TABLE waitlist ADD COLUMN IF NOT EXISTS phone_normalized TEXT
+					GENERATED ALWAYS AS (regexp_replace(regexp_replace(COALESCE(phone, ''), '\D', '', 'g'), '^1(\d{10})$', '\1')) STORED;
+				CREATE INDEX IF NOT EXISTS waitlist_email_normalized_idx ON waitlist (email_normalized);
+				CREATE INDEX IF NOT EXISTS waitlist_phone_normalized_idx ON waitlist (phone_normalized);`,
+		},
+		{
+			Name: "waitlist_deposit_status Enum",
+			Query: `
+				DO $$ BEGIN
+					CREATE TYPE waitlist_deposit_status AS ENUM ('None', 'Requested', 'Paid', 'Refunded');
+				EXCEPTION
+					WHEN duplicate_object THEN null;
+				END $$;`,
+		},
+		{
+			Name: "waitlist preferences",
+			Query: `
+				ALTER TABLE waitlist ADD COLUMN IF NOT EXISTS preferred_gender puppy_gender;
+				ALTER TABLE waitlist ADD COLUMN IF NOT EXISTS preferred_colors TEXT[] NOT NULL DEFAULT '{}';
+				ALTER TABLE waitlist ADD COLUMN IF NOT EXISTS desired_from DATE;
+				ALTER TABLE waitlist ADD COLUMN IF NOT EXISTS desired_by DATE;
+				ALTER TABLE waitlist ADD COLUMN IF NOT EXISTS deposit_status waitlist_deposit_status NOT NULL DEFAULT 'None';`,
+		},
+		{
+			Name: "waitlist_status Enum values",
+			Query: `
+				ALTER TYPE waitlist_status ADD VALUE IF NOT EXISTS 'Deposited';
+				ALTER TYPE waitlist_status ADD VALUE IF NOT EXISTS 'Matched';
+				ALTER TYPE waitlist_status ADD VALUE IF NOT EXISTS 'Withdrawn';`,
+		},
+		{
+			Name: "waitlist deposits and queue",
+			Query: `
+				ALTER TABLE waitlist ADD COLUMN IF NOT EXISTS deposit_amount NUMERIC(10, 2);
+				ALTER TABLE waitlist ADD COLUMN IF NOT EXISTS deposit_date DATE;
+				ALTER TABLE waitlist ADD COLUMN IF NOT EXISTS deposit_method VARCHAR(50);
+				ALTER TABLE waitlist ADD COLUMN IF NOT EXISTS queue_position INT;
+				UPDATE waitlist w
+				SET queue_position = sub.base + sub.rn
+				FROM (
+					SELECT id,
+						ROW_NUMBER() OVER (ORDER BY created_at, id) AS rn,
+						(SELECT COALESCE(MAX(queue_position), 0) FROM waitlist) AS base
+					FROM waitlist
+					WHERE queue_position IS NULL
+				) sub
+				WHERE w.id = sub.id;
+				CREATE INDEX IF NOT EXISTS waitlist_queue_position_idx ON waitlist (queue_position);`,
+		},
+		{
+			Name: "waitlist_status_history",
+			Query: `
+				CREATE TABLE IF NOT EXISTS waitlist_status_history (
+					id BIGSERIAL PRIMARY KEY,
+					waitlist_id INT NOT NULL REFERENCES waitlist(id) ON DELETE CASCADE,
+					from_status waitlist_status,
+					to_status waitlist_status NOT NULL,
+					changed_by INT REFERENCES users(id) ON DELETE SET NULL,
+					note TEXT,
+					created_at TIMESTAMPTZ DEFAULT NOW()
+				);
+				CREATE INDEX IF NOT EXISTS waitlist_status_history_waitlist_idx ON waitlist_status_history (waitlist_id, created_at);`,
+		},
+		{
+			Name: "reservation_status Enum",
+			Query: `
+				DO $$ BEGIN
+					CREATE TYPE reservation_status AS ENUM ('Active', 'Completed', 'Cancelled');
+				EXCEPTION
+					WHEN duplicate_object THEN null;
+				END $$;`,
+		},
+		{
+			Name: "puppy_reservations",
+			Query: `
+				CREATE TABLE IF NOT EXISTS puppy_reservations (
+					id SERIAL PRIMARY KEY,
+					puppy_id INT NOT NULL REFERENCES puppies(id) ON DELETE RESTRICT,
+					waitlist_id INT NOT NULL REFERENCES waitlist(id) ON DELETE RESTRICT,
+					status reservation_status NOT NULL DEFAULT 'Active',
+					reserved_date DATE NOT NULL DEFAULT CURRENT_DATE,
+					deposit_amount NUMERIC(10, 2),
+					pickup_date DATE,
+					notes TEXT,
+					previous_waitlist_status waitlist_status,
+					created_by INT REFERENCES users(id) ON DELETE SET NULL,
+					created_at TIMESTAMPTZ DEFAULT NOW(),
+					updated_at TIMESTAMPTZ DEFAULT NOW()
+				);
+				CREATE UNIQUE INDEX IF NOT EXISTS puppy_reservations_active_idx ON puppy_reservations (puppy_id) WHERE status = 'Active';
+				CREATE INDEX IF NOT EXISTS puppy_reservations_waitlist_idx ON puppy_reservations (waitlist_id);`,
+		},
+		{
+			Name: "waitlist_tokens",
+			Query: `
+				CREATE TABLE IF NOT EXISTS waitlist_tokens (
+					id SERIAL PRIMARY KEY,
+					waitlist_id INT NOT NULL REFERENCES waitlist(id) ON DELETE CASCADE,
+					token_hash CHAR(64) UNIQUE NOT NULL,
+					expires_at TIMESTAMPTZ NOT NULL,
+					last_used_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ DEFAULT NOW()
+				);`,
+		},
+		{
+			Name: "waitlist_email_changes",
+			Query: `
+				CREATE TABLE IF NOT EXISTS waitlist_email_changes (
+					id SERIAL PRIMARY KEY,
+					waitlist_id INT NOT NULL REFERENCES waitlist(id) ON DELETE CASCADE,
+					new_email VARCHAR(150) NOT NULL,
+					token_hash CHAR(64) UNIQUE NOT NULL,
+					expires_at TIMESTAMPTZ NOT NULL,
+					confirmed_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ DEFAULT NOW()
+				);`,
+		},
+		{
+			Name: "external_ancestors",
+			Query: `
+				CREATE TABLE IF NOT EXISTS external_ancestors (
+					id SERIAL PRIMARY KEY,
+					name VARCHAR(100) NOT NULL,
+					gender dog_gender NOT NULL,
+					registration_number VARCHAR(50),
+					registry VARCHAR(50),
+					birth_date DATE,
+					color VARCHAR(50),
+					breeder VARCHAR(100),
+					sire_id INT REFERENCES dogs(id) ON DELETE SET NULL,
+					sire_external_id INT REFERENCES external_ancestors(id) ON DELETE SET NULL,
+					dam_id INT REFERENCES dogs(id) ON DELETE SET NULL,
+					dam_external_id INT REFERENCES external_ancestors(id) ON DELETE SET NULL,
+					notes TEXT,
+					created_at TIMESTAMPTZ DEFAULT NOW(),
+					updated_at TIMESTAMPTZ DEFAULT NOW()
+				);
+				CREATE UNIQUE INDEX IF NOT EXISTS external_ancestors_registration_idx
+					ON external_ancestors (registry, registration_number) WHERE registration_number IS NOT NULL AND registration_number <> '';`,
+		},
+		{
+			Name: "pedigree columns",
+			Query: `
+				ALTER TABLE dogs ADD COLUMN IF NOT EXISTS registration_number VARCHAR(50);
+				ALTER TABLE dogs ADD COLUMN IF NOT EXISTS sire_id INT REFERENCES dogs(id) ON DELETE SET NULL;
+				ALTER TABLE dogs ADD COLUMN IF NOT EXISTS sire_external_id INT REFERENCES external_ancestors(id) ON DELETE SET NULL;
+				ALTER TABLE dogs ADD COLUMN IF NOT EXISTS dam_id INT REFERENCES dogs(id) ON DELETE SET NULL;
+				ALTER TABLE dogs ADD COLUMN IF NOT EXISTS dam_external_id INT REFERENCES external_ancestors(id) ON DELETE SET NULL;
+				ALTER TABLE litters ADD COLUMN IF NOT EXISTS mother_external_id INT REFERENCES external_ancestors(id) ON DELETE SET NULL;
+				ALTER TABLE litters ADD COLUMN IF NOT EXISTS father_external_id INT REFERENCES external_ancestors(id) ON DELETE SET NULL;`,
+		},
+		{
+			Name: "litters coi column",
+			Query: `
+				ALTER TABLE litters ADD COLUMN IF NOT EXISTS coi DOUBLE PRECISION;`,
+		},
+		{
+			Name: "puppy promotion",
+			Query: `
+				ALTER TYPE puppy_status ADD VALUE IF NOT EXISTS 'Retained';
+				ALTER TABLE dogs ADD COLUMN IF NOT EXISTS litter_id INT REFERENCES litters(id) ON DELETE SET NULL;
+				ALTER TABLE puppies ADD COLUMN IF NOT EXISTS promoted_dog_id INT REFERENCES dogs(id) ON DELETE SET NULL;`,
+		},
+		{
+			Name: "health_record_type Enum",
+			Query: `
+				DO $$ BEGIN
+					CREATE TYPE health_record_type AS ENUM ('Vaccination', 'Deworming', 'Vet Visit', 'Genetic Test', 'Other');
+				EXCEPTION
+					WHEN duplicate_object THEN null;
+				END $$;`,
+		},
+		{
+			Name: "health_records",
+			Query: `
+				CREATE TABLE IF NOT EXISTS health_records (
+					id SERIAL PRIMARY KEY,
+					dog_id INT REFERENCES dogs(id) ON DELETE CASCADE,
+					puppy_id INT REFERENCES puppies(id) ON DELETE CASCADE,
+					type health_record_type NOT NULL,
+					title VARCHAR(150) NOT NULL,
+					record_date DATE NOT NULL,
+					next_due_date DATE,
+					veterinarian VARCHAR(150),
+					result VARCHAR(100),
+					lab VARCHAR(100),
+					certificate_file_id INT REFERENCES files(id) ON DELETE SET NULL,
+					notes TEXT,
+					is_public BOOLEAN NOT NULL DEFAULT false,
+					reminder_sent_at TIMESTAMPTZ,
+					created_by INT REFERENCES users(id) ON DELETE SET NULL,
+					created_at TIMESTAMPTZ DEFAULT NOW(),
+					updated_at TIMESTAMPTZ DEFAULT NOW(),
+					CHECK ((dog_id IS NULL) <> (puppy_id IS NULL))
+				);
+				CREATE INDEX IF NOT EXISTS health_records_dog_idx ON health_records (dog_id, record_date);
+				CREATE INDEX IF NOT EXISTS health_records_puppy_idx ON health_records (puppy_id, record_date);
+				CREATE INDEX IF NOT EXISTS health_records_due_idx ON health_records (next_due_date) WHERE reminder_sent_at IS NULL;`,
+		},
+		{
+			Name: "weight_unit Enum",
+			Query: `
+				DO $$ BEGIN
+					CREATE TYPE weight_unit AS ENUM ('g', 'kg', 'oz', 'lb');
+				EXCEPTION
+					WHEN duplicate_object THEN null;
+				END $$;`,
+		},
+		{
+			Name: "puppy_weights",
+			Query: `
+				CREATE TABLE IF NOT EXISTS puppy_weights (
+					id SERIAL PRIMARY KEY,
+					puppy_id INT NOT NULL REFERENCES puppies(id) ON DELETE CASCADE,
+					weighed_on DATE NOT NULL,
+					weight NUMERIC(8, 2) NOT NULL CHECK (weight > 0),
+					unit weight_unit NOT NULL,
+					weight_grams NUMERIC GENERATED ALWAYS AS (
+						weight * CASE unit WHEN 'kg' THEN 1000 WHEN 'oz' THEN 28.349523125 WHEN 'lb' THEN 453.59237 ELSE 1 END
+					) STORED,
+					notes TEXT,
+					created_by INT REFERENCES users(id) ON DELETE SET NULL,
+					created_at TIMESTAMPTZ DEFAULT NOW(),
+					updated_at TIMESTAMPTZ DEFAULT NOW(),
+					UNIQUE (puppy_id, weighed_on)
+				);`,
+		},
+		{
+			Name: "breeding_event_type Enum",
+			Query: `
+				DO $$ BEGIN
+					CREATE TYPE breeding_event_type AS ENUM ('Heat', 'Progesterone', 'Breeding');
+				EXCEPTION
+					WHEN duplicate_object THEN null;
+				END $$;`,
+		},
+		{
+			Name: "breeding_events",
+			Query: `
+				CREATE TABLE IF NOT EXISTS breeding_events (
+					id SERIAL PRIMARY KEY,
+					dog_id INT NOT NULL REFERENCES dogs(id) ON DELETE CASCADE,
+					type breeding_event_type NOT NULL,
+					event_date DATE NOT NULL,
+					end_date DATE,
+					progesterone NUMERIC(6, 2),
+					sire_id INT REFERENCES dogs(id) ON DELETE SET NULL,
+					sire_external_id INT REFERENCES external_ancestors(id) ON DELETE SET NULL,
+					sire_name VARCHAR(100),
+					due_date DATE GENERATED ALWAYS AS (CASE WHEN type = 'Breeding' THEN event_date + 63 END) STORED,
+					litter_id INT REFERENCES litters(id) ON DELETE SET NULL,
+					notes TEXT,
+					created_by INT REFERENCES users(id) ON DELETE SET NULL,
+					created_at TIMESTAMPTZ DEFAULT NOW(),
+					updated_at TIMESTAMPTZ DEFAULT NOW(),
+					CHECK (end_date IS NULL OR end_date >= event_date),
+					CHECK ((type = 'Progesterone') = (progesterone IS NOT NULL))
+				);
+				CREATE INDEX IF NOT EXISTS breeding_events_dog_idx ON breeding_events (dog_id, event_date);
+				CREATE INDEX IF NOT EXISTS breeding_events_due_idx ON breeding_events (due_date) WHERE due_date IS NOT NULL;`,
+		},
+		{
+			Name: "coat_colors",
+			Query: `
+				CREATE TABLE IF NOT EXISTS coat_colors (
+					id SERIAL PRIMARY KEY,
+					name VARCHAR(50) NOT NULL,
+					sort_order INT NOT NULL DEFAULT 0,
+					created_at TIMESTAMPTZ DEFAULT NOW()
+				);
+				CREATE UNIQUE INDEX IF NOT EXISTS coat_colors_name_idx ON coat_colors (lower(name));
+				INSERT INTO coat_colors (name, sort_order)
+				SELECT v.name, v.sort_order
+				FROM (VALUES ('Fawn', 1), ('Black', 2), ('Apricot', 3), ('Silver Fawn', 4), ('Silver', 5), ('Brindle', 6)) AS v(name, sort_order)
+				WHERE NOT EXISTS (SELECT 1 FROM coat_colors);`,
+		},
+		{
+			Name: "puppy attributes",
+			Query: `
+				ALTER TABLE puppies ADD COLUMN IF NOT EXISTS color_id INT REFERENCES coat_colors(id) ON DELETE SET NULL;
+				ALTER TABLE puppies ADD COLUMN IF NOT EXISTS color_genetics JSONB NOT NULL DEFAULT '{}'::jsonb;
+				ALTER TABLE puppies ADD COLUMN IF NOT EXISTS registration_body VARCHAR(50);
+				ALTER TABLE puppies ADD COLUMN IF NOT EXISTS registration_number VARCHAR(50);
+				ALTER TABLE puppies ADD COLUMN IF NOT EXISTS microchip VARCHAR(20);
+				ALTER TABLE puppies ADD COLUMN IF NOT EXISTS expected_adult_weight NUMERIC(6, 2) CHECK (expected_adult_weight > 0);
+				ALTER TABLE puppies ADD COLUMN IF NOT EXISTS expected_adult_weight_unit weight_unit;
+				ALTER TABLE puppies ADD COLUMN IF NOT EXISTS expected_adult_weight_grams NUMERIC GENERATED ALWAYS AS (
+					expected_adult_weight * CASE expected_adult_weight_unit WHEN 'kg' THEN 1000 WHEN 'oz' THEN 28.349523125 WHEN 'lb' THEN 453.59237 ELSE 1 END
+				) STORED;
+				UPDATE puppies p SET color_id = cc.id
+				FROM coat_colors cc
+				WHERE p.color_id IS NULL AND lower(p.color) = lower(cc.name);
+				CREATE INDEX IF NOT EXISTS puppies_color_id_idx ON puppies (color_id);
+				CREATE INDEX IF NOT EXISTS puppies_color_genetics_idx ON puppies USING GIN (color_genetics);
+				CREATE UNIQUE INDEX IF NOT EXISTS puppies_microchip_idx ON puppies (microchip) WHERE microchip IS NOT NULL;
+				CREATE UNIQUE INDEX IF NOT EXISTS puppies_registration_idx
+					ON puppies (registration_body, registration_number) WHERE registration_number IS NOT NULL;`,
+		},
+		{
+			Name: "puppy pricing",
+			Query: `
+				ALTER TABLE puppies ADD COLUMN IF NOT EXISTS list_price NUMERIC(10, 2) CHECK (list_price >= 0);
+				ALTER TABLE puppies ADD COLUMN IF NOT EXISTS price_visible BOOLEAN NOT NULL DEFAULT false;
+				ALTER TABLE puppies ADD COLUMN IF NOT EXISTS deposit_amount NUMERIC(10, 2) CHECK (deposit_amount >= 0);`,
+		},
+		{
+			Name: "puppy_sales",
+			Query: `
+				CREATE TABLE IF NOT EXISTS puppy_sales (
+					id SERIAL PRIMARY KEY,
+					puppy_id INT UNIQUE NOT NULL REFERENCES puppies(id) ON DELETE RESTRICT,
+					reservation_id INT REFERENCES puppy_reservations(id) ON DELETE SET NULL,
+					sale_price NUMERIC(10, 2) NOT NULL CHECK (sale_price >= 0),
+					sale_date DATE NOT NULL,
+					buyer_name VARCHAR(200) NOT NULL,
+					buyer_email VARCHAR(150),
+					buyer_phone VARCHAR(50),
+					buyer_address TEXT,
+					notes TEXT,
+					created_by INT REFERENCES users(id) ON DELETE SET NULL,
+					created_at TIMESTAMPTZ DEFAULT NOW(),
+					updated_at TIMESTAMPTZ DEFAULT NOW()
+				);
+				CREATE INDEX IF NOT EXISTS puppy_sales_date_idx ON puppy_sales (sale_date);`,
+		},
+		{
+			Name: "payment_kind Enum",
+			Query: `
+				DO $$ BEGIN
+					CREATE TYPE payment_kind AS ENUM ('Deposit', 'Payment', 'Refund');
+				EXCEPTION
+					WHEN duplicate_object THEN null;
+				END $$;`,
+		},
+		{
+			Name: "puppy_payments",
+			Query: `
+				CREATE TABLE IF NOT EXISTS puppy_payments (
+					id SERIAL PRIMARY KEY,
+					puppy_id INT NOT NULL REFERENCES puppies(id) ON DELETE RESTRICT,
+					kind payment_kind NOT NULL,
+					amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+					paid_on DATE NOT NULL,
+					method VARCHAR(50),
+					reference VARCHAR(100),
+					notes TEXT,
+					created_by INT REFERENCES users(id) ON DELETE SET NULL,
+					created_at TIMESTAMPTZ DEFAULT NOW()
+				);
+				CREATE INDEX IF NOT EXISTS puppy_payments_puppy_idx ON puppy_payments (puppy_id, paid_on);`,
+		},
+		{
+			Name: "contract_templates",
+			Query: `
+				CREATE TABLE IF NOT EXISTS contract_templates (
+					id SERIAL PRIMARY KEY,
+					name VARCHAR(100) NOT NULL,
+					body TEXT NOT NULL,
+					health_guarantee TEXT,
+					is_default BOOLEAN NOT NULL DEFAULT false,
+					created_at TIMESTAMPTZ DEFAULT NOW(),
+					updated_at TIMESTAMPTZ DEFAULT NOW()
+				);
+				CREATE UNIQUE INDEX IF NOT EXISTS contract_templates_default_idx ON contract_templates (is_default) WHERE is_default;
+				ALTER TABLE puppy_sales ADD COLUMN IF NOT EXISTS contract_file_id INT REFERENCES files(id) ON DELETE SET NULL;`,
+		},
 	}
 
 	for _, item := range tables {
@@ -0,0 +1,31 @@
+package utils
+
+import "strings"
+
+// NormalizeEmail and NormalizePhone mirror the email_normalized and
+// phone_normalized generated columns on the waitlist table.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func NormalizePhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+
+	if len(digits) == 11 && digits[0] == '1' {
+		return digits[1:]
+	}
+	return digits
+}
+
+// minPhoneDigits keeps partial or placeholder numbers from matching
+// unrelated entries.
+const minPhoneDigits = 7
+
+func IsMatchablePhone(normalized string) bool {
+	return len(normalized) >= minPhoneDigits
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+)
+
+// DefaultContractTemplate is used when no contract template has been saved as
+// the default. Templates are plain text run through text/template: a line
+// starting with "# " is the title, "## " a section heading, and blank lines
+// separate paragraphs.
+const DefaultContractTemplate = `# Puppy Sale Contract
+
+This agreement is made on {{.Date}} between {{.BreederName}} of {{.BreederLocation}} ("Breeder") and {{.BuyerName}} ("Buyer").
+
+## The Puppy
+Name: {{.PuppyName}}
+Sex: {{.PuppyGender}}
+Color: {{.PuppyColor}}
+Date of birth: {{.PuppyBirthDate}}
+Litter: {{.LitterName}}
+Sire: {{.SireName}}
+Dam: {{.DamName}}
+Microchip: {{.PuppyMicrochip}}
+Registration: {{.PuppyRegistration}}
+
+## Price and Payment
+The purchase price is {{.SalePrice}}. A non-refundable deposit of {{.DepositAmount}} secures the puppy. Payments received to date total {{.AmountPaid}}, leaving a balance of {{.Balance}} due on or before pickup.
+
+## Health Guarantee
+{{.HealthGuarantee}}
+
+## Buyer
+{{.BuyerName}}
+{{.BuyerAddress}}
+{{.BuyerEmail}} {{.BuyerPhone}}
+
+## Signatures
+Breeder: ______________________________  Date: ____________
+
+Buyer: ______________________________  Date: ____________`
+
+const DefaultHealthGuarantee = `The Breeder guarantees the puppy to be in good health at the time of sale, as confirmed by the enclosed veterinary records. The Buyer agrees to have the puppy examined by a licensed veterinarian within 72 hours of pickup. The Breeder further guarantees the puppy against life-threatening congenital defects for one year from the date of sale, on receipt of a written diagnosis from a licensed veterinarian. Under this guarantee the Breeder will offer a replacement puppy of equal value; no cash refunds are given.`
+
+// ContractSample lists every placeholder a contract template may use. It is
+// used to validate templates before they are saved.
+var ContractSample = map[string]any{
+	"Date":              "March 1, 2025",
+	"BreederName":       "April Smith",
+	"BreederEmail":      "april@example.com",
+	"BreederPhone":      "555-0100",
+	"BreederLocation":   "Springfield, MO",
+	"BuyerName":         "Jane Doe",
+	"BuyerEmail":        "jane@example.com",
+	"BuyerPhone":        "555-0123",
+	"BuyerAddress":      "1 Main St, Springfield, MO",
+	"PuppyName":         "Biscuit",
+	"PuppyGender":       "Female",
+	"PuppyColor":        "Fawn",
+	"PuppyBirthDate":    "January 4, 2025",
+	"PuppyMicrochip":    "985112345678901",
+	"PuppyRegistration": "AKC PR12345678",
+	"LitterName":        "Bella x Max",
+	"SireName":          "Max",
+	"DamName":           "Bella",
+	"ListPrice":         "$2,500.00",
+	"SalePrice":         "$2,500.00",
+	"DepositAmount":     "$500.00",
+	"AmountPaid":        "$500.00",
+	"Balance":           "$2,000.00",
+	"SaleDate":          "March 1, 2025",
+	"HealthGuarantee":   DefaultHealthGuarantee,
+}
+
+// RenderContractText fills in a contract template. Referencing a placeholder
+// that isn't in data is an error.
+func RenderContractText(body string, data map[string]any) (string, error) {
+	tmpl, err := texttemplate.New("contract").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("invalid contract template: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render contract: %w", err)
+	}
+	return out.String(), nil
+}
+
+// ContractPDF lays out rendered contract text as a PDF.
+func ContractPDF(title string, text string) []byte {
+	var blocks []PDFBlock
+	var paragraph []string
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, PDFBlock{Text: strings.Join(paragraph, "\n"), Spacing: 8})
+			paragraph = nil
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "## "):
+			flush()
+			blocks = append(blocks, PDFBlock{Text: strings.TrimPrefix(trimmed, "## "), Bold: true, Size: 12.5, Spacing: 4})
+		case strings.HasPrefix(trimmed, "# "):
+			flush()
+			blocks = append(blocks, PDFBlock{Text: strings.TrimPrefix(trimmed, "# "), Bold: true, Size: 18, Spacing: 12})
+		case trimmed == "":
+			flush()
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+
+	return RenderPDF(title, blocks)
+}
+
+// FormatMoney formats an amount as US dollars, e.g. $2,500.00.
+func FormatMoney(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	whole, cents, _ := strings.Cut(strconv.FormatFloat(amount, 'f', 2, 64), ".")
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return sign + "$" + whole + "." + cents
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/config"
+)
+
+const (
+	SessionCookieName = "alp_session"
+	CSRFCookieName    = "alp_csrf"
+	CSRFHeaderName    = "X-CSRF-Token"
+)
+
+// SetAuthCookies issues the session token as an HttpOnly cookie alongside a
+// JS-readable CSRF cookie. Clients echo the CSRF value back in the
+// X-CSRF-Token header on mutating requests (double-submit pattern).
+func SetAuthCookies(w http.ResponseWriter, sessionToken string, csrfToken string, expiresAt time.Time) {
+	cfg := config.Load()
+	maxAge := int(time.Until(expiresAt).Seconds())
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    sessionToken,
+		Path:     "/api",
+		Domain:   cfg.CookieDomain,
+		Expires:  expiresAt,
+		MaxAge:   maxAge,
+		Secure:   cfg.CookieSecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    csrfToken,
+		Path:     "/",
+		Domain:   cfg.CookieDomain,
+		Expires:  expiresAt,
+		MaxAge:   maxAge,
+		Secure:   cfg.CookieSecure,
+		HttpOnly: false,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func ClearAuthCookies(w http.ResponseWriter) {
+	cfg := config.Load()
+
+	for name, path := range map[string]string{SessionCookieName: "/api", CSRFCookieName: "/"} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     path,
+			Domain:   cfg.CookieDomain,
+			Expires:  time.Unix(0, 0),
+			MaxAge:   -1,
+			Secure:   cfg.CookieSecure,
+			HttpOnly: name == SessionCookieName,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+}
@@ -2,37 +2,40 @@ package utils
 
 import (
 	"fmt"
-	"net/smtp"
+	"net/mail"
 
 	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/config"
 )
 
 func SendEmail(to []string, subject string, htmlBody string) error {
+	return SendMessage(&EmailMessage{To: to, Subject: subject, HTMLBody: htmlBody})
+}
+
+func SendMessage(msg *EmailMessage) error {
 	cfg := config.Load()
 
-	if cfg.EmailUser == "" || cfg.EmailPassword == "" {
-		return fmt.Errorf("email credentials are not set")
+	if cfg.EmailUser == "" {
+		return fmt.Errorf("email sender is not set")
 	}
 
-	auth := smtp.PlainAuth("", cfg.EmailUser, cfg.EmailPassword, cfg.EmailServiceHost)
-
-	headers := make(map[string]string)
-	headers["From"] = cfg.EmailUser
-	headers["To"] = to[0]
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=\"UTF-8\""
-
-	message := ""
-	for k, v := range headers {
-		message += fmt.Sprintf("%s: %s\r\n", k, v)
+	for i := range msg.Attachments {
+		if msg.Attachments[i].Data != nil {
+			continue
+		}
+		data, err := ReadStoredFile(msg.Attachments[i].URL)
+		if err != nil {
+			return fmt.Errorf("failed to load attachment %q: %v", msg.Attachments[i].Name, err)
+		}
+		msg.Attachments[i].Data = data
 	}
-	message += "\r\n" + htmlBody
 
-	addr := fmt.Sprintf("%s:%s", cfg.EmailServiceHost, cfg.EmailServicePort)
-
-	err := smtp.SendMail(addr, auth, cfg.EmailUser, to, []byte(message))
+	transport, err := NewMailTransport(cfg)
 	if err != nil {
+		return err
+	}
+
+	from := mail.Address{Name: cfg.EmailFromName, Address: cfg.EmailUser}
+	if err := transport.Send(from, msg); err != nil {
 		return fmt.Errorf("failed to send email: %v", err)
 	}
 
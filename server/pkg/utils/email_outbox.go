@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+const (
+	emailWorkerInterval = 5 * time.Second
+	emailWorkerBatch    = 10
+	emailDeliveryLease  = 5 * time.Minute
+	emailBaseBackoff    = 30 * time.Second
+	emailMaxBackoff     = 6 * time.Hour
+)
+
+type outboxDelivery struct {
+	id          int64
+	message     EmailMessage
+	attempts    int
+	maxAttempts int
+}
+
+func QueueEmail(ctx context.Context, to []string, subject string, htmlBody string) (int64, error) {
+	return QueueMessage(ctx, EmailMessage{To: to, Subject: subject, HTMLBody: htmlBody})
+}
+
+// QueueSensitiveEmail queues a message whose body holds a live token, such as
+// a password reset link, so the outbox never shows it to other admins.
+func QueueSensitiveEmail(ctx context.Context, to []string, subject string, htmlBody string) (int64, error) {
+	return QueueMessage(ctx, EmailMessage{To: to, Subject: subject, HTMLBody: htmlBody, Sensitive: true})
+}
+
+// QueueMessage stores a message in the email_outbox table for the background
+// worker to deliver. Callers should treat a nil error as "will be sent": the
+// worker retries with backoff and dead-letters messages that keep failing.
+// Attachments are stored by URL and read from storage at delivery time.
+func QueueMessage(ctx context.Context, msg EmailMessage) (int64, error) {
+	if len(msg.To) == 0 {
+		return 0, fmt.Errorf("email has no recipients")
+	}
+
+	var textBody *string
+	if msg.TextBody != "" {
+		textBody = &msg.TextBody
+	}
+
+	attachments := msg.Attachments
+	if attachments == nil {
+		attachments = []models.EmailAttachment{}
+	}
+
+	var id int64
+	query := `
+		INSERT INTO email_outbox (recipients, cc, bcc, subject, text_body, html_body, attachments, sensitive)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`
+
+	err := database.Pool.QueryRow(ctx, query,
+		msg.To, nonNilStrings(msg.Cc), nonNilStrings(msg.Bcc), msg.Subject, textBody, msg.HTMLBody, attachments, msg.Sensitive,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to queue email: %w", err)
+	}
+
+	slog.Debug("email outbox: queued", "email_id", id, "recipient_count", len(msg.Recipients()), "attachment_count", len(attachments))
+	return id, nil
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func StartEmailWorker() {
+	slog.Info("email worker: started", "interval", emailWorkerInterval.String())
+
+	ticker := time.NewTicker(emailWorkerInterval)
+	defer ticker.Stop()
+
+	processEmailOutbox()
+	for range ticker.C {
+		processEmailOutbox()
+	}
+}
+
+func processEmailOutbox() {
+	if database.Pool == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Claiming pushes next_attempt_at out by a lease, so a message whose
+	// delivery is interrupted by a crash is picked up again once it expires.
+	query := `
+		UPDATE email_outbox SET next_attempt_at = $1, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = 'Pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipients, cc, bcc, subject, COALESCE(text_body, ''), html_body, attachments, attempts, max_attempts`
+
+	rows, err := database.Pool.Query(ctx, query, time.Now().Add(emailDeliveryLease), emailWorkerBatch)
+	if err != nil {
+		slog.Error("email worker: failed to claim messages", "error", err)
+		return
+	}
+
+	var batch []outboxDelivery
+	for rows.Next() {
+		var d outboxDelivery
+		if err := rows.Scan(
+			&d.id, &d.message.To, &d.message.Cc, &d.message.Bcc, &d.message.Subject, &d.message.TextBody,
+			&d.message.HTMLBody, &d.message.Attachments, &d.attempts, &d.maxAttempts,
+		); err != nil {
+			slog.Warn("email worker: failed to scan message", "error", err)
+			continue
+		}
+		batch = append(batch, d)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		slog.Error("email worker: failed to read claimed messages", "error", err)
+	}
+
+	for _, d := range batch {
+		deliverOutboxEmail(d)
+	}
+}
+
+func deliverOutboxEmail(d outboxDelivery) {
+	sendErr := SendMessage(&d.message)
+	attempts := d.attempts + 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if sendErr == nil {
+		_, err := database.Pool.Exec(ctx, `
+			UPDATE email_outbox SET status = 'Sent', attempts = $1, last_error = NULL, sent_at = NOW(), updated_at = NOW()
+			WHERE id = $2`, attempts, d.id)
+		if err != nil {
+			slog.Error("email worker: failed to mark message sent", "email_id", d.id, "error", err)
+			return
+		}
+
+		slog.Info("email worker: message sent", "email_id", d.id, "attempts", attempts)
+		return
+	}
+
+	if attempts >= d.maxAttempts {
+		_, err := database.Pool.Exec(ctx, `
+			UPDATE email_outbox SET status = 'Dead', attempts = $1, last_error = $2, updated_at = NOW()
+			WHERE id = $3`, attempts, sendErr.Error(), d.id)
+		if err != nil {
+			slog.Error("email worker: failed to dead-letter message", "email_id", d.id, "error", err)
+			return
+		}
+
+		slog.Error("email worker: message dead-lettered", "email_id", d.id, "attempts", attempts, "error", sendErr)
+		return
+	}
+
+	nextAttempt := time.Now().Add(emailBackoff(attempts))
+	_, err := database.Pool.Exec(ctx, `
+		UPDATE email_outbox SET attempts = $1, last_error = $2, next_attempt_at = $3, updated_at = NOW()
+		WHERE id = $4`, attempts, sendErr.Error(), nextAttempt, d.id)
+	if err != nil {
+		slog.Error("email worker: failed to schedule retry", "email_id", d.id, "error", err)
+		return
+	}
+
+	slog.Warn("email worker: delivery failed, will retry", "email_id", d.id, "attempts", attempts, "next_attempt_at", nextAttempt, "error", sendErr)
+}
+
+// emailBackoff doubles the wait after each failed attempt, capped at
+// emailMaxBackoff, with up to 20% jitter so retries don't bunch up.
+func emailBackoff(attempts int) time.Duration {
+	backoff := emailMaxBackoff
+	if attempts < 20 {
+		backoff = min(emailBaseBackoff<<(attempts-1), emailMaxBackoff)
+	}
+
+	jitter := time.Duration(rand.Int64N(int64(backoff) / 5))
+	return backoff + jitter
+}
@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"log/slog"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+const (
+	EmailTemplateWaitlistAdmin        = "waitlist_admin_notification"
+	EmailTemplateWaitlistConfirmation = "waitlist_confirmation"
+	EmailTemplatePasswordReset        = "password_reset"
+	EmailTemplateEmailVerification    = "email_verification"
+	EmailTemplateFileAttachment       = "file_attachment"
+	EmailTemplateWaitlistPortal       = "waitlist_portal_link"
+	EmailTemplateWaitlistEmailChange  = "waitlist_email_change"
+	EmailTemplateWaitlistEmailNotice  = "waitlist_email_change_notice"
+	EmailTemplateHealthReminder       = "health_reminder"
+)
+
+// EmailTemplateDefinition is a built-in email. Admins can override the subject
+// and body through the email_templates table; Sample is used to validate and
+// preview edits, and lists the variables the template may reference.
+type EmailTemplateDefinition struct {
+	Key         string
+	Description string
+	Subject     string
+	HTMLBody    string
+	Sample      map[string]any
+}
+
+var DefaultEmailTemplates = []EmailTemplateDefinition{
+	{
+		Key:         EmailTemplateWaitlistAdmin,
+		Description: "Sent to every admin user when someone joins the waitlist.",
+		Subject:     "New Waitlist Entry - April's Lil Pugs",
+		HTMLBody: `<h2>New Waitlist Entry Received</h2>
+<p><strong>Name:</strong> {{.FirstName}} {{.LastName}}</p>
+<p><strong>Email:</strong> {{.Email}}</p>
+<p><strong>Phone Number:</strong> {{.Phone}}</p>
+<p><strong>Status:</strong> {{.Status}}</p>
+{{if .LookingFor}}<p><strong>Looking For:</strong> {{.LookingFor}}</p>{{end}}
+<p><strong>Preferences/Notes:</strong> {{.Preferences}}</p>
+<p><strong>Date Added:</strong> {{.CreatedAt}}</p>
+<p>View the waitlist on the <a href="{{.AdminURL}}">website</a>.</p>`,
+		Sample: map[string]any{
+			"FirstName":   "Jane",
+			"LastName":    "Doe",
+			"Email":       "jane@example.com",
+			"Phone":       "555-0100",
+			"Status":      "New",
+			"Preferences": "Fawn female",
+			"LookingFor":  "Female, Fawn, from 2025-03-01",
+			"CreatedAt":   "2025-01-01 09:00 AM",
+			"AdminURL":    "https://aprilslilpugs.com/admin",
+		},
+	},
+	{
+		Key:         EmailTemplateWaitlistConfirmation,
+		Description: "Sent to the applicant after they join the waitlist.",
+		Subject:     "You're on the waitlist - April's Lil Pugs",
+		HTMLBody: `<h2>Thanks for joining our waitlist, {{.FirstName}}!</h2>
+<p>We've received your request and will be in touch as puppies become available.</p>
+{{if .Preferences}}<p><strong>Your preferences:</strong> {{.Preferences}}</p>{{end}}
+<p>In the meantime, you can see our current litters on the <a href="{{.SiteURL}}">website</a>.</p>
+{{if .PortalURL}}<p>You can check your place in line or update your details <a href="{{.PortalURL}}">here</a>.</p>{{end}}
+<p>April's Lil Pugs</p>`,
+		Sample: map[string]any{
+			"FirstName":   "Jane",
+			"LastName":    "Doe",
+			"Preferences": "Fawn female",
+			"SiteURL":     "https://aprilslilpugs.com",
+			"PortalURL":   "https://aprilslilpugs.com/waitlist/portal?token=example",
+		},
+	},
+	{
+		Key:         EmailTemplateWaitlistPortal,
+		Description: "Sent when an applicant asks for a link to manage their waitlist entry.",
+		Subject:     "Manage your waitlist entry - April's Lil Pugs",
+		HTMLBody: `<h2>Your Waitlist Entry</h2>
+<p>Hi {{.FirstName}},</p>
+<p>Use the link below to see your place in line, update your contact details and preferences, or leave the waitlist.</p>
+<p><a href="{{.PortalURL}}">Manage my waitlist entry</a></p>
+<p>This link expires in {{.ExpiresIn}}. If you didn't ask for it, you can ignore this email.</p>`,
+		Sample: map[string]any{
+			"FirstName": "Jane",
+			"PortalURL": "https://aprilslilpugs.com/waitlist/portal?token=example",
+			"ExpiresIn": "7 days",
+		},
+	},
+	{
+		Key:         EmailTemplateWaitlistEmailChange,
+		Description: "Sent to the new address when an applicant changes their email from the waitlist portal.",
+		Subject:     "Confirm your new email - April's Lil Pugs",
+		HTMLBody: `<h2>Confirm Your New Email</h2>
+<p>Hi {{.FirstName}},</p>
+<p>Please confirm that you'd like us to use this address for your waitlist entry.</p>
+<p><a href="{{.ConfirmURL}}">Confirm my new email</a></p>
+<p>This link expires in {{.ExpiresIn}}. Until then we'll keep using your previous address. If you didn't ask for this, you can ignore this email.</p>`,
+		Sample: map[string]any{
+			"FirstName":  "Jane",
+			"ConfirmURL": "https://aprilslilpugs.com/waitlist/email/confirm?token=example",
+			"ExpiresIn":  "24 hours",
+		},
+	},
+	{
+		Key:         EmailTemplateWaitlistEmailNotice,
+		Description: "Sent to the current address when someone asks to change the email on a waitlist entry.",
+		Subject:     "Your waitlist email is being changed - April's Lil Pugs",
+		HTMLBody: `<h2>Email Change Requested</h2>
+<p>Hi {{.FirstName}},</p>
+<p>Someone asked to change the email on your waitlist entry to {{.NewEmail}}. The change only takes effect once it is confirmed from that address.</p>
+<p>If this wasn't you, please reply to this email or contact us so we can keep your place in line safe.</p>`,
+		Sample: map[string]any{
+			"FirstName": "Jane",
+			"NewEmail":  "jane.new@example.com",
+		},
+	},
+	{
+		Key:         EmailTemplatePasswordReset,
+		Description: "Sent when a user requests a password reset.",
+		Subject:     "Reset Your Password - April's Lil Pugs",
+		HTMLBody: `<h2>Password Reset Requested</h2>
+<p>Hi {{.FirstName}},</p>
+<p>We received a request to reset the password for your April's Lil Pugs account.</p>
+<p><a href="{{.ResetURL}}">Choose a new password</a></p>
+<p>This link expires in {{.ExpiresIn}} and can only be used once. If you didn't request a reset, you can safely ignore this email.</p>`,
+		Sample: map[string]any{
+			"FirstName": "Jane",
+			"ResetURL":  "https://aprilslilpugs.com/reset-password?token=example",
+			"ExpiresIn": "1 hour",
+		},
+	},
+	{
+		Key:         EmailTemplateEmailVerification,
+		Description: "Sent to newly created users to confirm their email address.",
+		Subject:     "Verify Your Email - April's Lil Pugs",
+		HTMLBody: `<h2>Verify Your Email Address</h2>
+<p>Hi {{.FirstName}},</p>
+<p>An account was created for you on the April's Lil Pugs website. Please confirm this is your email address.</p>
+<p><a href="{{.VerifyURL}}">Verify your email</a></p>
+<p>This link expires in {{.ExpiresIn}}.</p>`,
+		Sample: map[string]any{
+			"FirstName": "Jane",
+			"VerifyURL": "https://aprilslilpugs.com/verify-email?token=example",
+			"ExpiresIn": "48 hours",
+		},
+	},
+	{
+		Key:         EmailTemplateFileAttachment,
+		Description: "Sent when an admin emails a document from the files library, such as a puppy contract.",
+		Subject:     "{{.Subject}}",
+		HTMLBody: `{{if .Message}}<p style="white-space: pre-line">{{.Message}}</p>{{end}}
+<p>Please find <strong>{{.FileName}}</strong> attached.</p>
+<p>April's Lil Pugs</p>`,
+		Sample: map[string]any{
+			"Subject":  "Your puppy contract - April's Lil Pugs",
+			"Message":  "Hi Jane,\nHere is the contract for Biscuit.",
+			"FileName": "biscuit-contract.pdf",
+		},
+	},
+	{
+		Key:         EmailTemplateHealthReminder,
+		Description: "Sent to every admin user when vaccinations, deworming or check-ups are coming due.",
+		Subject:     "{{.Count}} health item(s) due - April's Lil Pugs",
+		HTMLBody: `<h2>Upcoming Health Care</h2>
+<p>The following are due within the next {{.Days}} days:</p>
+<ul>
+{{range .Items}}<li><strong>{{.Animal}}</strong> ({{.Kind}}): {{.Type}} - {{.Title}}, due {{.DueDate}}{{if .Overdue}} <strong>(overdue)</strong>{{end}}</li>
+{{end}}</ul>
+<p>Record the visit on the <a href="{{.AdminURL}}">website</a> to clear the reminder.</p>`,
+		Sample: map[string]any{
+			"Count": 2,
+			"Days":  7,
+			"Items": []map[string]any{
+				{"Animal": "Bella", "Kind": "Dog", "Type": "Vaccination", "Title": "Rabies", "DueDate": "2025-03-01", "Overdue": true},
+				{"Animal": "Biscuit", "Kind": "Puppy", "Type": "Deworming", "Title": "Pyrantel", "DueDate": "2025-03-05", "Overdue": false},
+			},
+			"AdminURL": "https://aprilslilpugs.com/admin",
+		},
+	},
+}
+
+func FindEmailTemplateDefinition(key string) (EmailTemplateDefinition, bool) {
+	for _, def := range DefaultEmailTemplates {
+		if def.Key == key {
+			return def, true
+		}
+	}
+	return EmailTemplateDefinition{}, false
+}
+
+// RenderEmailTemplate renders the stored override for key, falling back to the
+// built-in default, and returns the subject and HTML body.
+func RenderEmailTemplate(ctx context.Context, key string, data map[string]any) (string, string, error) {
+	def, ok := FindEmailTemplateDefinition(key)
+	if !ok {
+		return "", "", fmt.Errorf("unknown email template %q", key)
+	}
+
+	subject, body := def.Subject, def.HTMLBody
+
+	if database.Pool != nil {
+		var storedSubject, storedBody string
+		err := database.Pool.QueryRow(ctx, "SELECT subject, html_body FROM email_templates WHERE key = $1", key).Scan(&storedSubject, &storedBody)
+		if err == nil {
+			subject, body = storedSubject, storedBody
+		} else if err != pgx.ErrNoRows {
+			slog.Warn("email template: failed to load override, using default", "key", key, "error", err)
+		}
+	}
+
+	return ExecuteEmailTemplate(key, subject, body, data)
+}
+
+// ExecuteEmailTemplate renders a subject with text/template and a body with
+// html/template, so values interpolated into the body are escaped. Referencing
+// a variable that isn't in data is an error.
+func ExecuteEmailTemplate(key string, subjectSrc string, bodySrc string, data map[string]any) (string, string, error) {
+	subjectTmpl, err := texttemplate.New(key + "_subject").Option("missingkey=error").Parse(subjectSrc)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid subject template: %w", err)
+	}
+
+	bodyTmpl, err := htmltemplate.New(key + "_body").Option("missingkey=error").Parse(bodySrc)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid body template: %w", err)
+	}
+
+	var subject, body bytes.Buffer
+	if err := subjectTmpl.Execute(&subject, data); err != nil {
+		return "", "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := bodyTmpl.Execute(&body, data); err != nil {
+		return "", "", fmt.Errorf("failed to render body: %w", err)
+	}
+
+	// Subjects end up in a mail header; never let template data add lines.
+	cleanSubject := strings.Join(strings.Fields(subject.String()), " ")
+
+	return cleanSubject, body.String(), nil
+}
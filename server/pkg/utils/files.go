@@ -63,3 +63,34 @@ func UploadAndCreateFile(c *gin.Context, formKey string, folder string) (*models
 }
 
 var DeleteFile = deleteStoredFile
+
+// StorePrivateFile saves content created by the server that holds personal
+// details, such as a signed contract, under the same naming scheme as uploads
+// but outside the statically served storage root. The returned URL only
+// resolves through the authenticated private-files route.
+func StorePrivateFile(folder string, name string, content []byte) (*models.File, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	stem := sanitizeFileStem(strings.TrimSuffix(name, filepath.Ext(name)))
+	suffix, err := randomSuffix()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate file name: %w", err)
+	}
+
+	fileName := fmt.Sprintf("%d-%s-%s%s", time.Now().UnixMilli(), stem, suffix, ext)
+	absPath, relPath, err := buildPrivateStoragePath(folder, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(absPath, content, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	now := time.Now()
+	return &models.File{
+		Name:      name,
+		URL:       buildPrivateFileURL(relPath),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
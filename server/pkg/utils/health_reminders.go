@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/config"
+	"github.com/jonahgcarpenter/aprilslilpugs/server/pkg/database"
+)
+
+const healthReminderInterval = time.Hour
+
+func StartHealthReminderWorker() {
+	slog.Info("health reminders: started", "interval", healthReminderInterval.String())
+
+	ticker := time.NewTicker(healthReminderInterval)
+	defer ticker.Stop()
+
+	sendHealthReminders()
+	for range ticker.C {
+		sendHealthReminders()
+	}
+}
+
+// sendHealthReminders emails admins one digest of health records coming due
+// within HEALTH_REMINDER_DAYS. A record is reminded about once; it counts as
+// done when a later record of the same type and title exists for the animal,
+// and puppies that have gone home or were promoted to dogs are skipped.
+func sendHealthReminders() {
+	if database.Pool == nil {
+		return
+	}
+
+	days, err := strconv.Atoi(config.Load().HealthRemindDays)
+	if err != nil || days <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Claim the records first so a second instance can't send the same
+	// reminders; the claim is released below if nothing gets queued.
+	query := `
+		WITH due AS (
+			SELECT h.id
+			FROM health_records h
+			LEFT JOIN puppies p ON h.puppy_id = p.id
+			WHERE h.next_due_date <= CURRENT_DATE + $1::int
+				AND h.reminder_sent_at IS NULL
+				AND (p.id IS NULL OR p.status NOT IN ('Sold', 'Retained'))
+				AND NOT EXISTS (
+					SELECT 1 FROM health_records n
+					WHERE n.dog_id IS NOT DISTINCT FROM h.dog_id
+						AND n.puppy_id IS NOT DISTINCT FROM h.puppy_id
+						AND n.type = h.type AND n.title = h.title
+						AND n.record_date > h.record_date
+				)
+			FOR UPDATE OF h SKIP LOCKED
+		)
+		UPDATE health_records h SET reminder_sent_at = NOW()
+		FROM due
+		WHERE h.id = due.id
+		RETURNING h.id,
+			COALESCE((SELECT name FROM dogs WHERE id = h.dog_id), (SELECT name FROM puppies WHERE id = h.puppy_id), ''),
+			h.dog_id IS NOT NULL, h.type, h.title, h.next_due_date`
+
+	rows, err := database.Pool.Query(ctx, query, days)
+	if err != nil {
+		slog.Error("health reminders: failed to claim due records", "error", err)
+		return
+	}
+
+	var ids []int
+	var items []map[string]any
+	today := time.Now().Format("2006-01-02")
+	for rows.Next() {
+		var id int
+		var animal, recordType, title string
+		var isDog bool
+		var due time.Time
+		if err := rows.Scan(&id, &animal, &isDog, &recordType, &title, &due); err != nil {
+			slog.Warn("health reminders: failed to scan record", "error", err)
+			continue
+		}
+
+		kind := "Puppy"
+		if isDog {
+			kind = "Dog"
+		}
+		ids = append(ids, id)
+		items = append(items, map[string]any{
+			"Animal":  animal,
+			"Kind":    kind,
+			"Type":    recordType,
+			"Title":   title,
+			"DueDate": due.Format("2006-01-02"),
+			"Overdue": due.Format("2006-01-02") < today,
+		})
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		slog.Error("health reminders: failed to read due records", "error", err)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	if queued := queueHealthReminder(ctx, items, days); queued == 0 {
+		if _, err := database.Pool.Exec(ctx, "UPDATE health_records SET reminder_sent_at = NULL WHERE id = ANY($1)", ids); err != nil {
+			slog.Error("health reminders: failed to release claimed records", "error", err)
+		}
+		return
+	}
+
+	slog.Info("health reminders: reminders sent", "record_count", len(ids))
+}
+
+func queueHealthReminder(ctx context.Context, items []map[string]any, days int) int {
+	rows, err := database.Pool.Query(ctx, "SELECT email FROM users")
+	if err != nil {
+		slog.Error("health reminders: failed to fetch recipients", "error", err)
+		return 0
+	}
+	defer rows.Close()
+
+	var recipients []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err == nil {
+			recipients = append(recipients, email)
+		}
+	}
+
+	subject, htmlBody, err := RenderEmailTemplate(ctx, EmailTemplateHealthReminder, map[string]any{
+		"Count":    len(items),
+		"Days":     days,
+		"Items":    items,
+		"AdminURL": strings.TrimSuffix(config.Load().AppBaseURL, "/") + "/admin",
+	})
+	if err != nil {
+		slog.Error("health reminders: failed to render template", "error", err)
+		return 0
+	}
+
+	queued := 0
+	for _, email := range recipients {
+		if _, err := QueueEmail(ctx, []string{email}, subject, htmlBody); err != nil {
+			slog.Error("health reminders: failed to queue email", "recipient", email, "error", err)
+			continue
+		}
+		queued++
+	}
+	return queued
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const icalProductID = "-//April's Lil Pugs//Calendar//EN"
+
+// ICalEvent is an all-day event. End is inclusive; a zero End means a
+// single-day event.
+type ICalEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	Tentative   bool
+}
+
+// WriteICalendar writes events as an RFC 5545 VCALENDAR: CRLF line endings,
+// escaped text values and lines folded at 75 octets.
+func WriteICalendar(w io.Writer, name string, events []ICalEvent) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	line := func(content string) {
+		writeICalLine(bw, content)
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:" + icalProductID)
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeICalText(name))
+	// Ask subscribers to refresh hourly (RFC 7986 plus the older extension).
+	line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	line("X-PUBLISHED-TTL:PT1H")
+
+	for _, event := range events {
+		end := event.End
+		if end.IsZero() || end.Before(event.Start) {
+			end = event.Start
+		}
+
+		line("BEGIN:VEVENT")
+		line("UID:" + event.UID)
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + event.Start.Format("20060102"))
+		// DTEND is exclusive for all-day events.
+		line("DTEND;VALUE=DATE:" + end.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escapeICalText(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION:" + escapeICalText(event.Description))
+		}
+		if event.URL != "" {
+			line("URL:" + event.URL)
+		}
+		if event.Tentative {
+			line("STATUS:TENTATIVE")
+		} else {
+			line("STATUS:CONFIRMED")
+		}
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+func escapeICalText(value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", `\n`).Replace(value)
+}
+
+// writeICalLine folds a content line into 75-octet pieces without splitting
+// a UTF-8 character; continuation lines start with a single space.
+func writeICalLine(w *bufio.Writer, content string) {
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		w.WriteString(content[:cut])
+		w.WriteString("\r\n ")
+		content = content[cut:]
+		limit = 74
+	}
+	w.WriteString(content)
+	w.WriteString("\r\n")
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -70,3 +71,32 @@ func UploadAndCreateImage(c *gin.Context, formKey string, folder string) (*model
 func DeleteImage(imageURL string) error {
 	return deleteStoredFile(imageURL)
 }
+
+// CopyImage duplicates a stored image into folder so the copy can be deleted
+// independently of the original.
+func CopyImage(img models.Image, folder string) (*models.Image, error) {
+	data, err := ReadStoredFile(img.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	stem := sanitizeFileStem(strings.TrimSuffix(img.AltText, filepath.Ext(img.AltText)))
+	suffix, err := randomSuffix()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate image name: %w", err)
+	}
+
+	fileName := fmt.Sprintf("%d-%s-%s%s", time.Now().UnixMilli(), stem, suffix, strings.ToLower(path.Ext(img.URL)))
+	absPath, relPath, err := buildStoragePath(folder, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(absPath, data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write image: %w", err)
+	}
+
+	copied := img
+	copied.URL = buildPublicUploadURL(relPath)
+	return &copied, nil
+}
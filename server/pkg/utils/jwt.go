@@ -1,20 +1,254 @@
 package utils
 
 import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/config"
 )
 
+type jwtKey struct {
+	id     string
+	method jwt.SigningMethod
+	sign   interface{}
+	verify jwt.VerificationKey
+}
+
+type jwtKeyRing struct {
+	signing *jwtKey
+	byID    map[string]*jwtKey
+	// legacy holds HMAC secrets used to verify tokens issued before key IDs
+	// were added, so deploying rotation support doesn't log anyone out.
+	legacy []jwt.VerificationKey
+}
+
+var jwtKeys *jwtKeyRing
+
+// InitJWTKeys builds the key ring used to sign and verify session tokens.
+//
+// The signing key is JWT_PRIVATE_KEY_FILE (Ed25519 or RSA, PEM encoded) when
+// set, otherwise the HS256 JWT_SECRET. Every configured key is also accepted
+// for verification, along with JWT_PREVIOUS_SECRETS and JWT_PUBLIC_KEY_FILES
+// (both comma-separated), which lets a key be retired without invalidating
+// the tokens it already signed. Tokens carry the signing key's ID in "kid".
+func InitJWTKeys(cfg *config.Config) error {
+	ring := &jwtKeyRing{byID: make(map[string]*jwtKey)}
+
+	if cfg.JWTSecret != "" {
+		if len(cfg.JWTSecret) < 32 {
+			slog.Warn("jwt: JWT_SECRET is shorter than 32 bytes, use a longer random value")
+		}
+		key := newHMACKey(cfg.JWTSecret)
+		ring.add(key)
+		ring.signing = key
+	}
+
+	for _, secret := range splitConfigList(cfg.JWTOldSecrets) {
+		ring.add(newHMACKey(secret))
+	}
+
+	if cfg.JWTKeyFile != "" {
+		key, err := loadPrivateJWTKey(cfg.JWTKeyFile)
+		if err != nil {
+			return err
+		}
+		ring.add(key)
+		ring.signing = key
+	}
+
+	for _, path := range splitConfigList(cfg.JWTOldKeyFiles) {
+		key, err := loadPublicJWTKey(path)
+		if err != nil {
+			return err
+		}
+		ring.add(key)
+	}
+
+	if ring.signing == nil {
+		return errors.New("no JWT signing key configured, set JWT_SECRET or JWT_PRIVATE_KEY_FILE")
+	}
+
+	jwtKeys = ring
+	slog.Info("jwt: keys loaded", "signing_kid", ring.signing.id, "signing_alg", ring.signing.method.Alg(), "verification_keys", len(ring.byID))
+	return nil
+}
+
 func GenerateToken(sessionID int) (string, error) {
-	cfg := config.Load()
+	if jwtKeys == nil {
+		return "", errors.New("jwt keys are not initialized")
+	}
+	key := jwtKeys.signing
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	token := jwt.NewWithClaims(key.method, jwt.MapClaims{
 		"sub": sessionID,
 		"sid": sessionID,
 		"exp": time.Now().Add(time.Hour * 24).Unix(),
 	})
+	token.Header["kid"] = key.id
+
+	return token.SignedString(key.sign)
+}
+
+func ParseToken(tokenString string) (*jwt.Token, error) {
+	if jwtKeys == nil {
+		return nil, errors.New("jwt keys are not initialized")
+	}
+
+	validMethods := []string{
+		jwt.SigningMethodHS256.Alg(),
+		jwt.SigningMethodEdDSA.Alg(),
+		jwt.SigningMethodRS256.Alg(),
+	}
+
+	return jwt.Parse(tokenString, jwtKeys.keyFunc, jwt.WithValidMethods(validMethods))
+}
+
+func (r *jwtKeyRing) add(key *jwtKey) {
+	r.byID[key.id] = key
+	if _, ok := key.method.(*jwt.SigningMethodHMAC); ok {
+		r.legacy = append(r.legacy, key.verify)
+	}
+}
+
+func (r *jwtKeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(r.legacy) == 0 {
+			return nil, errors.New("token is missing a key id")
+		}
+		return jwt.VerificationKeySet{Keys: r.legacy}, nil
+	}
+
+	key, ok := r.byID[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.verify, nil
+}
+
+func newHMACKey(secret string) *jwtKey {
+	return &jwtKey{
+		id:     deriveKeyID("HS256", []byte(secret)),
+		method: jwt.SigningMethodHS256,
+		sign:   []byte(secret),
+		verify: []byte(secret),
+	}
+}
+
+func loadPrivateJWTKey(path string) (*jwtKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT private key %s: %w", path, err)
+	}
+
+	switch priv := parsed.(type) {
+	case ed25519.PrivateKey:
+		return newPublicJWTKey(priv.Public(), priv)
+	case *rsa.PrivateKey:
+		return newPublicJWTKey(&priv.PublicKey, priv)
+	default:
+		return nil, fmt.Errorf("unsupported JWT private key type %T in %s", parsed, path)
+	}
+}
+
+func loadPublicJWTKey(path string) (*jwtKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT public key %s: %w", path, err)
+	}
+
+	return newPublicJWTKey(parsed, nil)
+}
+
+func newPublicJWTKey(public interface{}, private interface{}) (*jwtKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JWT public key: %w", err)
+	}
+
+	var method jwt.SigningMethod
+	switch public.(type) {
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	case *rsa.PublicKey:
+		method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported JWT public key type %T", public)
+	}
+
+	return &jwtKey{
+		id:     deriveKeyID(method.Alg(), der),
+		method: method,
+		sign:   private,
+		verify: public,
+	}, nil
+}
+
+func readPEMBlock(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	return block, nil
+}
+
+// deriveKeyID gives each key a stable, non-secret identifier so rotating keys
+// doesn't require operators to label them by hand.
+func deriveKeyID(alg string, material []byte) string {
+	sum := sha256.Sum256(append([]byte(alg+":"), material...))
+	return hex.EncodeToString(sum[:8])
+}
 
-	return token.SignedString([]byte(cfg.JWTSecret))
+func splitConfigList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
 }
@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/config"
+)
+
+const (
+	MailTransportSMTP     = "smtp"
+	MailTransportSendmail = "sendmail"
+	MailTransportMaildir  = "maildir"
+	MailTransportHTTP     = "http"
+
+	SMTPTLSRequired = "required"
+	SMTPTLSOptional = "optional"
+	SMTPTLSImplicit = "implicit"
+
+	mailDialTimeout    = 30 * time.Second
+	mailSessionTimeout = 2 * time.Minute
+)
+
+// MailTransport delivers a fully populated message. Attachment data must be
+// loaded before Send is called.
+type MailTransport interface {
+	Send(from mail.Address, msg *EmailMessage) error
+}
+
+// NewMailTransport returns the transport selected by EMAIL_TRANSPORT.
+func NewMailTransport(cfg *config.Config) (MailTransport, error) {
+	switch strings.ToLower(cfg.EmailTransport) {
+	case "", MailTransportSMTP:
+		mode, err := smtpTLSMode(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &smtpTransport{
+			host:     cfg.EmailServiceHost,
+			port:     cfg.EmailServicePort,
+			username: cfg.EmailUser,
+			password: cfg.EmailPassword,
+			tlsMode:  mode,
+		}, nil
+	case MailTransportSendmail:
+		return &sendmailTransport{path: cfg.EmailSendmail}, nil
+	case MailTransportMaildir:
+		return &maildirTransport{dir: cfg.EmailMaildir}, nil
+	case MailTransportHTTP:
+		if cfg.EmailHTTPURL == "" {
+			return nil, fmt.Errorf("EMAIL_HTTP_URL is required for the http mail transport")
+		}
+		return &httpTransport{url: cfg.EmailHTTPURL, token: cfg.EmailHTTPToken}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", cfg.EmailTransport)
+	}
+}
+
+// smtpTLSMode defaults to implicit TLS on port 465 and required STARTTLS
+// everywhere else.
+func smtpTLSMode(cfg *config.Config) (string, error) {
+	mode := strings.ToLower(cfg.EmailTLSMode)
+	if mode == "" {
+		if cfg.EmailServicePort == "465" {
+			return SMTPTLSImplicit, nil
+		}
+		return SMTPTLSRequired, nil
+	}
+
+	switch mode {
+	case SMTPTLSRequired, SMTPTLSOptional, SMTPTLSImplicit:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown EMAIL_TLS_MODE %q", cfg.EmailTLSMode)
+	}
+}
+
+type smtpTransport struct {
+	host     string
+	port     string
+	username string
+	password string
+	tlsMode  string
+}
+
+func (t *smtpTransport) Send(from mail.Address, msg *EmailMessage) error {
+	data, err := BuildMIMEMessage(from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	recipients, err := envelopeAddresses(msg.Recipients())
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(t.host, t.port)
+	tlsConfig := &tls.Config{ServerName: t.host, MinVersion: tls.VersionTLS12}
+	dialer := &net.Dialer{Timeout: mailDialTimeout}
+
+	var conn net.Conn
+	if t.tlsMode == SMTPTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(mailSessionTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, t.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start smtp session: %w", err)
+	}
+	defer client.Close()
+
+	if t.tlsMode != SMTPTLSImplicit {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("starttls failed: %w", err)
+			}
+		} else if t.tlsMode == SMTPTLSRequired {
+			return fmt.Errorf("server %s does not support STARTTLS", addr)
+		}
+	}
+
+	// A local SMTP stand-in (e.g. Mailpit) accepts mail without credentials.
+	if t.password != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("server %s does not support AUTH", addr)
+		}
+		if err := client.Auth(smtp.PlainAuth("", t.username, t.password, t.host)); err != nil {
+			return fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp MAIL FROM rejected: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s rejected: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA rejected: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp server rejected message: %w", err)
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// httpTransport posts messages as JSON to a provider endpoint (or a small
+// adapter in front of one). The payload is intentionally provider-neutral.
+type httpTransport struct {
+	url   string
+	token string
+}
+
+type httpMailAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+type httpMailPayload struct {
+	From        string               `json:"from"`
+	FromName    string               `json:"fromName,omitempty"`
+	To          []string             `json:"to"`
+	Cc          []string             `json:"cc,omitempty"`
+	Bcc         []string             `json:"bcc,omitempty"`
+	Subject     string               `json:"subject"`
+	Text        string               `json:"text"`
+	HTML        string               `json:"html,omitempty"`
+	Attachments []httpMailAttachment `json:"attachments,omitempty"`
+}
+
+var mailHTTPClient = &http.Client{Timeout: mailSessionTimeout}
+
+func (t *httpTransport) Send(from mail.Address, msg *EmailMessage) error {
+	to, err := envelopeAddresses(msg.To)
+	if err != nil {
+		return err
+	}
+	cc, err := envelopeAddresses(msg.Cc)
+	if err != nil {
+		return err
+	}
+	bcc, err := envelopeAddresses(msg.Bcc)
+	if err != nil {
+		return err
+	}
+
+	text := msg.TextBody
+	if text == "" {
+		text = HTMLToText(msg.HTMLBody)
+	}
+
+	payload := httpMailPayload{
+		From:     from.Address,
+		FromName: from.Name,
+		To:       to,
+		Cc:       cc,
+		Bcc:      bcc,
+		Subject:  strings.Join(strings.Fields(msg.Subject), " "),
+		Text:     text,
+		HTML:     msg.HTMLBody,
+	}
+
+	for _, attachment := range msg.Attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(strings.ToLower(filepath.Ext(attachment.Name)))
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		payload.Attachments = append(payload.Attachments, httpMailAttachment{
+			Filename:    attachment.Name,
+			ContentType: contentType,
+			Content:     base64.StdEncoding.EncodeToString(attachment.Data),
+		})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	start := time.Now()
+	resp, err := mailHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("mail provider request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("mail provider returned %d after %s: %s", resp.StatusCode, time.Since(start).Round(time.Millisecond), strings.TrimSpace(string(detail)))
+	}
+
+	return nil
+}
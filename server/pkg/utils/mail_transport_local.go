@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// sendmailTransport pipes the message to a sendmail-compatible binary, which
+// covers local MTAs as well as dev tools like mailpit's sendmail shim.
+type sendmailTransport struct {
+	path string
+}
+
+func (t *sendmailTransport) Send(from mail.Address, msg *EmailMessage) error {
+	data, err := BuildMIMEMessage(from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	recipients, err := envelopeAddresses(msg.Recipients())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mailSessionTimeout)
+	defer cancel()
+
+	args := append([]string{"-i", "-f", from.Address, "--"}, recipients...)
+	cmd := exec.CommandContext(ctx, t.path, args...)
+	cmd.Stdin = bytes.NewReader(data)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sendmail failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// maildirTransport writes each message into a Maildir so development and test
+// environments can inspect outgoing email without a mail server. The envelope
+// recipients are recorded in an X-Envelope-To header since Bcc is not
+// otherwise visible in the message.
+type maildirTransport struct {
+	dir string
+}
+
+func (t *maildirTransport) Send(from mail.Address, msg *EmailMessage) error {
+	data, err := BuildMIMEMessage(from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	recipients, err := envelopeAddresses(msg.Recipients())
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(t.dir, sub), 0o755); err != nil {
+			return fmt.Errorf("failed to create maildir: %w", err)
+		}
+	}
+
+	suffix, err := randomSuffix()
+	if err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	name := fmt.Sprintf("%d.%s.%s", time.Now().UnixNano(), suffix, strings.ReplaceAll(hostname, "/", "_"))
+
+	content := append([]byte("X-Envelope-To: "+strings.Join(recipients, ", ")+"\r\n"), data...)
+
+	tmpPath := filepath.Join(t.dir, "tmp", name)
+	if err := os.WriteFile(tmpPath, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(t.dir, "new", name)); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to deliver message: %w", err)
+	}
+
+	return nil
+}
+
+func envelopeAddresses(recipients []string) ([]string, error) {
+	addresses := make([]string, 0, len(recipients))
+	for _, rcpt := range recipients {
+		addr, err := mail.ParseAddress(rcpt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", rcpt, err)
+		}
+		addresses = append(addresses, addr.Address)
+	}
+	return addresses, nil
+}
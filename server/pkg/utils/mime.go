@@ -0,0 +1,240 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/models"
+)
+
+type EmailMessage struct {
+	To          []string
+	Cc          []string
+	Bcc         []string
+	Subject     string
+	TextBody    string
+	HTMLBody    string
+	Attachments []models.EmailAttachment
+	// Sensitive marks messages carrying sign-in or confirmation links, whose
+	// bodies are kept out of the outbox listing.
+	Sensitive bool
+}
+
+// Recipients returns every envelope recipient, including Bcc addresses that
+// are deliberately left out of the message headers.
+func (m *EmailMessage) Recipients() []string {
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	recipients = append(recipients, m.Bcc...)
+	return recipients
+}
+
+// BuildMIMEMessage renders msg as an RFC 5322 message with a multipart/alternative
+// text and HTML body, wrapped in multipart/mixed when there are attachments.
+// Attachments must already have their Data loaded.
+func BuildMIMEMessage(from mail.Address, msg *EmailMessage, now time.Time) ([]byte, error) {
+	if len(msg.To) == 0 {
+		return nil, fmt.Errorf("email has no To recipients")
+	}
+
+	to, err := formatAddressList(msg.To)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := formatAddressList(msg.Cc)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := formatAddressList(msg.Bcc); err != nil {
+		return nil, err
+	}
+
+	messageID, err := newMessageID(from.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := strings.Join(strings.Fields(msg.Subject), " ")
+
+	var buf bytes.Buffer
+	writeHeader := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+
+	writeHeader("From", from.String())
+	writeHeader("To", to)
+	if cc != "" {
+		writeHeader("Cc", cc)
+	}
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", subject))
+	writeHeader("Date", now.Format(time.RFC1123Z))
+	writeHeader("Message-ID", messageID)
+	writeHeader("MIME-Version", "1.0")
+
+	textBody := msg.TextBody
+	if textBody == "" {
+		textBody = HTMLToText(msg.HTMLBody)
+	}
+
+	var alternative bytes.Buffer
+	altWriter := multipart.NewWriter(&alternative)
+	if err := writeTextPart(altWriter, "text/plain", textBody); err != nil {
+		return nil, err
+	}
+	if msg.HTMLBody != "" {
+		if err := writeTextPart(altWriter, "text/html", msg.HTMLBody); err != nil {
+			return nil, err
+		}
+	}
+	if err := altWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	if len(msg.Attachments) == 0 {
+		writeHeader("Content-Type", "multipart/alternative; boundary="+altWriter.Boundary())
+		buf.WriteString("\r\n")
+		buf.Write(alternative.Bytes())
+		return buf.Bytes(), nil
+	}
+
+	mixedWriter := multipart.NewWriter(&buf)
+	writeHeader("Content-Type", "multipart/mixed; boundary="+mixedWriter.Boundary())
+	buf.WriteString("\r\n")
+
+	altPart, err := mixedWriter.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + altWriter.Boundary()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := altPart.Write(alternative.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range msg.Attachments {
+		if err := writeAttachmentPart(mixedWriter, attachment); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mixedWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeTextPart(w *multipart.Writer, contentType string, body string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeAttachmentPart(w *multipart.Writer, attachment models.EmailAttachment) error {
+	name := sanitizePathSegment(attachment.Name)
+	if name == "" {
+		name = "attachment"
+	}
+
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": name})},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	for len(encoded) > 76 {
+		if _, err := part.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = part.Write([]byte(encoded + "\r\n"))
+	return err
+}
+
+func formatAddressList(addresses []string) (string, error) {
+	formatted := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			return "", fmt.Errorf("invalid email address %q: %w", address, err)
+		}
+		formatted = append(formatted, parsed.String())
+	}
+	return strings.Join(formatted, ", "), nil
+}
+
+func newMessageID(fromAddress string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate message id: %w", err)
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(fromAddress, "@"); at >= 0 && at < len(fromAddress)-1 {
+		domain = fromAddress[at+1:]
+	}
+
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(buf), domain), nil
+}
+
+var (
+	htmlLinkPattern      = regexp.MustCompile(`(?is)<a\s[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	htmlBreakPattern     = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|h[1-6]|li|tr)>`)
+	htmlTagPattern       = regexp.MustCompile(`(?s)<[^>]*>`)
+	excessNewlinePattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLToText produces a readable plain-text alternative for the simple HTML
+// used in our emails. Links are kept as "text (url)".
+func HTMLToText(htmlBody string) string {
+	text := htmlLinkPattern.ReplaceAllString(htmlBody, "$2 ($1)")
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = strings.Join(lines, "\n")
+
+	text = excessNewlinePattern.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text) + "\n"
+}
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// PDFBlock is one paragraph of a generated document. Blocks are word-wrapped
+// to the page width and flow onto new pages as needed.
+type PDFBlock struct {
+	Text    string
+	Bold    bool
+	Size    float64
+	Spacing float64
+}
+
+// Letter paper with one-inch margins, in points.
+const (
+	pdfPageWidth  = 612.0
+	pdfPageHeight = 792.0
+	pdfMargin     = 72.0
+)
+
+// Glyph widths for printable ASCII (32-126) in the standard Helvetica fonts,
+// in thousandths of the font size. Every PDF reader ships these fonts, so
+// nothing needs embedding.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// winAnsiExtras maps typographic characters to their WinAnsiEncoding bytes.
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// pdfEncode converts text to WinAnsiEncoding, which the standard fonts use.
+// Characters it can't represent become '?'.
+func pdfEncode(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '\t':
+			out = append(out, ' ')
+		case r >= 32 && r <= 126, r >= 160 && r <= 255:
+			out = append(out, byte(r))
+		default:
+			if b, ok := winAnsiExtras[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+func pdfTextWidth(text []byte, bold bool, size float64) float64 {
+	widths := &helveticaWidths
+	if bold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, b := range text {
+		if b >= 32 && b <= 126 {
+			total += widths[b-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// pdfWrap breaks a paragraph into lines no wider than width. A single word
+// longer than the line is split wherever it overflows.
+func pdfWrap(text []byte, bold bool, size, width float64) [][]byte {
+	var lines [][]byte
+	var line []byte
+	for _, word := range bytes.Fields(text) {
+		candidate := word
+		if len(line) > 0 {
+			candidate = append(append(append([]byte{}, line...), ' '), word...)
+		}
+		if pdfTextWidth(candidate, bold, size) <= width {
+			line = candidate
+			continue
+		}
+		if len(line) > 0 {
+			lines = append(lines, line)
+			line = nil
+		}
+		for pdfTextWidth(word, bold, size) > width {
+			cut := len(word) - 1
+			for cut > 1 && pdfTextWidth(word[:cut], bold, size) > width {
+				cut--
+			}
+			lines = append(lines, word[:cut])
+			word = word[cut:]
+		}
+		line = append([]byte{}, word...)
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func pdfEscape(text []byte) []byte {
+	var out bytes.Buffer
+	for _, b := range text {
+		if b == '\\' || b == '(' || b == ')' {
+			out.WriteByte('\\')
+		}
+		out.WriteByte(b)
+	}
+	return out.Bytes()
+}
+
+// RenderPDF lays blocks out top to bottom on Letter pages and returns a
+// PDF 1.4 document. Each page is numbered in the footer.
+func RenderPDF(title string, blocks []PDFBlock) []byte {
+	width := pdfPageWidth - 2*pdfMargin
+	var pages []*bytes.Buffer
+	var page *bytes.Buffer
+	y := 0.0
+
+	newPage := func() {
+		page = &bytes.Buffer{}
+		pages = append(pages, page)
+		y = pdfPageHeight - pdfMargin
+	}
+	newPage()
+
+	for _, block := range blocks {
+		size := block.Size
+		if size == 0 {
+			size = 11
+		}
+		font := "F1"
+		if block.Bold {
+			font = "F2"
+		}
+		leading := size * 1.35
+
+		for _, rawLine := range strings.Split(block.Text, "\n") {
+			for _, line := range pdfWrap(pdfEncode(rawLine), block.Bold, size, width) {
+				if y-leading < pdfMargin {
+					newPage()
+				}
+				y -= leading
+				if len(line) > 0 {
+					fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, pdfMargin, y, pdfEscape(line))
+				}
+			}
+		}
+		y -= block.Spacing
+	}
+
+	var doc bytes.Buffer
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, doc.Len())
+		fmt.Fprintf(&doc, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	doc.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-4 are fixed; each page then takes a page and a content object.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (April's Lil Pugs) >>", pdfEscape(pdfEncode(title))))
+
+	for i, content := range pages {
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		footerX := pdfPageWidth - pdfMargin - pdfTextWidth([]byte(footer), false, 9)
+		fmt.Fprintf(content, "BT /F1 9.0 Tf %.2f %.2f Td (%s) Tj ET\n", footerX, pdfMargin/2, footer)
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 7+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return doc.Bytes()
+}
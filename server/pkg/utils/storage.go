@@ -16,10 +16,38 @@ import (
 
 var uploadFolders = []string{"breeders", "dogs", "litters", "puppies", "files"}
 
+// privateFolders live under PrivateStorageRoot, which is not served
+// statically; their files are downloaded through PrivateFilesURLBase.
+var privateFolders = []string{"contracts"}
+
+// PrivateFilesURLBase is the authenticated route private files are linked
+// and downloaded from.
+const PrivateFilesURLBase = "/api/private-files"
+
 func EnsureStorageDirectories() error {
-	root := config.Load().StorageRoot
+	cfg := config.Load()
+
+	// Everything under StorageRoot is served statically, so private files
+	// kept inside it would be public.
+	absPublic, err := filepath.Abs(cfg.StorageRoot)
+	if err != nil {
+		return err
+	}
+	absPrivate, err := filepath.Abs(cfg.PrivateRoot)
+	if err != nil {
+		return err
+	}
+	if absPrivate == absPublic || strings.HasPrefix(absPrivate, absPublic+string(os.PathSeparator)) {
+		return fmt.Errorf("private storage root %q must not be inside storage root %q", cfg.PrivateRoot, cfg.StorageRoot)
+	}
+
 	for _, folder := range uploadFolders {
-		if err := os.MkdirAll(filepath.Join(root, folder), 0o755); err != nil {
+		if err := os.MkdirAll(filepath.Join(cfg.StorageRoot, folder), 0o755); err != nil {
+			return err
+		}
+	}
+	for _, folder := range privateFolders {
+		if err := os.MkdirAll(filepath.Join(cfg.PrivateRoot, folder), 0o700); err != nil {
 			return err
 		}
 	}
@@ -27,6 +55,14 @@ func EnsureStorageDirectories() error {
 }
 
 func buildStoragePath(folder, fileName string) (string, string, error) {
+	return buildPathUnder(config.Load().StorageRoot, folder, fileName)
+}
+
+func buildPrivateStoragePath(folder, fileName string) (string, string, error) {
+	return buildPathUnder(config.Load().PrivateRoot, folder, fileName)
+}
+
+func buildPathUnder(root, folder, fileName string) (string, string, error) {
 	cleanFolder := sanitizePathSegment(folder)
 	if cleanFolder == "" {
 		return "", "", fmt.Errorf("invalid storage folder")
@@ -37,7 +73,6 @@ func buildStoragePath(folder, fileName string) (string, string, error) {
 		return "", "", fmt.Errorf("invalid file name")
 	}
 
-	root := config.Load().StorageRoot
 	absRoot, err := filepath.Abs(root)
 	if err != nil {
 		return "", "", err
@@ -54,42 +89,61 @@ func buildStoragePath(folder, fileName string) (string, string, error) {
 }
 
 func buildPublicUploadURL(relPath string) string {
-	base := strings.TrimSuffix(config.Load().UploadsURLBase, "/")
+	return joinURLPath(config.Load().UploadsURLBase, relPath)
+}
+
+func buildPrivateFileURL(relPath string) string {
+	return joinURLPath(PrivateFilesURLBase, relPath)
+}
+
+func joinURLPath(base, relPath string) string {
 	cleanRel := strings.TrimLeft(filepath.ToSlash(relPath), "/")
-	return base + "/" + cleanRel
+	return strings.TrimSuffix(base, "/") + "/" + cleanRel
 }
 
+// storagePathFromURL maps an uploads or private-files URL back to the file
+// on disk, returning "" for URLs that are not in local storage.
 func storagePathFromURL(fileURL string) (string, error) {
 	if fileURL == "" {
 		return "", nil
 	}
 
-	base := strings.TrimSuffix(config.Load().UploadsURLBase, "/")
 	u, err := url.Parse(fileURL)
 	if err != nil {
 		return "", fmt.Errorf("invalid url: %w", err)
 	}
 
+	cfg := config.Load()
 	cleanPath := path.Clean(u.Path)
+	if absPath, ok, err := pathUnderBase(cleanPath, PrivateFilesURLBase, cfg.PrivateRoot); ok || err != nil {
+		return absPath, err
+	}
+	absPath, _, err := pathUnderBase(cleanPath, cfg.UploadsURLBase, cfg.StorageRoot)
+	return absPath, err
+}
+
+// pathUnderBase resolves cleanPath against root when it sits under the URL
+// base, reporting whether it did.
+func pathUnderBase(cleanPath, base, root string) (string, bool, error) {
+	base = strings.TrimSuffix(base, "/")
 	if !strings.HasPrefix(cleanPath, base+"/") {
-		return "", nil
+		return "", false, nil
 	}
 
 	relURLPath := strings.TrimPrefix(cleanPath, base+"/")
 	relFSPath := filepath.FromSlash(relURLPath)
 
-	root := config.Load().StorageRoot
 	absRoot, err := filepath.Abs(root)
 	if err != nil {
-		return "", err
+		return "", true, err
 	}
 
 	absPath := filepath.Join(absRoot, relFSPath)
 	if !strings.HasPrefix(absPath, absRoot+string(os.PathSeparator)) {
-		return "", fmt.Errorf("invalid storage path")
+		return "", true, fmt.Errorf("invalid storage path")
 	}
 
-	return absPath, nil
+	return absPath, true, nil
 }
 
 func deleteStoredFile(fileURL string) error {
@@ -152,3 +206,30 @@ func randomSuffix() (string, error) {
 	}
 	return hex.EncodeToString(buf), nil
 }
+
+// ReadStoredFile loads the contents of a file previously saved under the
+// uploads or private-files URL base, e.g. a files-library document being
+// attached to an email.
+func ReadStoredFile(fileURL string) ([]byte, error) {
+	absPath, err := storagePathFromURL(fileURL)
+	if err != nil {
+		return nil, err
+	}
+	if absPath == "" {
+		return nil, fmt.Errorf("file is not in local storage: %s", fileURL)
+	}
+
+	return os.ReadFile(absPath)
+}
+
+// PrivateFilePath returns where a private-files URL is stored on disk, or ""
+// when the URL does not point into private storage.
+func PrivateFilePath(fileURL string) (string, error) {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+
+	absPath, _, err := pathUnderBase(path.Clean(u.Path), PrivateFilesURLBase, config.Load().PrivateRoot)
+	return absPath, err
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonahgcarpenter/aprilslilpugs/server/internal/config"
+)
+
+// GenerateSecureToken returns a random URL-safe token along with the SHA-256
+// hash that should be persisted in its place. Only the hash is stored, so a
+// leaked database row cannot be replayed.
+func GenerateSecureToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashSecureToken(token), nil
+}
+
+func HashSecureToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+const APIKeyPrefix = "alp_"
+
+// GenerateAPIKey returns a new API key, the short prefix shown in listings so
+// keys can be told apart, and the hash to store.
+func GenerateAPIKey() (string, string, string, error) {
+	token, _, err := GenerateSecureToken()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	key := APIKeyPrefix + token
+	return key, key[:len(APIKeyPrefix)+8], HashSecureToken(key), nil
+}
+
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// SignFormToken returns a token recording when the server handed out a
+// public form, so the submit handler can measure fill time without trusting
+// the browser's clock. The form name keeps tokens from one form being
+// replayed on another.
+func SignFormToken(form string, issuedAt time.Time) string {
+	stamp := strconv.FormatInt(issuedAt.UnixMilli(), 10)
+	return stamp + "." + formTokenMAC(form, stamp)
+}
+
+// VerifyFormToken checks a token from SignFormToken and returns when it was
+// issued.
+func VerifyFormToken(form, token string) (time.Time, error) {
+	stamp, mac, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(formTokenMAC(form, stamp))) {
+		return time.Time{}, errors.New("invalid form token")
+	}
+
+	millis, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("invalid form token")
+	}
+	return time.UnixMilli(millis), nil
+}
+
+func formTokenMAC(form, stamp string) string {
+	mac := hmac.New(sha256.New, []byte("form-token:"+config.Load().JWTSecret))
+	mac.Write([]byte(form + ":" + stamp))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}